@@ -2,10 +2,12 @@ package main
 
 import (
 	"bufio"
+	"fmt"
 	"io"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/patrickdappollonio/http-server/internal/server"
 	"github.com/spf13/cobra"
@@ -24,6 +26,9 @@ func run() error {
 	// Server and settings holder
 	var server server.Server
 
+	// When set, the configuration is checked and the program exits
+	var checkOnly bool
+
 	// Define the config prefix for config files
 	server.ConfigFilePrefix = configFilePrefix
 
@@ -44,6 +49,9 @@ func run() error {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 
+		// The server has no use for shell completions
+		CompletionOptions: cobra.CompletionOptions{DisableDefaultCmd: true},
+
 		// Bind viper settings against the root command
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
 			return bindCobraAndViper(cmd)
@@ -55,6 +63,17 @@ func run() error {
 			server.LogOutput = cmd.OutOrStdout()
 			server.SetVersion(version)
 
+			// If only a configuration check was requested, run all the
+			// startup validations and exit without listening
+			if checkOnly {
+				if err := server.Check(); err != nil {
+					return err
+				}
+
+				fmt.Fprintln(os.Stdout, "Configuration is valid.")
+				return nil
+			}
+
 			// Validate fields to make sure they're correct
 			if err := server.Validate(); err != nil {
 				return err
@@ -93,8 +112,30 @@ func run() error {
 	// Define the flags for the root command
 	flags := rootCmd.Flags()
 	flags.IntVarP(&server.Port, "port", "p", 5000, "port to configure the server to listen on")
+	flags.StringVar(&server.Addr, "addr", "", "address to listen on, such as \"127.0.0.1:5000\", takes precedence over --port")
+	flags.BoolVar(&server.SocketActivation, "socket-activation", false, "use the sockets passed by systemd through socket activation, if any, instead of binding the address")
+	flags.IntVar(&server.MaxConnections, "max-connections", 0, "maximum number of concurrent connections, further connections wait until one is closed (0 for no limit)")
+	flags.DurationVar(&server.ReadHeaderTimeout, "read-header-timeout", 10*time.Second, "maximum time clients can take to send the headers of a request before the connection is closed (0 for no limit)")
+	flags.IntVar(&server.MaxPathDepth, "max-path-depth", 0, "maximum number of path segments a request can have, longer paths get a 400 error (0 for no limit)")
+	flags.StringVar(&server.BackslashPaths, "backslash-paths", "reject", "how to handle backslashes in request paths: reject them with a 400 error, turn them into slashes, or keep them literal as part of file names (reject, slash or literal)")
+	flags.Int64Var(&server.MaxRequestBodyBytes, "max-request-body-bytes", 1<<30, "maximum size in bytes of any request body, regardless of the method, answering with a 413 error otherwise (0 for no limit)")
+	flags.IntVar(&server.ReadBufferSize, "read-buffer-size", 0, "read served files from disk in chunks of this many bytes, to tune throughput for the storage backend (0 to use the default)")
 	flags.StringVarP(&server.Path, "path", "d", "./", "path to the directory you want to serve")
+	flags.StringSliceVar(&server.Roots, "overlay", nil, "directories layered over the served path, merged into a single view where files in later overlays take precedence")
+	flags.StringToStringVar(&server.Aliases, "alias", nil, "directories served at a URL prefix instead of the served path, such as \"/docs/=/srv/docs\", where the longest matching prefix wins")
+	flags.StringVar(&server.ACMEChallengeDir, "acme-challenge-dir", "", "directory served at \"/.well-known/acme-challenge/\" without authentication, for ACME clients like certbot to complete HTTP-01 challenges")
 	flags.StringVar(&server.PathPrefix, "pathprefix", "/", "path prefix for the URL where the server will listen on")
+	flags.StringSliceVar(&server.ExtraPathPrefixes, "extra-pathprefix", nil, "more path prefixes serving the same directory as --pathprefix, such as \"/download/\"")
+	flags.StringVar(&server.ExternalPrefix, "external-prefix", "", "path prefix clients see in front of the server when behind a reverse proxy, used for generated links")
+	flags.StringVar(&server.BaseURL, "base-url", "", "scheme and host clients use to reach the server, such as \"https://files.example.com\", used for absolute links (defaults to the request host)")
+	flags.BoolVar(&server.TrustProxy, "trust-proxy", false, "trust headers set by a reverse proxy, such as \"X-Forwarded-Prefix\" and \"X-Forwarded-For\"")
+	flags.StringVar(&server.AccelRedirect, "accel-redirect", "", "let the reverse proxy send files, either \"nginx\" with the \"X-Accel-Redirect\" header or \"sendfile\" with the \"X-Sendfile\" header")
+	flags.StringVar(&server.AccelRedirectPrefix, "accel-redirect-prefix", "/internal/", "internal nginx location the served path is available at, used with \"--accel-redirect nginx\"")
+	flags.StringSliceVar(&server.AllowCIDRs, "allow-cidr", nil, "only allow requests from clients in these network ranges, in CIDR notation")
+	flags.StringSliceVar(&server.DenyCIDRs, "deny-cidr", nil, "deny requests from clients in these network ranges, in CIDR notation")
+	flags.StringVar(&server.SplashTemplate, "splash-template", "", "path to an HTML template served with a 200 status code for every path, hiding the real content, such as a \"coming soon\" page")
+	flags.StringSliceVar(&server.SplashAllowCIDRs, "splash-allow-cidr", nil, "clients in these network ranges, in CIDR notation, see the real content instead of the splash page")
+	flags.BoolVar(&server.AllowOptions, "allow-options", false, "answer OPTIONS requests with the allowed methods and supported features instead of rejecting them")
 	flags.BoolVar(&server.CorsEnabled, "cors", false, "enable CORS support by setting the \"Access-Control-Allow-Origin\" header to \"*\"")
 	flags.StringVar(&server.Username, "username", "", "username for basic authentication")
 	flags.StringVar(&server.Password, "password", "", "password for basic authentication")
@@ -105,15 +146,120 @@ func run() error {
 	flags.BoolVar(&server.MarkdownBeforeDir, "markdown-before-dir", false, "render markdown content before the directory listing")
 	flags.StringVar(&server.JWTSigningKey, "jwt-key", "", "signing key for JWT authentication")
 	flags.BoolVar(&server.ValidateTimedJWT, "ensure-unexpired-jwt", false, "enable time validation for JWT claims \"exp\" and \"nbf\"")
+	flags.StringVar(&server.ShareSecret, "share-secret", "", "secret used to sign share links, which grant temporary access to a path without authentication")
+	flags.BoolVar(&checkOnly, "check", false, "validate the configuration, templates and redirections file, then exit without starting the server")
+	flags.StringVar(&server.ErrorTemplate, "error-template", "", "path to an HTML template rendered for error responses, instead of plain text")
+	flags.BoolVar(&server.StrictTemplates, "strict-templates", false, "fail to start if the directory listing templates can't be parsed, instead of rendering plain listings")
+	flags.StringToStringVar(&server.Layouts, "layout", nil, "named HTML templates directory listings can be rendered with, such as \"gallery=/srv/gallery.tmpl\", picked with a \".layout\" file in the directory or with --layout-path")
+	flags.StringToStringVar(&server.LayoutPaths, "layout-path", nil, "directory path patterns rendered with a layout, such as \"/photos/*=gallery\", where the longest matching pattern wins")
 	flags.StringVar(&server.BannerMarkdown, "banner", "", "markdown text to be rendered at the top of the directory listing page")
 	flags.BoolVar(&server.ETagDisabled, "disable-etag", false, "disable ETag header generation")
-	flags.BoolVar(&server.GzipEnabled, "gzip", false, "enable gzip compression for supported content-types")
+	flags.StringVar(&server.ServerHeader, "server-header", "http-server", "value of the \"Server\" header sent with every response (empty to not send it)")
+	flags.BoolVar(&server.ExtendedHealthCheck, "extended-health-check", false, "respond to the health check endpoint with a JSON body including version and uptime")
+	flags.StringVar(&server.HealthBody, "health-body", "OK", "body of the health check response, unless the extended health check is enabled")
+	flags.StringVar(&server.HealthContentType, "health-content-type", "text/plain; charset=utf-8", "content type of the health check response, unless the extended health check is enabled")
+	flags.BoolVar(&server.LogServedPath, "log-served-path", false, "include the filesystem path served for each request in the access log")
+	flags.BoolVar(&server.LogServedPathOnErrors, "log-served-path-on-errors", false, "also include the filesystem path in the access log for error responses, such as 404s")
+	flags.StringVar(&server.LogLevel, "log-level", "info", "minimum level of the lines logged, for both requests and warnings: debug, info, warn or error")
+	flags.StringSliceVar(&server.LogStatusLevels, "log-status-levels", nil, "level requests are logged at by status code class, such as \"4xx=info\", overriding the defaults: info for 1xx to 3xx, warn for 4xx and error for 5xx")
+	flags.DurationVar(&server.LogFlushInterval, "log-flush-interval", 0, "buffer the log output in memory and write it out at most this often, such as \"1s\", reducing write calls under high request rates (0 writes every line right away)")
+	flags.BoolVar(&server.MetricsEnabled, "metrics", false, "expose request metrics in the Prometheus and OpenMetrics formats at the \"/_/metrics\" endpoint")
+	flags.BoolVar(&server.DebugEndpoints, "debug-endpoints", false, "expose runtime statistics, such as open files and goroutines, at the \"/_/debug\" endpoint, protected by the configured authentication")
+	flags.DurationVar(&server.ResponseDelay, "chaos-response-delay", 0, "testing only: delay every response by this long, to check how clients handle slow servers (0 to disable)")
+	flags.BoolVar(&server.GzipEnabled, "gzip", false, "enable compression for supported content-types, with the algorithms in --compression-algorithms")
+	flags.StringSliceVar(&server.CompressionAlgorithms, "compression-algorithms", []string{"zstd", "gzip"}, "compression algorithms used with --gzip, in order of preference, among the ones the client accepts: zstd or gzip")
+	flags.StringSliceVar(&server.NoCompressUserAgents, "no-compress-user-agents", []string{"MSIE 6."}, "skip compression for clients whose user agent contains any of these patterns, case-insensitively")
+	flags.BoolVar(&server.AllowUpload, "allow-upload", false, "allow uploading files into directories through a form in the directory listing")
+	flags.Int64Var(&server.MaxUploadSize, "max-upload-size", 100<<20, "maximum size in bytes of a single upload request (0 for no limit)")
+	flags.IntVar(&server.MaxUploadFiles, "max-upload-files", 100, "maximum number of files in a single upload request (0 for no limit)")
+	flags.BoolVar(&server.RequireWritePreconditions, "require-write-preconditions", false, "reject uploads replacing a file and moves without an \"If-Match\" or \"If-Unmodified-Since\" header")
 	flags.BoolVar(&server.DisableRedirects, "disable-redirects", false, "disable redirection file handling")
+	flags.StringSliceVar(&server.Redirects, "redirect", nil, "redirect requests, written as the source path, the target and optionally the status code, such as \"/old/* /new/ 302\", where an asterisk keeps the rest of the path")
+	flags.StringVar(&server.RootDocument, "root-document", "", "file within the served path shown for the root URL instead of its index file or listing, such as \"dashboard.html\"")
 	flags.BoolVar(&server.DisableDirectoryList, "disable-directory-listing", false, "disable the directory listing feature and return 404s for directories without index")
+	flags.BoolVar(&server.IndexTemplating, "index-templating", false, "render \"index.html\" files as Go templates with the directory listing data, instead of serving them as they are")
+	flags.StringSliceVar(&server.TryExtensions, "try-extensions", []string{".html", ".htm"}, "extensions to try, in order, when an extensionless URL doesn't match a file (empty to disable)")
+	flags.StringSliceVar(&server.ForceDownloadExtensions, "force-download-extensions", nil, "extensions of files always downloaded by browsers instead of displayed, such as \".zip\", unless requested otherwise with \"?dl=0\"")
+	flags.StringSliceVar(&server.ForceInlineExtensions, "force-inline-extensions", nil, "extensions of files always displayed by browsers instead of downloaded, such as \".pdf\", unless requested otherwise with \"?dl\"")
+	flags.Int64Var(&server.MaxInlineBytes, "max-inline-bytes", 0, "download files larger than this many bytes instead of displaying them, unless requested with \"?inline\" (0 for no limit)")
+	flags.BoolVar(&server.CaseInsensitive, "case-insensitive", false, "redirect requests for paths that don't exist to a file or directory matching them regardless of case, if there's only one")
+	flags.BoolVar(&server.RedirectDuplicateSlashes, "redirect-duplicate-slashes", false, "redirect requests for paths with consecutive slashes, such as \"/a//b.txt\", to the path with single slashes")
+	flags.BoolVar(&server.ServeGzipped, "serve-gzipped", false, "serve \"file.gz\" when \"file\" doesn't exist, decompressing it for clients that don't accept gzip")
+	flags.BoolVar(&server.GzipPreviews, "gzip-previews", false, "show the first decompressed lines of \".gz\" files as plain text when requested with \"?preview=1\"")
+	flags.BoolVar(&server.CodeViewer, "code-viewer", false, "show text files with their syntax highlighted when requested with \"?view=1\"")
+	flags.DurationVar(&server.ListingCacheTTL, "listing-cache-ttl", 0, "cache rendered directory listings in memory for this long, or until the directory changes (0 to disable)")
+	flags.Int64Var(&server.MaxListingBytes, "max-listing-bytes", 0, "maximum size in bytes of a rendered directory listing, answering with a 500 error otherwise (0 for no limit)")
+	flags.DurationVar(&server.ListingRenderTimeout, "listing-render-timeout", 0, "maximum time spent rendering a directory listing, answering with a 500 error otherwise (0 for no limit)")
+	flags.StringVar(&server.ListingCacheControl, "listing-cache-control", "no-cache", "value of the \"Cache-Control\" header sent with directory listings, without affecting files (empty to not send it)")
+	flags.BoolVar(&server.BareListing, "bare-listing", false, "render directory listings as a plain list of links, without styling or scripts")
+	flags.IntVar(&server.CharsetSniffBytes, "charset-sniff-bytes", 4096, "maximum bytes read from text files when their charset can't be detected confidently from the first 512 bytes")
+	flags.StringToStringVar(&server.ContentTypes, "content-type", nil, "content types of files by extension or exact name, overriding the built-in ones, such as \".ts=text/plain\" or \"Jenkinsfile=text/plain\"")
+	flags.BoolVar(&server.NoCharset, "no-charset", false, "send content types of files without a charset parameter, skipping charset detection")
+	flags.IntVar(&server.CharsetConfidence, "charset-confidence", 50, "minimum confidence, from 1 to 100, needed to use a detected charset other than UTF-8")
+	flags.BoolVar(&server.StripBOM, "strip-bom", false, "leave out the UTF-8 byte order mark at the start of text files when serving them")
+	flags.BoolVar(&server.RefuseWorldWritable, "refuse-world-writable", false, "answer with a 403 error instead of serving files writable by their group or others (no effect on Windows)")
+	flags.BoolVar(&server.StreamListing, "stream-listing", false, "stream directory listings in batches as they're read from disk, unsorted and without markdown rendering")
+	flags.StringSliceVar(&server.ListColumns, "list-columns", []string{"name", "size", "modtime"}, "columns to show in the directory listing, in order, out of: name, size, modtime, mode")
+	flags.BoolVar(&server.ShowMode, "show-mode", false, "show file permissions, and owners on Unix systems, in the directory listing")
+	flags.BoolVar(&server.ShowChecksums, "show-checksums", false, "show the SHA-256 checksum of every file in the directory listing, computed in the background")
+	flags.BoolVar(&server.ShowSymlinkTargets, "show-symlink-targets", false, "show where symbolic links point to in the directory listing, marking links that leave the served directory or are broken")
+	flags.BoolVar(&server.HideDotfiles, "hide-dotfiles", false, "hide files and directories starting with a dot from directory listings, while still serving them when requested directly")
+	flags.BoolVar(&server.GroupDotfiles, "group-dotfiles", false, "list files and directories starting with a dot after every other entry in directory listings, under a separator")
+	flags.StringVar(&server.DefaultSort, "default-sort", "name", "property directory listings are sorted by, unless requested otherwise with \"?sort=\": name, size or modtime")
+	flags.StringVar(&server.DefaultOrder, "default-order", "asc", "order directory listings are sorted in, unless requested otherwise with \"?order=\": asc or desc")
+	flags.BoolVar(&server.IndexJSON, "index-json", false, "generate a \".index.json\" file in every directory with its listing as JSON, unless a real file with that name exists")
+	flags.IntVar(&server.IndexJSONPageSize, "index-json-page-size", 0, "maximum entries in each \".index.json\" response, with a \"next\" cursor to request the rest with \"?after=\" (0 for no limit)")
+	flags.BoolVar(&server.ImageConversion, "image-conversion", false, "convert images to another format when requested with the \"format\" query string parameter, which is CPU intensive")
+	flags.DurationVar(&server.ImageCacheTTL, "image-cache-ttl", 10*time.Minute, "keep converted images in memory for this long, or until the source image changes (0 to disable)")
+	flags.BoolVar(&server.DirectoryFeeds, "directory-feeds", false, "serve directory listings as RSS feeds of their most recent files when requested with \"?format=rss\"")
+	flags.IntVar(&server.FeedMaxItems, "feed-max-items", 20, "maximum amount of files included in directory feeds")
+	flags.BoolVar(&server.DirectoryPlaylists, "directory-playlists", false, "serve the audio and video files of directory listings as M3U playlists when requested with \"?format=m3u\"")
+	flags.BoolVar(&server.Checksums, "checksums", false, "answer with the checksum of a file instead of its contents when requested with \"?checksum=sha256\", \"sha1\" or \"md5\"")
+	flags.BoolVar(&server.ChecksumHeaders, "checksum-headers", false, "include the SHA-256 checksum of files in the \"X-Checksum-SHA256\" header of HEAD requests")
+	flags.BoolVar(&server.ChecksumTrailers, "checksum-trailers", false, "send the SHA-256 checksum of files in the \"X-Checksum-SHA256\" trailer, after their contents, for HTTP/1.1 and newer clients")
+	flags.IntVar(&server.MaxExpensiveOps, "max-expensive-ops", 0, "maximum number of expensive operations, such as image conversions and directory feeds, running at once (0 for no limit)")
+	flags.DurationVar(&server.ExpensiveOpsWait, "expensive-ops-wait", 10*time.Second, "how long requests wait for an expensive operation to start before getting a 503 error")
+	flags.BoolVar(&server.Watch, "watch", false, "send changes to directories as server-sent events when requested with \"?watch=1\", which keeps a watcher open per client")
+	flags.IntVar(&server.MaxWatchers, "max-watchers", 100, "maximum number of clients watching directories at once, further clients get a 503 error (0 for no limit)")
+
+	rootCmd.AddCommand(shareCommand(&server))
 
 	return rootCmd.Execute()
 }
 
+// shareCommand creates the command that generates share links, signed with
+// the same secret the server uses to validate them
+func shareCommand(server *server.Server) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "share <path>",
+		Short: "Generate a link granting temporary access to a path, end it with a slash to share a directory",
+		Args:  cobra.ExactArgs(1),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := server.Validate(); err != nil {
+				return err
+			}
+
+			link, err := server.ShareLink(args[0])
+			if err != nil {
+				return err
+			}
+
+			fmt.Fprintln(os.Stdout, link)
+			return nil
+		},
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&server.ShareSecret, "share-secret", "", "secret used to sign share links, it must match the one the server is started with")
+	flags.DurationVar(&server.ShareExpiry, "share-expiry", 24*time.Hour, "how long the generated link is valid for")
+	flags.StringVar(&server.PathPrefix, "pathprefix", "/", "path prefix for the URL where the server will listen on")
+	flags.StringVar(&server.ExternalPrefix, "external-prefix", "", "path prefix clients see in front of the server when behind a reverse proxy")
+	flags.StringVar(&server.BaseURL, "base-url", "", "scheme and host clients use to reach the server, to generate an absolute link")
+
+	return cmd
+}
+
 // sendPipeToLogger reads from the pipe and sends the output to the logger
 func sendPipeToLogger(logger *log.Logger, pipe io.Reader) {
 	// Scan the log messages per line
@@ -140,7 +286,8 @@ var ignoredFlags = map[string]struct{}{
 // A list of cobra flags that need the long form of the environment
 // variable name, because the short form can be ambiguous
 var skipShortVersionFlag = map[string]struct{}{
-	"path": {},
+	"path":  {},
+	"check": {},
 }
 
 // A set of cobra flag names to environment variable aliases
@@ -211,6 +358,24 @@ func bindCobraAndViper(rootCommand *cobra.Command) error {
 		// If the flag hasn't been changed, and the value is set in
 		// the environment, set the flag to the value from the environment
 		if !f.Changed && v.IsSet(f.Name) {
+			// Lists coming from the configuration file can't be converted
+			// to a single string, so they're set as a whole instead
+			if sv, ok := f.Value.(pflag.SliceValue); ok {
+				if _, isList := v.Get(f.Name).([]interface{}); isList {
+					sv.Replace(v.GetStringSlice(f.Name))
+					return
+				}
+			}
+
+			// Maps coming from the configuration file are set one key at
+			// a time, since the first value set replaces the default
+			if m, isMap := v.Get(f.Name).(map[string]interface{}); isMap {
+				for key, value := range m {
+					rootCommand.Flags().Set(f.Name, fmt.Sprintf("%s=%v", key, value))
+				}
+				return
+			}
+
 			rootCommand.Flags().Set(f.Name, v.GetString(f.Name))
 		}
 	})
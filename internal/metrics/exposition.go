@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	contentTypeText        = "text/plain; version=0.0.4; charset=utf-8"
+	contentTypeOpenMetrics = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+)
+
+// ServeHTTP renders the metrics using the format negotiated through the
+// "Accept" header. The Prometheus text format is the default, so older
+// scrapers keep working, while clients asking for OpenMetrics also get
+// the exemplars linking the duration histogram to traces.
+func (m *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	openMetrics := acceptsOpenMetrics(r.Header.Get("Accept"))
+
+	var buf bytes.Buffer
+	m.write(&buf, openMetrics)
+
+	if openMetrics {
+		w.Header().Set("Content-Type", contentTypeOpenMetrics)
+	} else {
+		w.Header().Set("Content-Type", contentTypeText)
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
+
+// acceptsOpenMetrics reports whether the "Accept" header lists the
+// OpenMetrics media type without explicitly rejecting it with "q=0"
+func acceptsOpenMetrics(accept string) bool {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil || mediaType != "application/openmetrics-text" {
+			continue
+		}
+
+		if q, found := params["q"]; found {
+			if value, err := strconv.ParseFloat(q, 64); err == nil && value == 0 {
+				continue
+			}
+		}
+
+		return true
+	}
+
+	return false
+}
+
+// write renders all the metrics into the buffer with the requested format
+func (m *Registry) write(buf *bytes.Buffer, openMetrics bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// The OpenMetrics format names counter families without the
+	// "_total" suffix, while the samples themselves keep it
+	counterFamily := "http_requests_total"
+	if openMetrics {
+		counterFamily = "http_requests"
+	}
+
+	writeHeader(buf, counterFamily, "counter", "Total number of HTTP requests served.")
+
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].method != keys[j].method {
+			return keys[i].method < keys[j].method
+		}
+		return keys[i].code < keys[j].code
+	})
+
+	for _, k := range keys {
+		fmt.Fprintf(buf, "http_requests_total{method=%q,code=\"%d\"} %d\n", k.method, k.code, m.requests[k])
+	}
+
+	writeHeader(buf, "http_request_duration_seconds", "histogram", "Duration of HTTP requests in seconds.")
+
+	var cumulative uint64
+	for i := range m.counts {
+		cumulative += m.counts[i]
+
+		le := "+Inf"
+		if i < len(m.buckets) {
+			le = formatFloat(m.buckets[i])
+		}
+
+		fmt.Fprintf(buf, "http_request_duration_seconds_bucket{le=%q} %d", le, cumulative)
+
+		if ex := m.exemplars[i]; openMetrics && ex != nil {
+			fmt.Fprintf(buf, " # {trace_id=%q} %s %s", ex.traceID, formatFloat(ex.value), formatTimestamp(ex.timestamp.UnixMilli()))
+		}
+
+		buf.WriteString("\n")
+	}
+
+	fmt.Fprintf(buf, "http_request_duration_seconds_sum %s\n", formatFloat(m.sum))
+	fmt.Fprintf(buf, "http_request_duration_seconds_count %d\n", m.count)
+
+	for _, g := range m.gauges {
+		value, ok := g.fn()
+		if !ok {
+			continue
+		}
+
+		writeHeader(buf, g.name, "gauge", g.help)
+		fmt.Fprintf(buf, "%s %s\n", g.name, formatFloat(value))
+	}
+
+	if openMetrics {
+		buf.WriteString("# EOF\n")
+	}
+}
+
+// writeHeader writes the type and help lines of a metric family
+func writeHeader(buf *bytes.Buffer, name, kind, help string) {
+	fmt.Fprintf(buf, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(buf, "# TYPE %s %s\n", name, kind)
+}
+
+// formatTimestamp formats a timestamp in milliseconds as seconds
+// with a fractional part, as required by the OpenMetrics format
+func formatTimestamp(ms int64) string {
+	return fmt.Sprintf("%d.%03d", ms/1000, ms%1000)
+}
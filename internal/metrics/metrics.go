@@ -0,0 +1,178 @@
+package metrics
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultBuckets are the upper bounds, in seconds, of the request
+// duration histogram buckets
+var defaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// knownMethods are the HTTP methods reported as-is in the metrics, any
+// other method is reported as "other" to keep the label cardinality bounded
+var knownMethods = map[string]struct{}{
+	http.MethodGet:     {},
+	http.MethodHead:    {},
+	http.MethodPost:    {},
+	http.MethodPut:     {},
+	http.MethodPatch:   {},
+	http.MethodDelete:  {},
+	http.MethodOptions: {},
+}
+
+// requestKey identifies a series of the requests counter
+type requestKey struct {
+	method string
+	code   int
+}
+
+// exemplar links an observation in the histogram to the trace
+// of the request that produced it
+type exemplar struct {
+	traceID   string
+	value     float64
+	timestamp time.Time
+}
+
+// gauge is a value read when the metrics are requested
+type gauge struct {
+	name string
+	help string
+	fn   func() (float64, bool)
+}
+
+// Registry keeps track of the metrics of the server and serves them
+// in either the Prometheus text format or the OpenMetrics format
+type Registry struct {
+	mu        sync.Mutex
+	requests  map[requestKey]uint64
+	buckets   []float64
+	counts    []uint64
+	exemplars []*exemplar
+	sum       float64
+	count     uint64
+	gauges    []gauge
+}
+
+// New creates a new, empty, metrics registry
+func New() *Registry {
+	return &Registry{
+		requests:  make(map[requestKey]uint64),
+		buckets:   defaultBuckets,
+		counts:    make([]uint64, len(defaultBuckets)+1),
+		exemplars: make([]*exemplar, len(defaultBuckets)+1),
+	}
+}
+
+// AddGauge registers a gauge whose value is read every time the metrics
+// are requested. If the function returns false, the gauge is omitted.
+func (m *Registry) AddGauge(name, help string, fn func() (float64, bool)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.gauges = append(m.gauges, gauge{name: name, help: help, fn: fn})
+}
+
+// observe records a finished request
+func (m *Registry) observe(method string, code int, duration time.Duration, traceID string) {
+	if _, found := knownMethods[method]; !found {
+		method = "other"
+	}
+
+	seconds := duration.Seconds()
+	bucket := sort.SearchFloat64s(m.buckets, seconds)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{method: method, code: code}]++
+	m.counts[bucket]++
+	m.sum += seconds
+	m.count++
+
+	if traceID != "" {
+		m.exemplars[bucket] = &exemplar{traceID: traceID, value: seconds, timestamp: time.Now()}
+	}
+}
+
+// Middleware records the method, status code and duration of every request
+func (m *Registry) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+
+		srw := &statusResponseWriter{rw: w}
+		next.ServeHTTP(srw, r)
+
+		statusCode := srw.statusCode
+		if statusCode == 0 {
+			statusCode = http.StatusOK
+		}
+
+		m.observe(r.Method, statusCode, time.Since(start), traceIDFromRequest(r))
+	})
+}
+
+// traceIDFromRequest extracts the trace ID from a W3C "traceparent" header,
+// formatted as "version-traceid-parentid-flags", or returns an empty string
+// if the header is missing or malformed
+func traceIDFromRequest(r *http.Request) string {
+	header := r.Header.Get("Traceparent")
+	if len(header) != 55 || header[2] != '-' || header[35] != '-' || header[52] != '-' {
+		return ""
+	}
+
+	traceID := header[3:35]
+	for _, c := range traceID {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return ""
+		}
+	}
+
+	// An all-zeroes trace ID is invalid per the specification
+	if traceID == "00000000000000000000000000000000" {
+		return ""
+	}
+
+	return traceID
+}
+
+// statusResponseWriter captures the status code sent to the client
+type statusResponseWriter struct {
+	rw         http.ResponseWriter
+	statusCode int
+}
+
+func (srw *statusResponseWriter) Header() http.Header {
+	return srw.rw.Header()
+}
+
+func (srw *statusResponseWriter) Write(p []byte) (int, error) {
+	if srw.statusCode == 0 {
+		srw.statusCode = http.StatusOK
+	}
+
+	return srw.rw.Write(p)
+}
+
+func (srw *statusResponseWriter) WriteHeader(statusCode int) {
+	if srw.statusCode == 0 {
+		srw.statusCode = statusCode
+	}
+
+	srw.rw.WriteHeader(statusCode)
+}
+
+func (srw *statusResponseWriter) Flush() {
+	if f, ok := srw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// formatFloat formats a float as expected by both exposition formats
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
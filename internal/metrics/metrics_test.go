@@ -0,0 +1,163 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistryFormats(t *testing.T) {
+	m := New()
+	m.AddGauge("disk_free_bytes", "Free bytes.", func() (float64, bool) { return 1024, true })
+	m.AddGauge("disk_missing_bytes", "Missing value.", func() (float64, bool) { return 0, false })
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Write([]byte("ok"))
+	}))
+
+	requests := []struct {
+		method      string
+		path        string
+		traceparent string
+	}{
+		{method: http.MethodGet, path: "/", traceparent: "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01"},
+		{method: http.MethodGet, path: "/missing"},
+		{method: "PROPFIND", path: "/"},
+	}
+
+	for _, req := range requests {
+		r := httptest.NewRequest(req.method, req.path, nil)
+		if req.traceparent != "" {
+			r.Header.Set("Traceparent", req.traceparent)
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	tests := []struct {
+		name            string
+		accept          string
+		wantContentType string
+		wantContains    []string
+		wantMissing     []string
+	}{
+		{
+			name:            "prometheus text format by default",
+			accept:          "",
+			wantContentType: contentTypeText,
+			wantContains: []string{
+				"# TYPE http_requests_total counter\n",
+				`http_requests_total{method="GET",code="200"} 1`,
+				`http_requests_total{method="GET",code="404"} 1`,
+				`http_requests_total{method="other",code="200"} 1`,
+				`http_request_duration_seconds_bucket{le="+Inf"} 3`,
+				"http_request_duration_seconds_count 3\n",
+				"disk_free_bytes 1024\n",
+			},
+			wantMissing: []string{"# EOF", "trace_id", "disk_missing_bytes"},
+		},
+		{
+			name:            "openmetrics format with exemplars",
+			accept:          "application/openmetrics-text;version=1.0.0,text/plain;version=0.0.4;q=0.5",
+			wantContentType: contentTypeOpenMetrics,
+			wantContains: []string{
+				"# TYPE http_requests counter\n",
+				`http_requests_total{method="GET",code="200"} 1`,
+				`# {trace_id="0af7651916cd43dd8448eb211c80319c"}`,
+				"disk_free_bytes 1024\n",
+			},
+			wantMissing: []string{"# TYPE http_requests_total", "disk_missing_bytes"},
+		},
+		{
+			name:            "openmetrics explicitly rejected",
+			accept:          "application/openmetrics-text;q=0,text/plain",
+			wantContentType: contentTypeText,
+			wantMissing:     []string{"# EOF", "trace_id"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tt.accept != "" {
+				r.Header.Set("Accept", tt.accept)
+			}
+
+			rec := httptest.NewRecorder()
+			m.ServeHTTP(rec, r)
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("expected content type %q, got %q", tt.wantContentType, got)
+			}
+
+			body := rec.Body.String()
+			for _, s := range tt.wantContains {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected body to contain %q, got:\n%s", s, body)
+				}
+			}
+
+			for _, s := range tt.wantMissing {
+				if strings.Contains(body, s) {
+					t.Errorf("expected body not to contain %q, got:\n%s", s, body)
+				}
+			}
+
+			if tt.wantContentType == contentTypeOpenMetrics && !strings.HasSuffix(body, "# EOF\n") {
+				t.Errorf("expected openmetrics body to end with an EOF marker")
+			}
+		})
+	}
+}
+
+func Test_traceIDFromRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		traceparent string
+		want        string
+	}{
+		{
+			name:        "valid header",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+			want:        "4bf92f3577b34da6a3ce929d0e0e4736",
+		},
+		{
+			name:        "missing header",
+			traceparent: "",
+			want:        "",
+		},
+		{
+			name:        "uppercase hex is invalid",
+			traceparent: "00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+			want:        "",
+		},
+		{
+			name:        "all zeroes trace id",
+			traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+			want:        "",
+		},
+		{
+			name:        "wrong length",
+			traceparent: "00-4bf92f3577b34da6a3ce929d0e0e4736-01",
+			want:        "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tt.traceparent != "" {
+				r.Header.Set("Traceparent", tt.traceparent)
+			}
+
+			if got := traceIDFromRequest(r); got != tt.want {
+				t.Errorf("expected trace id %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
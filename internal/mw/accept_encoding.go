@@ -0,0 +1,69 @@
+package mw
+
+import (
+	"strconv"
+	"strings"
+)
+
+// QualityValue returns the quality value of an element of a header like
+// "Accept" or "Accept-Encoding", given its parameters, the part after the
+// first ";". Elements without a valid "q" parameter have a quality of 1,
+// and values outside of the 0 to 1 range are clamped to it.
+func QualityValue(params string) float64 {
+	for _, param := range strings.Split(params, ";") {
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+
+		q, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return 1
+		}
+
+		return min(max(q, 0), 1)
+	}
+
+	return 1
+}
+
+// acceptedEncodings maps the lowercased content codings listed in an
+// "Accept-Encoding" header, including "*", to their quality values
+type acceptedEncodings map[string]float64
+
+// parseAcceptEncoding parses an "Accept-Encoding" header. When a coding
+// is listed more than once, its highest quality value is kept.
+func parseAcceptEncoding(header string) acceptedEncodings {
+	accepted := make(acceptedEncodings)
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+
+		q := QualityValue(params)
+		if current, found := accepted[coding]; !found || q > current {
+			accepted[coding] = q
+		}
+	}
+
+	return accepted
+}
+
+// quality returns the quality value the client gives the coding, either
+// by name or through "*", which covers every coding not listed by name.
+// Codings not covered by either aren't acceptable, so they get 0.
+func (a acceptedEncodings) quality(coding string) float64 {
+	if q, found := a[coding]; found {
+		return q
+	}
+
+	return a["*"]
+}
+
+// AcceptsEncoding checks if the "Accept-Encoding" header accepts the
+// content coding, by name or through "*", without refusing it with "q=0"
+func AcceptsEncoding(acceptEncoding, coding string) bool {
+	return parseAcceptEncoding(acceptEncoding).quality(strings.ToLower(coding)) > 0
+}
@@ -0,0 +1,54 @@
+package mw
+
+import "testing"
+
+func TestQualityValue(t *testing.T) {
+	tests := []struct {
+		params string
+		want   float64
+	}{
+		{params: "", want: 1},
+		{params: "q=0", want: 0},
+		{params: "q=0.5", want: 0.5},
+		{params: " Q = 0.25 ", want: 0.25},
+		{params: "level=1;q=0.3", want: 0.3},
+		{params: "q=invalid", want: 1},
+		{params: "q=2", want: 1},
+		{params: "q=-1", want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.params, func(t *testing.T) {
+			if got := QualityValue(tt.params); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAcceptsEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		coding         string
+		want           bool
+	}{
+		{name: "listed", acceptEncoding: "gzip, deflate", coding: "gzip", want: true},
+		{name: "not listed", acceptEncoding: "deflate, br", coding: "gzip", want: false},
+		{name: "empty header", acceptEncoding: "", coding: "gzip", want: false},
+		{name: "wildcard", acceptEncoding: "*", coding: "gzip", want: true},
+		{name: "refused", acceptEncoding: "gzip;q=0", coding: "gzip", want: false},
+		{name: "refused despite wildcard", acceptEncoding: "*, gzip;q=0", coding: "gzip", want: false},
+		{name: "listed despite refused wildcard", acceptEncoding: "*;q=0, gzip;q=0.1", coding: "gzip", want: true},
+		{name: "highest quality of repeated codings", acceptEncoding: "gzip;q=0, gzip;q=0.5", coding: "gzip", want: true},
+		{name: "case insensitive", acceptEncoding: "GZIP", coding: "gzip", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := AcceptsEncoding(tt.acceptEncoding, tt.coding); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
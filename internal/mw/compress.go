@@ -0,0 +1,136 @@
+package mw
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/gzhttp"
+)
+
+// compressedExtensions are the extensions of file formats that are
+// already compressed, so compressing them again only wastes CPU time
+var compressedExtensions = map[string]bool{
+	// Images
+	".jpg": true, ".jpeg": true, ".png": true, ".gif": true, ".webp": true, ".avif": true, ".heic": true,
+	// Video and audio
+	".mp4": true, ".m4v": true, ".mkv": true, ".webm": true, ".mov": true, ".avi": true,
+	".mp3": true, ".m4a": true, ".aac": true, ".ogg": true, ".opus": true, ".flac": true,
+	// Archives and compressed files
+	".zip": true, ".gz": true, ".tgz": true, ".bz2": true, ".xz": true, ".zst": true,
+	".7z": true, ".rar": true, ".br": true, ".jar": true, ".apk": true,
+	// Fonts and documents using compression internally
+	".woff": true, ".woff2": true, ".pdf": true, ".docx": true, ".xlsx": true, ".pptx": true,
+}
+
+// CompressionAlgorithms are the content encodings responses can be
+// compressed with
+var CompressionAlgorithms = []string{"zstd", "gzip"}
+
+// Gzip is a middleware that compresses responses with gzip for clients
+// that support it. See Compress for the rules followed.
+func Gzip(noCompressUserAgents []string) (func(http.Handler) http.Handler, error) {
+	return Compress([]string{"gzip"}, noCompressUserAgents)
+}
+
+// Compress is a middleware that compresses responses for clients that support
+// it, with the first of the given algorithms, in order of preference, that the
+// client accepts. Since byte ranges refer to offsets in the uncompressed
+// content, requests with a "Range" header are never compressed, and compressed
+// responses don't advertise "Accept-Ranges", as their length differs from the
+// file size.
+//
+// Files of already compressed formats are never compressed, based on their
+// extension, even if their content type wasn't detected as such. Clients
+// whose "User-Agent" header contains any of the given patterns, compared
+// case-insensitively, are never sent compressed responses either, to work
+// around clients that mishandle them.
+func Compress(algorithms, noCompressUserAgents []string) (func(http.Handler) http.Handler, error) {
+	for _, algorithm := range algorithms {
+		if !slices.Contains(CompressionAlgorithms, algorithm) {
+			return nil, fmt.Errorf("unknown compression algorithm %q, use one of: %s", algorithm, strings.Join(CompressionAlgorithms, ", "))
+		}
+	}
+
+	wrapper, err := gzhttp.NewWrapper()
+	if err != nil {
+		return nil, err
+	}
+
+	patterns := make([]string, 0, len(noCompressUserAgents))
+	for _, p := range noCompressUserAgents {
+		if p = strings.ToLower(strings.TrimSpace(p)); p != "" {
+			patterns = append(patterns, p)
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		gzipped := wrapper(next)
+		zstdCompressed := zstdHandler(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Range") != "" || isCompressedFile(r.URL.Path) || matchesUserAgent(r.UserAgent(), patterns) {
+				w.Header().Add("Vary", "Accept-Encoding")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			switch negotiateEncoding(r.Header.Get("Accept-Encoding"), algorithms) {
+			case "zstd":
+				zstdCompressed.ServeHTTP(w, r)
+			case "gzip":
+				gzipped.ServeHTTP(w, r)
+			default:
+				w.Header().Add("Vary", "Accept-Encoding")
+				next.ServeHTTP(w, r)
+			}
+		})
+	}, nil
+}
+
+// negotiateEncoding returns the algorithm the client accepts with the
+// highest quality value, either by name or through "*", with ties going to
+// the earliest one in the list, the server's preference. Nothing is returned
+// when the client listed "identity" with a higher quality than any of them,
+// since it prefers the response uncompressed.
+func negotiateEncoding(acceptEncoding string, algorithms []string) string {
+	accepted := parseAcceptEncoding(acceptEncoding)
+
+	best, bestQ := "", 0.0
+	for _, algorithm := range algorithms {
+		if q := accepted.quality(algorithm); q > bestQ {
+			best, bestQ = algorithm, q
+		}
+	}
+
+	if q, found := accepted["identity"]; found && q > bestQ {
+		return ""
+	}
+
+	return best
+}
+
+// isCompressedFile checks if the requested path has the extension
+// of an already compressed file format
+func isCompressedFile(p string) bool {
+	return compressedExtensions[strings.ToLower(path.Ext(p))]
+}
+
+// matchesUserAgent checks if the user agent contains any of the
+// given lowercased patterns
+func matchesUserAgent(userAgent string, patterns []string) bool {
+	if userAgent == "" || len(patterns) == 0 {
+		return false
+	}
+
+	userAgent = strings.ToLower(userAgent)
+	for _, p := range patterns {
+		if strings.Contains(userAgent, p) {
+			return true
+		}
+	}
+
+	return false
+}
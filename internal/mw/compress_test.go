@@ -0,0 +1,418 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestGzipAndRanges(t *testing.T) {
+	content := strings.Repeat("compressible content ", 500)
+
+	gzip, err := Gzip(nil)
+	if err != nil {
+		t.Fatalf("unable to create gzip middleware: %s", err)
+	}
+
+	modtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		http.ServeContent(w, r, "file.txt", modtime, strings.NewReader(content))
+	}))
+
+	tests := []struct {
+		name             string
+		headers          map[string]string
+		wantStatus       int
+		wantEncoding     string
+		wantAcceptRanges string
+	}{
+		{
+			name:             "compressed response does not advertise ranges",
+			headers:          map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus:       http.StatusOK,
+			wantEncoding:     "gzip",
+			wantAcceptRanges: "",
+		},
+		{
+			name:             "range request is not compressed",
+			headers:          map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-99"},
+			wantStatus:       http.StatusPartialContent,
+			wantEncoding:     "",
+			wantAcceptRanges: "bytes",
+		},
+		{
+			name:             "ignored range still skips compression",
+			headers:          map[string]string{"Accept-Encoding": "gzip", "Range": "bytes=0-99", "If-Range": `"no-match"`},
+			wantStatus:       http.StatusOK,
+			wantEncoding:     "",
+			wantAcceptRanges: "bytes",
+		},
+		{
+			name:             "no compression support keeps ranges",
+			headers:          map[string]string{},
+			wantStatus:       http.StatusOK,
+			wantEncoding:     "",
+			wantAcceptRanges: "bytes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			if got := rec.Header().Get("Accept-Ranges"); got != tt.wantAcceptRanges {
+				t.Errorf("expected Accept-Ranges %q, got %q", tt.wantAcceptRanges, got)
+			}
+
+			if tt.wantStatus == http.StatusPartialContent && rec.Body.String() != content[:100] {
+				t.Errorf("expected partial body to match the uncompressed content")
+			}
+		})
+	}
+}
+
+func TestGzipBypass(t *testing.T) {
+	content := strings.Repeat("compressible content ", 500)
+
+	gzip, err := Gzip([]string{"MSIE 6.", " QuirkyClient "})
+	if err != nil {
+		t.Fatalf("unable to create gzip middleware: %s", err)
+	}
+
+	handler := gzip(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Pretend the content type of every file was detected as text
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.Write([]byte(content))
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		userAgent    string
+		wantEncoding string
+	}{
+		{
+			name:         "text file compressed",
+			path:         "/file.txt",
+			userAgent:    "Mozilla/5.0 (X11; Linux x86_64)",
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "denied user agent not compressed",
+			path:         "/file.txt",
+			userAgent:    "Mozilla/4.0 (compatible; MSIE 6.0; Windows NT 5.1)",
+			wantEncoding: "",
+		},
+		{
+			name:         "user agent patterns are case insensitive",
+			path:         "/file.txt",
+			userAgent:    "quirkyclient/1.0",
+			wantEncoding: "",
+		},
+		{
+			name:         "compressed image not compressed",
+			path:         "/photo.JPG",
+			wantEncoding: "",
+		},
+		{
+			name:         "archive not compressed",
+			path:         "/backup.tar.gz",
+			wantEncoding: "",
+		},
+		{
+			name:         "video not compressed",
+			path:         "/movie.mp4",
+			wantEncoding: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Accept-Encoding", "gzip")
+			req.Header.Set("User-Agent", tt.userAgent)
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Errorf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary header %q, got %q", "Accept-Encoding", got)
+			}
+
+			if tt.wantEncoding == "" && rec.Body.String() != content {
+				t.Errorf("expected uncompressed body to match the content")
+			}
+		})
+	}
+}
+
+func TestCompressZstd(t *testing.T) {
+	content := strings.Repeat("compressible content ", 500)
+
+	compress, err := Compress([]string{"zstd", "gzip"}, nil)
+	if err != nil {
+		t.Fatalf("unable to create compression middleware: %s", err)
+	}
+
+	modtime := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	handler := compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/small.txt":
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			w.Write([]byte("too small to compress"))
+		case "/song.ogg":
+			w.Header().Set("Content-Type", "audio/ogg")
+			w.Write([]byte(content))
+		default:
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			http.ServeContent(w, r, "file.txt", modtime, strings.NewReader(content))
+		}
+	}))
+
+	tests := []struct {
+		name         string
+		path         string
+		headers      map[string]string
+		wantStatus   int
+		wantEncoding string
+	}{
+		{
+			name:         "zstd preferred when accepted",
+			path:         "/file.txt",
+			headers:      map[string]string{"Accept-Encoding": "gzip, deflate, br, zstd"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "zstd",
+		},
+		{
+			name:         "gzip used when zstd isn't accepted",
+			path:         "/file.txt",
+			headers:      map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "zstd refused with a zero quality",
+			path:         "/file.txt",
+			headers:      map[string]string{"Accept-Encoding": "zstd;q=0, gzip"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "gzip",
+		},
+		{
+			name:         "small responses not compressed",
+			path:         "/small.txt",
+			headers:      map[string]string{"Accept-Encoding": "zstd"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "",
+		},
+		{
+			name:         "compressed content types not compressed",
+			path:         "/song.ogg",
+			headers:      map[string]string{"Accept-Encoding": "zstd"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "",
+		},
+		{
+			name:         "range request is not compressed",
+			path:         "/file.txt",
+			headers:      map[string]string{"Accept-Encoding": "zstd", "Range": "bytes=0-99"},
+			wantStatus:   http.StatusPartialContent,
+			wantEncoding: "",
+		},
+		{
+			name:         "conditional request not compressed",
+			path:         "/file.txt",
+			headers:      map[string]string{"Accept-Encoding": "zstd", "If-Modified-Since": modtime.Format(http.TimeFormat)},
+			wantStatus:   http.StatusNotModified,
+			wantEncoding: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected Content-Encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			if got := rec.Header().Get("Vary"); got != "Accept-Encoding" {
+				t.Errorf("expected Vary header %q, got %q", "Accept-Encoding", got)
+			}
+
+			if tt.wantEncoding != "zstd" {
+				return
+			}
+
+			if got := rec.Header().Get("Accept-Ranges"); got != "" {
+				t.Errorf("expected no Accept-Ranges header, got %q", got)
+			}
+
+			if got := rec.Header().Get("Content-Length"); got != "" {
+				t.Errorf("expected no Content-Length header, got %q", got)
+			}
+
+			dec, err := zstd.NewReader(rec.Body)
+			if err != nil {
+				t.Fatalf("unable to decompress response: %s", err)
+			}
+			defer dec.Close()
+
+			body, err := io.ReadAll(dec)
+			if err != nil {
+				t.Fatalf("unable to decompress response: %s", err)
+			}
+
+			if string(body) != content {
+				t.Errorf("expected decompressed body to match the content")
+			}
+		})
+	}
+}
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		algorithms     []string
+		want           string
+	}{
+		{
+			name:           "server preference wins",
+			acceptEncoding: "gzip, zstd",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "zstd",
+		},
+		{
+			name:           "server preference wins over client order",
+			acceptEncoding: "zstd, gzip",
+			algorithms:     []string{"gzip", "zstd"},
+			want:           "gzip",
+		},
+		{
+			name:           "algorithm not allowed",
+			acceptEncoding: "zstd",
+			algorithms:     []string{"gzip"},
+			want:           "",
+		},
+		{
+			name:           "wildcard",
+			acceptEncoding: "*",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "zstd",
+		},
+		{
+			name:           "wildcard with explicit refusal",
+			acceptEncoding: "zstd;q=0, *",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "gzip",
+		},
+		{
+			name:           "case insensitive",
+			acceptEncoding: "ZSTD",
+			algorithms:     []string{"zstd"},
+			want:           "zstd",
+		},
+		{
+			name:           "nothing accepted",
+			acceptEncoding: "",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "",
+		},
+		{
+			name:           "client quality wins over server preference",
+			acceptEncoding: "zstd;q=0.5, gzip;q=1",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "gzip",
+		},
+		{
+			name:           "refused algorithm with an unsupported one preferred",
+			acceptEncoding: "gzip;q=0, br;q=1",
+			algorithms:     []string{"gzip"},
+			want:           "",
+		},
+		{
+			name:           "wildcard with a lower quality than a listed algorithm",
+			acceptEncoding: "*;q=0.1, gzip;q=0.8",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "gzip",
+		},
+		{
+			name:           "refused wildcard",
+			acceptEncoding: "*;q=0",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "",
+		},
+		{
+			name:           "identity preferred over compression",
+			acceptEncoding: "gzip;q=0.5, identity",
+			algorithms:     []string{"gzip"},
+			want:           "",
+		},
+		{
+			name:           "refused identity",
+			acceptEncoding: "identity;q=0, gzip;q=0.1",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "gzip",
+		},
+		{
+			name:           "quality with spaces and uppercase",
+			acceptEncoding: "zstd ; Q=0 , gzip",
+			algorithms:     []string{"zstd", "gzip"},
+			want:           "gzip",
+		},
+		{
+			name:           "invalid quality counts as 1",
+			acceptEncoding: "zstd;q=high",
+			algorithms:     []string{"zstd"},
+			want:           "zstd",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiateEncoding(tt.acceptEncoding, tt.algorithms); got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestCompressUnknownAlgorithm(t *testing.T) {
+	if _, err := Compress([]string{"br"}, nil); err == nil {
+		t.Fatal("expected an error for an unknown algorithm")
+	}
+}
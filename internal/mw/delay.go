@@ -0,0 +1,26 @@
+package mw
+
+import (
+	"net/http"
+	"time"
+)
+
+// Delay is a middleware that waits for the given duration before handing
+// the request to the next handler, so nothing is written to the client,
+// headers included, until the delay is over. It's meant to test how
+// clients cope with slow servers. If the client goes away while waiting,
+// the request is dropped without a response.
+func Delay(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timer := time.NewTimer(d)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+				next.ServeHTTP(w, r)
+			case <-r.Context().Done():
+			}
+		})
+	}
+}
@@ -0,0 +1,47 @@
+package mw
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestDelay(t *testing.T) {
+	var called bool
+	h := Delay(50 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	t.Run("response is delayed", func(t *testing.T) {
+		called = false
+		start := time.Now()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+			t.Errorf("expected response to be delayed by at least 50ms, took %s", elapsed)
+		}
+
+		if !called || rec.Code != http.StatusOK {
+			t.Errorf("expected the next handler to be called after the delay")
+		}
+	})
+
+	t.Run("delay is interrupted when the client goes away", func(t *testing.T) {
+		called = false
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+		if called {
+			t.Errorf("expected the next handler not to be called for a canceled request")
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package mw
+
+import (
+	"net/http"
+	"strings"
+)
+
+// RedirectDuplicateSlashes is a middleware that redirects requests whose
+// path has consecutive slashes, such as "/a//b///c.txt", to the same path
+// with single slashes, keeping the query string, so every resource has a
+// single URL. Requests for which skip returns true are let through as
+// they are.
+func RedirectDuplicateSlashes(statusCode int, skip func(*http.Request) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// The decoded path can't be used in the redirection, since
+			// file names with characters such as "%" would be decoded
+			// again by the client
+			escaped := r.URL.EscapedPath()
+			if !strings.Contains(escaped, "//") || skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			location := mergeSlashes(escaped)
+			if r.URL.RawQuery != "" {
+				location += "?" + r.URL.RawQuery
+			}
+
+			http.Redirect(w, r, location, statusCode)
+		})
+	}
+}
+
+// mergeSlashes replaces every run of consecutive slashes with a single one
+func mergeSlashes(p string) string {
+	var sb strings.Builder
+	sb.Grow(len(p))
+
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && i > 0 && p[i-1] == '/' {
+			continue
+		}
+
+		sb.WriteByte(p[i])
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,91 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRedirectDuplicateSlashes(t *testing.T) {
+	tests := []struct {
+		name         string
+		target       string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:       "clean path",
+			target:     "/a/b/c.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "double slash",
+			target:       "/a//b/c.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a/b/c.txt",
+		},
+		{
+			name:         "several runs of slashes",
+			target:       "/a//b///c.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a/b/c.txt",
+		},
+		{
+			name:         "leading slashes",
+			target:       "///a/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a/",
+		},
+		{
+			name:         "trailing slashes",
+			target:       "/a/b//",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a/b/",
+		},
+		{
+			name:         "query string is kept",
+			target:       "/a//b/?sort=name&filter=*.pdf",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a/b/?sort=name&filter=*.pdf",
+		},
+		{
+			name:         "escaped characters are kept",
+			target:       "/100%25//report%20final.pdf",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/100%25/report%20final.pdf",
+		},
+		{
+			name:         "encoded slashes are not merged",
+			target:       "/a%2F%2Fb//c",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/a%2F%2Fb/c",
+		},
+		{
+			name:       "skipped paths",
+			target:     "/_//health",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	handler := RedirectDuplicateSlashes(http.StatusMovedPermanently, func(r *http.Request) bool {
+		return strings.HasPrefix(r.URL.Path, "/_/")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
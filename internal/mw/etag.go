@@ -17,10 +17,12 @@ var bufPool = sync.Pool{
 }
 
 type etagResponseWriter struct {
-	hash    hash.Hash
-	headers map[string][]string
-	buf     *bytes.Buffer
-	status  int
+	rw        http.ResponseWriter
+	hash      hash.Hash
+	headers   map[string][]string
+	buf       *bytes.Buffer
+	status    int
+	streaming bool
 }
 
 // Header returns the header map that will be sent by WriteHeader
@@ -41,6 +43,11 @@ func (e *etagResponseWriter) Write(p []byte) (int, error) {
 		e.status = http.StatusOK
 	}
 
+	// Once streaming, data goes straight to the client
+	if e.streaming {
+		return e.rw.Write(p)
+	}
+
 	// Write the data to the hash for ETag calculation
 	e.hash.Write(p)
 
@@ -48,11 +55,44 @@ func (e *etagResponseWriter) Write(p []byte) (int, error) {
 	return e.buf.Write(p)
 }
 
+// Flush switches the writer to pass-through mode: a handler that flushes
+// is streaming its response, so it can't be held back to compute an ETag.
+// Anything buffered so far is sent to the client along with the headers.
+func (e *etagResponseWriter) Flush() {
+	if !e.streaming {
+		e.streaming = true
+		e.writeTo(e.rw)
+	}
+
+	if f, ok := e.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// writeTo copies the buffered headers, status code and body
+// to the given response writer
+func (e *etagResponseWriter) writeTo(w http.ResponseWriter) {
+	for key, vals := range e.headers {
+		for _, val := range vals {
+			w.Header().Add(key, val)
+		}
+	}
+
+	if e.status == 0 {
+		e.status = http.StatusOK
+	}
+
+	w.WriteHeader(e.status)
+	w.Write(e.buf.Bytes())
+}
+
 // Etag middleware
 func Etag(enabled bool) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if !enabled {
+			// HEAD requests have no body to hash, so an ETag computed from
+			// them wouldn't match the one produced by the equivalent GET
+			if !enabled || r.Method == http.MethodHead {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -64,6 +104,7 @@ func Etag(enabled bool) func(next http.Handler) http.Handler {
 			}()
 
 			alternateWriter := &etagResponseWriter{
+				rw:      w,
 				headers: http.Header{},
 				buf:     buf,
 				hash:    sha1.New(),
@@ -72,8 +113,18 @@ func Etag(enabled bool) func(next http.Handler) http.Handler {
 			// Call the next handler and stream the data while hashing
 			next.ServeHTTP(alternateWriter, r)
 
-			// If the status is in the range of 200-399, calculate ETag
-			if alternateWriter.status >= http.StatusOK && alternateWriter.status < http.StatusBadRequest {
+			// If the handler streamed its response, everything
+			// has been sent already
+			if alternateWriter.streaming {
+				return
+			}
+
+			// If the status is in the range of 200-399, calculate ETag, unless
+			// the handler already provided one on its own or the response is
+			// partial, since the ETag has to describe the full content
+			hasEtag := alternateWriter.Header().Get("Etag") != ""
+			isPartial := alternateWriter.status == http.StatusPartialContent
+			if !hasEtag && !isPartial && alternateWriter.status >= http.StatusOK && alternateWriter.status < http.StatusBadRequest {
 				etag := fmt.Sprintf("%q", hex.EncodeToString(alternateWriter.hash.Sum(nil)))
 				alternateWriter.Header().Set("Etag", etag)
 
@@ -84,13 +135,7 @@ func Etag(enabled bool) func(next http.Handler) http.Handler {
 			}
 
 			// Pass the response to the actual response writer
-			for key, vals := range alternateWriter.headers {
-				for _, val := range vals {
-					w.Header().Add(key, val)
-				}
-			}
-			w.WriteHeader(alternateWriter.status)
-			w.Write(alternateWriter.buf.Bytes())
+			alternateWriter.writeTo(w)
 		})
 	}
 }
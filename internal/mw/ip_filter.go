@@ -0,0 +1,87 @@
+package mw
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ParseCIDRs parses a list of CIDR ranges, such as "10.0.0.0/8" or
+// "fd00::/8", returning an error pointing at the first invalid one
+func ParseCIDRs(cidrs []string) ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(cidrs))
+
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR range %q: %w", cidr, err)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return prefixes, nil
+}
+
+// IPFilter is a middleware that rejects requests with a 403 status code if
+// the client IP is in one of the denied ranges or, when allowed ranges are
+// given, if it's not in any of them. When trustProxy is set, the client IP
+// is taken from the "X-Forwarded-For" header set by the reverse proxy.
+func IPFilter(allowed, denied []netip.Prefix, trustProxy bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip, ok := clientIP(r, trustProxy)
+			if !ok || !isIPAllowed(ip, allowed, denied) {
+				http.Error(w, "403 forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isIPAllowed checks the IP against the denied ranges first, then
+// against the allowed ranges, if there are any
+func isIPAllowed(ip netip.Addr, allowed, denied []netip.Prefix) bool {
+	for _, prefix := range denied {
+		if prefix.Contains(ip) {
+			return false
+		}
+	}
+
+	if len(allowed) == 0 {
+		return true
+	}
+
+	for _, prefix := range allowed {
+		if prefix.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// clientIP returns the IP address of the client. When the proxy is trusted,
+// the last address in "X-Forwarded-For" is used, since it's the one added by
+// the proxy itself, while previous ones could have been sent by the client.
+func clientIP(r *http.Request, trustProxy bool) (netip.Addr, bool) {
+	if trustProxy {
+		if header := r.Header.Values("X-Forwarded-For"); len(header) > 0 {
+			forwarded := strings.Split(header[len(header)-1], ",")
+			ip, err := netip.ParseAddr(strings.TrimSpace(forwarded[len(forwarded)-1]))
+			return ip.Unmap(), err == nil
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	ip, err := netip.ParseAddr(host)
+	return ip.Unmap(), err == nil
+}
@@ -0,0 +1,137 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestIPFilter(t *testing.T) {
+	tests := []struct {
+		name          string
+		allow         []string
+		deny          []string
+		trustProxy    bool
+		remoteAddr    string
+		forwardedFor  string
+		wantForbidden bool
+	}{
+		{
+			name:       "no ranges allow everyone",
+			remoteAddr: "203.0.113.10:1234",
+		},
+		{
+			name:       "ipv4 in allowed range",
+			allow:      []string{"10.0.0.0/8"},
+			remoteAddr: "10.1.2.3:1234",
+		},
+		{
+			name:          "ipv4 outside allowed range",
+			allow:         []string{"10.0.0.0/8"},
+			remoteAddr:    "192.168.1.1:1234",
+			wantForbidden: true,
+		},
+		{
+			name:          "deny takes precedence over allow",
+			allow:         []string{"10.0.0.0/8"},
+			deny:          []string{"10.0.0.0/24"},
+			remoteAddr:    "10.0.0.5:1234",
+			wantForbidden: true,
+		},
+		{
+			name:       "ipv4 outside denied range",
+			deny:       []string{"10.0.0.0/24"},
+			remoteAddr: "10.0.1.5:1234",
+		},
+		{
+			name:       "ipv6 in allowed range",
+			allow:      []string{"fd00::/8"},
+			remoteAddr: "[fd12:3456::1]:1234",
+		},
+		{
+			name:          "ipv6 in denied range",
+			deny:          []string{"2001:db8::/32"},
+			remoteAddr:    "[2001:db8::1]:1234",
+			wantForbidden: true,
+		},
+		{
+			name:       "ipv4-mapped ipv6 matches ipv4 range",
+			allow:      []string{"10.0.0.0/8"},
+			remoteAddr: "[::ffff:10.0.0.1]:1234",
+		},
+		{
+			name:          "forwarded header ignored without trusting the proxy",
+			allow:         []string{"10.0.0.0/8"},
+			remoteAddr:    "192.168.1.1:1234",
+			forwardedFor:  "10.0.0.1",
+			wantForbidden: true,
+		},
+		{
+			name:         "forwarded header used when trusting the proxy",
+			allow:        []string{"10.0.0.0/8"},
+			trustProxy:   true,
+			remoteAddr:   "192.168.1.1:1234",
+			forwardedFor: "10.0.0.1",
+		},
+		{
+			name:          "only the address added by the proxy is used",
+			allow:         []string{"10.0.0.0/8"},
+			trustProxy:    true,
+			remoteAddr:    "192.168.1.1:1234",
+			forwardedFor:  "10.0.0.1, 203.0.113.10",
+			wantForbidden: true,
+		},
+		{
+			name:          "invalid forwarded address is rejected",
+			trustProxy:    true,
+			remoteAddr:    "10.0.0.1:1234",
+			forwardedFor:  "not-an-ip",
+			wantForbidden: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			allowed, err := ParseCIDRs(tt.allow)
+			if err != nil {
+				t.Fatalf("unable to parse allowed ranges: %s", err)
+			}
+
+			denied, err := ParseCIDRs(tt.deny)
+			if err != nil {
+				t.Fatalf("unable to parse denied ranges: %s", err)
+			}
+
+			handler := IPFilter(allowed, denied, tt.trustProxy)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Code == http.StatusForbidden; got != tt.wantForbidden {
+				t.Errorf("expected forbidden to be %v, got status %d", tt.wantForbidden, rec.Code)
+			}
+		})
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	if _, err := ParseCIDRs([]string{"10.0.0.0/8", "fd00::/8"}); err != nil {
+		t.Errorf("expected valid ranges to parse, got: %s", err)
+	}
+
+	if _, err := ParseCIDRs([]string{"10.0.0.0/8", "10.0.0.0/33"}); err == nil {
+		t.Errorf("expected an error for an invalid range")
+	}
+
+	if _, err := ParseCIDRs([]string{"10.0.0.1"}); err == nil {
+		t.Errorf("expected an error for an address without a prefix length")
+	}
+}
@@ -0,0 +1,108 @@
+package mw
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LogLevel is the severity of a log line
+type LogLevel int
+
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// logLevelNames are the names log levels are configured with
+var logLevelNames = map[string]LogLevel{
+	"debug": LogDebug,
+	"info":  LogInfo,
+	"warn":  LogWarn,
+	"error": LogError,
+}
+
+// String returns the name of the log level
+func (l LogLevel) String() string {
+	for name, level := range logLevelNames {
+		if level == l {
+			return name
+		}
+	}
+
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// ParseLogLevel returns the log level with the given name
+func ParseLogLevel(name string) (LogLevel, error) {
+	level, found := logLevelNames[strings.ToLower(strings.TrimSpace(name))]
+	if !found {
+		return 0, fmt.Errorf("unknown log level %q, use one of: debug, info, warn, error", name)
+	}
+
+	return level, nil
+}
+
+// LogLevels decides the level requests are logged at based on the class
+// of their status code, such as "4xx", and which levels are logged at all.
+// By default, informational, successful and redirection responses are
+// logged as info, client errors as warnings, and server errors as errors.
+type LogLevels struct {
+	minimum LogLevel
+	classes [6]LogLevel
+}
+
+// NewLogLevels creates the log levels from the minimum level to log and
+// a list of status code classes mapped to a level, such as "4xx=info",
+// which override the default level of those classes
+func NewLogLevels(minimum string, statusLevels []string) (*LogLevels, error) {
+	levels := &LogLevels{
+		classes: [6]LogLevel{LogInfo, LogInfo, LogInfo, LogInfo, LogWarn, LogError},
+	}
+
+	if minimum != "" {
+		level, err := ParseLogLevel(minimum)
+		if err != nil {
+			return nil, err
+		}
+
+		levels.minimum = level
+	} else {
+		levels.minimum = LogInfo
+	}
+
+	for _, spec := range statusLevels {
+		class, name, found := strings.Cut(spec, "=")
+		class = strings.ToLower(strings.TrimSpace(class))
+
+		if !found || len(class) != 3 || class[0] < '1' || class[0] > '5' || class[1:] != "xx" {
+			return nil, fmt.Errorf("invalid status level %q, use a status code class and a level, such as \"4xx=info\"", spec)
+		}
+
+		level, err := ParseLogLevel(name)
+		if err != nil {
+			return nil, err
+		}
+
+		levels.classes[class[0]-'0'] = level
+	}
+
+	return levels, nil
+}
+
+// ForStatus returns the level a response with the status code is logged at
+func (l *LogLevels) ForStatus(statusCode int) LogLevel {
+	class := statusCode / 100
+	if class < 1 || class > 5 {
+		return LogError
+	}
+
+	return l.classes[class]
+}
+
+// Enabled reports whether lines at the given level are logged. A nil
+// set of levels logs everything.
+func (l *LogLevels) Enabled(level LogLevel) bool {
+	return l == nil || level >= l.minimum
+}
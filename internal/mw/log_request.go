@@ -1,6 +1,7 @@
 package mw
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -29,8 +30,36 @@ func (lrw *logResponseWriter) WriteHeader(statusCode int) {
 	lrw.statusCode = statusCode
 }
 
-// LogRequest middleware
-func LogRequest(output io.Writer, format string, redactedQuerystringFields ...string) func(http.Handler) http.Handler {
+func (lrw *logResponseWriter) Flush() {
+	if f, ok := lrw.rw.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// servedPathKey is the context key holding the filesystem path
+// served for the request, if any
+type servedPathKey struct{}
+
+// servedPathHolder is stored in the request context so handlers down
+// the chain can report the filesystem path they served
+type servedPathHolder struct {
+	path string
+}
+
+// SetServedPath records the filesystem path served for the request, so
+// it can be logged through the "{served_path}" placeholder
+func SetServedPath(r *http.Request, path string) {
+	if holder, ok := r.Context().Value(servedPathKey{}).(*servedPathHolder); ok {
+		holder.path = path
+	}
+}
+
+// LogRequest middleware. The "{served_path}" placeholder is only filled for
+// error responses if logServedPathOnErrors is set, since it could expose
+// internal paths for requests that were denied or not found. Requests are
+// logged at the level matching their status code, available through the
+// "{log_level}" placeholder, and skipped if that level isn't enabled.
+func LogRequest(output io.Writer, format string, logServedPathOnErrors bool, levels *LogLevels, redactedQuerystringFields ...string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			start := time.Now()
@@ -40,6 +69,10 @@ func LogRequest(output io.Writer, format string, redactedQuerystringFields ...st
 				rw: w,
 			}
 
+			// Allow handlers to report the path they served
+			holder := &servedPathHolder{}
+			r = r.WithContext(context.WithValue(r.Context(), servedPathKey{}, holder))
+
 			// Call the next middleware or handler
 			next.ServeHTTP(lrw, r)
 
@@ -61,6 +94,22 @@ func LogRequest(output io.Writer, format string, redactedQuerystringFields ...st
 				statusCode = http.StatusOK
 			}
 
+			// Skip requests logged at a level that isn't enabled
+			level := LogInfo
+			if levels != nil {
+				level = levels.ForStatus(statusCode)
+			}
+
+			if !levels.Enabled(level) {
+				return
+			}
+
+			// Get the served path, if it's allowed to be logged
+			servedPath := holder.path
+			if servedPath == "" || (statusCode >= http.StatusBadRequest && !logServedPathOnErrors) {
+				servedPath = "-"
+			}
+
 			// Log the request details
 			s := strings.NewReplacer(
 				"{http_method}", r.Method,
@@ -70,9 +119,13 @@ func LogRequest(output io.Writer, format string, redactedQuerystringFields ...st
 				"{status_text}", http.StatusText(statusCode),
 				"{duration}", time.Since(start).String(),
 				"{bytes_written}", fmt.Sprintf("%d", lrw.bytesWritten),
+				"{served_path}", servedPath,
+				"{log_level}", level.String(),
 			).Replace(format)
 
-			fmt.Fprintln(output, s)
+			// Empty placeholders at the end of the format can leave
+			// trailing whitespace, and each request takes one line
+			fmt.Fprintln(output, strings.TrimRight(s, " \t\r\n"))
 		})
 	}
 }
@@ -0,0 +1,185 @@
+package mw
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogRequestServedPath(t *testing.T) {
+	tests := []struct {
+		name          string
+		statusCode    int
+		servedPath    string
+		includeErrors bool
+		want          string
+	}{
+		{
+			name:       "served path logged for successful requests",
+			statusCode: http.StatusOK,
+			servedPath: "/srv/site/index.html",
+			want:       "200 /srv/site/index.html",
+		},
+		{
+			name:       "served path not set",
+			statusCode: http.StatusOK,
+			want:       "200 -",
+		},
+		{
+			name:       "served path hidden for errors by default",
+			statusCode: http.StatusNotFound,
+			servedPath: "/srv/site/missing.html",
+			want:       "404 -",
+		},
+		{
+			name:          "served path logged for errors when enabled",
+			statusCode:    http.StatusNotFound,
+			servedPath:    "/srv/site/missing.html",
+			includeErrors: true,
+			want:          "404 /srv/site/missing.html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			handler := LogRequest(&buf, "{status_code} {served_path}", tt.includeErrors, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.servedPath != "" {
+					SetServedPath(r, tt.servedPath)
+				}
+				w.WriteHeader(tt.statusCode)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := strings.TrimSpace(buf.String()); got != tt.want {
+				t.Errorf("expected log line %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestLogRequestLevels(t *testing.T) {
+	tests := []struct {
+		name         string
+		minimum      string
+		statusLevels []string
+		statusCode   int
+		want         string
+	}{
+		{
+			name:       "successful requests are info by default",
+			statusCode: http.StatusOK,
+			want:       "200 info",
+		},
+		{
+			name:       "client errors are warnings by default",
+			statusCode: http.StatusNotFound,
+			want:       "404 warn",
+		},
+		{
+			name:       "server errors are errors by default",
+			statusCode: http.StatusInternalServerError,
+			want:       "500 error",
+		},
+		{
+			name:         "status class mapped to a different level",
+			statusLevels: []string{"4xx=info"},
+			statusCode:   http.StatusNotFound,
+			want:         "404 info",
+		},
+		{
+			name:       "levels below the minimum are skipped",
+			minimum:    "warn",
+			statusCode: http.StatusOK,
+			want:       "",
+		},
+		{
+			name:         "debug requests are skipped by default",
+			statusLevels: []string{"3xx=debug"},
+			statusCode:   http.StatusMovedPermanently,
+			want:         "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			levels, err := NewLogLevels(tt.minimum, tt.statusLevels)
+			if err != nil {
+				t.Fatalf("unable to create log levels: %s", err)
+			}
+
+			var buf bytes.Buffer
+			handler := LogRequest(&buf, "{status_code} {log_level}", false, levels)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			}))
+
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := strings.TrimSpace(buf.String()); got != tt.want {
+				t.Errorf("expected log line %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewLogLevelsErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		minimum      string
+		statusLevels []string
+	}{
+		{name: "unknown minimum level", minimum: "verbose"},
+		{name: "unknown status class", statusLevels: []string{"6xx=info"}},
+		{name: "missing level", statusLevels: []string{"4xx"}},
+		{name: "unknown level", statusLevels: []string{"4xx=loud"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewLogLevels(tt.minimum, tt.statusLevels); err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestLogRequestTrailingWhitespace(t *testing.T) {
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{
+			name:   "trailing spaces trimmed",
+			format: "{status_code}   ",
+			want:   "200\n",
+		},
+		{
+			name:   "trailing newline not repeated",
+			format: "{status_code}\n",
+			want:   "200\n",
+		},
+		{
+			name:   "leading whitespace kept",
+			format: "  {status_code} \t",
+			want:   "  200\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			handler := LogRequest(&buf, tt.format, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("expected log output %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,22 @@
+package mw
+
+import "net/http"
+
+// MaxBodySize is a middleware that limits the size of request bodies, so
+// no handler can be forced to read an unbounded amount of input. Requests
+// announcing a bigger body through their "Content-Length" header are
+// rejected upfront, while bodies without a length fail to be read once
+// they go over the limit.
+func MaxBodySize(limit int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength > limit {
+				http.Error(w, "413 request entity too large", http.StatusRequestEntityTooLarge)
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySize(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          string
+		unknownLength bool
+		wantStatus    int
+	}{
+		{
+			name:       "no body",
+			body:       "",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "body within the limit",
+			body:       strings.Repeat("a", 10),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "body over the limit",
+			body:       strings.Repeat("a", 11),
+			wantStatus: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name:          "body without a length within the limit",
+			body:          strings.Repeat("a", 10),
+			unknownLength: true,
+			wantStatus:    http.StatusOK,
+		},
+		{
+			name:          "body without a length over the limit",
+			body:          strings.Repeat("a", 11),
+			unknownLength: true,
+			wantStatus:    http.StatusRequestEntityTooLarge,
+		},
+	}
+
+	handler := MaxBodySize(10)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := io.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			if tt.unknownLength {
+				req.ContentLength = -1
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
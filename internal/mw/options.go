@@ -0,0 +1,31 @@
+package mw
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Options is a middleware that answers OPTIONS requests, either for a path
+// or for the whole server with "OPTIONS *", with a "204 No Content" status
+// code and the given headers describing what the server supports. The
+// "Allow" header lists the allowed verbs, like VerbsAllowed does, so both
+// stay consistent.
+func Options(allowedVerbs []string, headers http.Header) func(http.Handler) http.Handler {
+	allowHeader := strings.Join(allowedVerbs, ", ")
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			for k, v := range headers {
+				w.Header()[k] = v
+			}
+
+			w.Header().Set("Allow", allowHeader)
+			w.WriteHeader(http.StatusNoContent)
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOptions(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		target     string
+		wantStatus int
+		wantAllow  string
+	}{
+		{
+			name:       "options for a path",
+			method:     http.MethodOptions,
+			target:     "/file.txt",
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "GET, HEAD, OPTIONS",
+		},
+		{
+			name:       "options for the server",
+			method:     http.MethodOptions,
+			target:     "*",
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "GET, HEAD, OPTIONS",
+		},
+		{
+			name:       "other methods pass through",
+			method:     http.MethodGet,
+			target:     "/file.txt",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	headers := http.Header{"Accept-Ranges": {"bytes"}}
+	handler := Options([]string{http.MethodGet, http.MethodHead, http.MethodOptions}, headers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.target, nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("expected Allow header %q, got %q", tt.wantAllow, got)
+			}
+
+			if tt.method == http.MethodOptions && rec.Header().Get("Accept-Ranges") != "bytes" {
+				t.Errorf("expected Accept-Ranges header %q, got %q", "bytes", rec.Header().Get("Accept-Ranges"))
+			}
+		})
+	}
+}
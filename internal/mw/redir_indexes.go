@@ -16,7 +16,10 @@ func RedirectIndexes(statusCode int) func(http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			for _, index := range indexes {
 				if strings.HasSuffix(r.URL.Path, index) {
-					http.Redirect(w, r, strings.TrimSuffix(r.URL.Path, index), statusCode)
+					// The decoded path can't be used in the redirection, since
+					// file names with characters such as "%" would be decoded
+					// again by the client
+					http.Redirect(w, r, strings.TrimSuffix(r.URL.EscapedPath(), index), statusCode)
 					return
 				}
 			}
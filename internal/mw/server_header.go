@@ -0,0 +1,15 @@
+package mw
+
+import "net/http"
+
+// ServerHeader is a middleware that sets the "Server" header on every
+// response to the given value. The standard library doesn't send the
+// header on its own, so leaving this middleware out suppresses it.
+func ServerHeader(value string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Server", value)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
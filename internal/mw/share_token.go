@@ -0,0 +1,150 @@
+package mw
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShareParam is the query string parameter holding a share token
+const ShareParam = "share"
+
+// shareCookie keeps the share token once a shared directory is opened,
+// so the links in its listing, which don't carry it, keep working
+const shareCookie = "http_server_share"
+
+// SignShare returns a token granting access to the given URL path until
+// the expiry time. Paths ending with a slash grant access to everything
+// under them, while any other path grants access to that path only.
+func SignShare(secret, urlPath string, expires time.Time) string {
+	exp := strconv.FormatInt(expires.Unix(), 10)
+	return exp + "." + shareSignature(secret, urlPath, exp)
+}
+
+// shareSignature computes the HMAC of the path and the expiry time
+func shareSignature(secret, urlPath, exp string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(urlPath + "\n" + exp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verifyShare checks the token against the path it was signed for,
+// returning when it expires
+func verifyShare(secret, urlPath, token string) (time.Time, error) {
+	exp, sig, found := strings.Cut(token, ".")
+	if !found {
+		return time.Time{}, fmt.Errorf("malformed token")
+	}
+
+	if !hmac.Equal([]byte(sig), []byte(shareSignature(secret, urlPath, exp))) {
+		return time.Time{}, fmt.Errorf("invalid signature")
+	}
+
+	unix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed expiry time")
+	}
+
+	expires := time.Unix(unix, 0)
+	if !time.Now().Before(expires) {
+		return time.Time{}, fmt.Errorf("token expired at %s", expires.UTC().Format(time.RFC3339))
+	}
+
+	return expires, nil
+}
+
+// shareCovers reports whether a token signed for the shared path
+// grants access to the requested one
+func shareCovers(shared, requested string) bool {
+	if strings.HasSuffix(shared, "/") {
+		return strings.HasPrefix(requested, shared)
+	}
+
+	return shared == requested
+}
+
+// cleanSharePath resolves any "." and ".." segments in the requested
+// path, keeping its trailing slash, so a path can't claim to be under a
+// shared directory while pointing somewhere else
+func cleanSharePath(urlPath string) string {
+	cleaned := path.Clean("/" + urlPath)
+	if strings.HasSuffix(urlPath, "/") && cleaned != "/" {
+		cleaned += "/"
+	}
+
+	return cleaned
+}
+
+// ShareAuth is a middleware that lets requests carrying a valid share
+// token, in the "share" query string parameter, skip the authentication
+// in protected for the path the token was signed for. Requests without a
+// token go through protected as usual, while expired or tampered tokens
+// are rejected with a 403. Share links only grant read access, so requests
+// with any method other than GET or HEAD, like uploads or moves, always go
+// through protected.
+//
+// A token for a directory is also stored in a cookie, so the listing can
+// be browsed without appending the token to every link.
+func ShareAuth(warnFunction func(string, ...interface{}), secret string, protected func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fallback := protected(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method != http.MethodGet && r.Method != http.MethodHead {
+				fallback.ServeHTTP(w, r)
+				return
+			}
+
+			requested := cleanSharePath(r.URL.Path)
+
+			if token := r.URL.Query().Get(ShareParam); token != "" {
+				handleShareToken(warnFunction, secret, requested, token, next, w, r)
+				return
+			}
+
+			// The cookie holds the shared path along with the token,
+			// and it's only used when it covers the requested path
+			if c, err := r.Cookie(shareCookie); err == nil {
+				shared, token, found := strings.Cut(c.Value, "|")
+				if found && shareCovers(shared, requested) {
+					if _, err := verifyShare(secret, shared, token); err == nil {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			fallback.ServeHTTP(w, r)
+		})
+	}
+}
+
+// handleShareToken validates a token given in the query string, which
+// is signed for the requested path itself, once cleaned
+func handleShareToken(warnFunction func(string, ...interface{}), secret, urlPath, token string, next http.Handler, w http.ResponseWriter, r *http.Request) {
+	expires, err := verifyShare(secret, urlPath, token)
+	if err != nil {
+		warnFunction("share token validation failed for url %q: %s", urlPath, err)
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if strings.HasSuffix(urlPath, "/") {
+		http.SetCookie(w, &http.Cookie{
+			Name:     shareCookie,
+			Value:    urlPath + "|" + token,
+			Path:     urlPath,
+			Expires:  expires,
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	next.ServeHTTP(w, r)
+}
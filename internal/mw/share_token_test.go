@@ -0,0 +1,179 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestShareAuth(t *testing.T) {
+	const secret = "secret-used-to-sign-share-tokens"
+
+	valid := time.Now().Add(time.Hour)
+	expired := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		cookie     string
+		wantStatus int
+		wantCookie bool
+	}{
+		{
+			name:       "no token goes through authentication",
+			path:       "/docs/file.txt",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "valid token for the file",
+			path:       "/docs/file.txt",
+			token:      SignShare(secret, "/docs/file.txt", valid),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "valid token for the directory",
+			path:       "/docs/",
+			token:      SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:       "token for another path",
+			path:       "/private/file.txt",
+			token:      SignShare(secret, "/docs/file.txt", valid),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "expired token",
+			path:       "/docs/file.txt",
+			token:      SignShare(secret, "/docs/file.txt", expired),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "token signed with another secret",
+			path:       "/docs/file.txt",
+			token:      SignShare("another-secret", "/docs/file.txt", valid),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "token with a tampered expiry",
+			path:       "/docs/file.txt",
+			token:      "9999999999" + SignShare(secret, "/docs/file.txt", valid)[10:],
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "malformed token",
+			path:       "/docs/file.txt",
+			token:      "not-a-token",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "cookie for the parent directory",
+			path:       "/docs/nested/file.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "cookie for another directory",
+			path:       "/private/file.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "cookie claiming a path it wasn't signed for",
+			path:       "/private/file.txt",
+			cookie:     "/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "cookie for a directory the path climbs out of",
+			path:       "/docs/../private/secret.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "token for a directory the path climbs out of",
+			path:       "/docs/../private/",
+			token:      SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "token for the directory with a dot segment",
+			path:       "/docs/./",
+			token:      SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusOK,
+			wantCookie: true,
+		},
+		{
+			name:       "token only grants reads",
+			method:     http.MethodPost,
+			path:       "/docs/",
+			token:      SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "cookie doesn't allow moving files out of the share",
+			method:     "MOVE",
+			path:       "/docs/file.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "head request with a cookie",
+			method:     http.MethodHead,
+			path:       "/docs/file.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", valid),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "expired cookie",
+			path:       "/docs/file.txt",
+			cookie:     "/docs/|" + SignShare(secret, "/docs/", expired),
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	protected := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		})
+	}
+
+	handler := ShareAuth(func(string, ...interface{}) {}, secret, protected)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.path
+			if tt.token != "" {
+				target += "?" + url.Values{ShareParam: {tt.token}}.Encode()
+			}
+
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+
+			req := httptest.NewRequest(method, target, nil)
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: shareCookie, Value: tt.cookie})
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := len(rec.Result().Cookies()) > 0; got != tt.wantCookie {
+				t.Fatalf("expected cookie to be set: %v, got: %v", tt.wantCookie, got)
+			}
+		})
+	}
+}
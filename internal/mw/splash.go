@@ -0,0 +1,29 @@
+package mw
+
+import (
+	"net/http"
+	"net/netip"
+)
+
+// Splash is a middleware that answers requests with the splash handler,
+// masking the real content, unless the client IP is in one of the allowed
+// ranges, so the real site can be previewed from them. Requests for which
+// exempt returns true always reach the real content. When trustProxy is
+// set, the client IP is taken from the "X-Forwarded-For" header.
+func Splash(allowed []netip.Prefix, trustProxy bool, exempt func(*http.Request) bool, splash http.Handler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt != nil && exempt(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if ip, ok := clientIP(r, trustProxy); ok && len(allowed) > 0 && isIPAllowed(ip, allowed, nil) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			splash.ServeHTTP(w, r)
+		})
+	}
+}
@@ -1,13 +1,25 @@
 package mw
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 // VerbsAllowed is a middleware that allows only specific HTTP verbs to be
 // processed. If the request verb is not in the list of allowed verbs, a
-// 405 Method Not Allowed response is returned.
-func VerbsAllowed(allowedVerbs ...string) func(http.Handler) http.Handler {
+// 405 Method Not Allowed response is returned, with an "Allow" header
+// listing the verbs that are supported. Requests for which skip returns
+// true are let through with any verb, for handlers checking it on their own.
+func VerbsAllowed(skip func(*http.Request) bool, allowedVerbs ...string) func(http.Handler) http.Handler {
+	allowHeader := strings.Join(allowedVerbs, ", ")
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if skip(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			for _, allowedVerb := range allowedVerbs {
 				if r.Method == allowedVerb {
 					next.ServeHTTP(w, r)
@@ -15,6 +27,8 @@ func VerbsAllowed(allowedVerbs ...string) func(http.Handler) http.Handler {
 				}
 			}
 
+			w.Header().Set("Allow", allowHeader)
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
 			w.WriteHeader(http.StatusMethodNotAllowed)
 			w.Write([]byte("405 method not allowed"))
 		})
@@ -0,0 +1,50 @@
+package mw
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestVerbsAllowed(t *testing.T) {
+	tests := []struct {
+		method     string
+		wantStatus int
+	}{
+		{method: http.MethodGet, wantStatus: http.StatusOK},
+		{method: http.MethodHead, wantStatus: http.StatusOK},
+		{method: http.MethodPost, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodPut, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodPatch, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodDelete, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodOptions, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodTrace, wantStatus: http.StatusMethodNotAllowed},
+		{method: http.MethodConnect, wantStatus: http.StatusMethodNotAllowed},
+	}
+
+	handler := VerbsAllowed(func(*http.Request) bool { return false }, http.MethodGet, http.MethodHead)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(tt.method, "/file.txt", nil))
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantStatus != http.StatusMethodNotAllowed {
+				if got := rec.Header().Get("Allow"); got != "" {
+					t.Errorf("expected no Allow header on allowed methods, got %q", got)
+				}
+				return
+			}
+
+			if got, want := rec.Header().Get("Allow"), "GET, HEAD"; got != want {
+				t.Errorf("expected Allow header %q, got %q", want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package mw
+
+import (
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/klauspost/compress/gzhttp"
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdEncoders keeps zstd encoders around between responses,
+// since creating them allocates large buffers
+var zstdEncoders = sync.Pool{
+	New: func() any {
+		enc, _ := zstd.NewWriter(nil, zstd.WithEncoderConcurrency(1), zstd.WithLowerEncoderMem(true))
+		return enc
+	},
+}
+
+// zstdResponseWriter compresses the response with zstd, following the same
+// rules as the gzip compression: the start of the body is held back until
+// it's known to be large enough to be worth compressing, and responses of
+// compressed content types, partial responses or responses that already
+// have a content encoding are sent as they are.
+type zstdResponseWriter struct {
+	http.ResponseWriter
+	enc    *zstd.Encoder
+	code   int
+	buf    []byte
+	ignore bool
+}
+
+// Write implements http.ResponseWriter
+func (w *zstdResponseWriter) Write(b []byte) (int, error) {
+	if w.enc != nil {
+		return w.enc.Write(b)
+	}
+
+	if w.ignore {
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) < gzhttp.DefaultMinSize {
+		return len(b), nil
+	}
+
+	if err := w.start(false); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// WriteHeader holds back the status code until it's known whether
+// the response is compressed, except for informational responses
+func (w *zstdResponseWriter) WriteHeader(code int) {
+	if code >= 100 && code <= 199 {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+
+	if w.code == 0 {
+		w.code = code
+	}
+}
+
+// start decides whether the response is compressed, based on what has been
+// buffered so far, and sends the headers along with the buffered body. When
+// flushing, the response is compressed regardless of how much was buffered.
+func (w *zstdResponseWriter) start(flushing bool) error {
+	hdr := w.Header()
+
+	// Sniff the content type like the standard library would,
+	// before the body is compressed and can't be sniffed anymore
+	if _, found := hdr["Content-Type"]; !found && len(w.buf) > 0 {
+		hdr.Set("Content-Type", http.DetectContentType(w.buf))
+	}
+
+	compress := (flushing || len(w.buf) >= gzhttp.DefaultMinSize) &&
+		len(w.buf) > 0 &&
+		len(hdr[gzhttp.HeaderNoCompression]) == 0 &&
+		hdr.Get("Content-Encoding") == "" &&
+		hdr.Get("Content-Range") == "" &&
+		gzhttp.DefaultContentTypeFilter(hdr.Get("Content-Type"))
+
+	hdr.Del(gzhttp.HeaderNoCompression)
+
+	var out io.Writer = w.ResponseWriter
+	if compress {
+		hdr.Set("Content-Encoding", "zstd")
+		hdr.Del("Content-Length")
+		hdr.Del("Accept-Ranges")
+
+		w.enc = zstdEncoders.Get().(*zstd.Encoder)
+		w.enc.Reset(w.ResponseWriter)
+		out = w.enc
+	} else {
+		w.ignore = true
+	}
+
+	if w.code != 0 {
+		w.ResponseWriter.WriteHeader(w.code)
+		w.code = 0
+	}
+
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	_, err := out.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// Flush implements http.Flusher, compressing whatever was buffered
+// so far, since the handler wants it sent right away
+func (w *zstdResponseWriter) Flush() {
+	if w.enc == nil && !w.ignore {
+		if len(w.buf) == 0 {
+			return
+		}
+
+		w.start(true)
+	}
+
+	if w.enc != nil {
+		w.enc.Flush()
+	}
+
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying response writer
+func (w *zstdResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// close finishes the response, sending it uncompressed if it was too
+// small to be worth compressing, and returns the encoder to the pool
+func (w *zstdResponseWriter) close() error {
+	if w.enc == nil {
+		if w.ignore {
+			return nil
+		}
+
+		return w.start(false)
+	}
+
+	err := w.enc.Close()
+	w.enc.Reset(nil)
+	zstdEncoders.Put(w.enc)
+	w.enc = nil
+	return err
+}
+
+// zstdHandler compresses the responses of next with zstd
+func zstdHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		zw := &zstdResponseWriter{ResponseWriter: w}
+		defer zw.close()
+
+		next.ServeHTTP(zw, r)
+	})
+}
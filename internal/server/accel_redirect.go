@@ -0,0 +1,57 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+const (
+	// accelRedirectNginx delegates files to nginx with
+	// the "X-Accel-Redirect" header
+	accelRedirectNginx = "nginx"
+
+	// accelRedirectSendfile delegates files to Apache or lighttpd
+	// with the "X-Sendfile" header
+	accelRedirectSendfile = "sendfile"
+)
+
+// delegateFile hands the transfer of the file over to the reverse proxy in
+// front of the server, by answering with a header pointing to the file and
+// no body. The proxy then serves the file itself, including range and
+// conditional requests. nginx gets the path to the file within an internal
+// location, while proxies using "X-Sendfile" get its absolute path on disk.
+// It reports whether the file was delegated: files outside the served path,
+// such as those from overlays, can't be mapped to the internal location and
+// are served by this server instead.
+func (s *Server) delegateFile(fp string, fi os.FileInfo, w http.ResponseWriter) bool {
+	switch s.AccelRedirect {
+	case accelRedirectNginx:
+		root, err := filepath.Abs(s.Path)
+		if err != nil {
+			return false
+		}
+
+		rel, err := filepath.Rel(root, fp)
+		if err != nil || !s.isWithinRoot(fp) {
+			return false
+		}
+
+		w.Header().Set("X-Accel-Redirect", fileURL(false, s.AccelRedirectPrefix, path.Clean("/"+filepath.ToSlash(rel))))
+
+	case accelRedirectSendfile:
+		w.Header().Set("X-Sendfile", fp)
+
+	default:
+		return false
+	}
+
+	// The proxy serves the file with its own validators, so provide an
+	// ETag in the same format nginx uses, rather than letting one be
+	// computed from the empty body
+	w.Header().Set("Etag", fmt.Sprintf("%q", fmt.Sprintf("%x-%x", fi.ModTime().Unix(), fi.Size())))
+	w.WriteHeader(http.StatusOK)
+	return true
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_accelRedirect(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/report 2024.pdf", "%PDF-1.4 contents")
+
+	overlay := t.TempDir()
+	writeTestFile(t, overlay, "extra.txt", "from the overlay")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		wantHeader string
+		wantValue  string
+		wantBody   string
+	}{
+		{
+			name:     "disabled by default",
+			server:   &Server{Path: root},
+			path:     "/docs/report%202024.pdf",
+			wantBody: "%PDF-1.4 contents",
+		},
+		{
+			name:       "nginx",
+			server:     &Server{Path: root, AccelRedirect: "nginx", AccelRedirectPrefix: "/internal/"},
+			path:       "/docs/report%202024.pdf",
+			wantHeader: "X-Accel-Redirect",
+			wantValue:  "/internal/docs/report%202024.pdf",
+		},
+		{
+			name:       "sendfile",
+			server:     &Server{Path: root, AccelRedirect: "sendfile"},
+			path:       "/docs/report%202024.pdf",
+			wantHeader: "X-Sendfile",
+			wantValue:  filepath.Join(root, "docs", "report 2024.pdf"),
+		},
+		{
+			name:     "files from overlays are served directly with nginx",
+			server:   &Server{Path: root, Roots: []string{overlay}, AccelRedirect: "nginx", AccelRedirectPrefix: "/internal/"},
+			path:     "/extra.txt",
+			wantBody: "from the overlay",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+
+			if tt.wantHeader == "" {
+				return
+			}
+
+			if got := rec.Header().Get(tt.wantHeader); got != tt.wantValue {
+				t.Errorf("expected %s header %q, got %q", tt.wantHeader, tt.wantValue, got)
+			}
+
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "application/pdf") {
+				t.Errorf("expected the content type to be resolved, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// acmeChallengePath is where ACME clients, like certbot, publish the
+// tokens for HTTP-01 challenges, which is always at the root of the host
+const acmeChallengePath = "/.well-known/acme-challenge/"
+
+// isACMEChallenge checks if the request is for an ACME challenge token,
+// when challenges are served from their own directory
+func (s *Server) isACMEChallenge(r *http.Request) bool {
+	return s.ACMEChallengeDir != "" && strings.HasPrefix(r.URL.Path, acmeChallengePath)
+}
+
+// isACMEToken checks if the name only has the characters an ACME token
+// can have, which are the ones of unpadded base64url encoding
+func isACMEToken(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// serveACMEChallenge serves a token from the challenge directory. It's
+// never listed nor protected by authentication, since the certificate
+// authority has to be able to read the tokens, and only files directly
+// inside the directory are served.
+func (s *Server) serveACMEChallenge(w http.ResponseWriter, r *http.Request) {
+	token := strings.TrimPrefix(r.URL.Path, acmeChallengePath)
+	if !isACMEToken(token) {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	dir, err := filepath.EvalSymlinks(s.ACMEChallengeDir)
+	if err != nil {
+		s.printWarning("unable to resolve ACME challenge directory %q: %s", s.ACMEChallengeDir, err)
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	// Tokens could be symbolic links, so make sure
+	// they end up inside the challenge directory
+	fp, err := filepath.EvalSymlinks(filepath.Join(dir, token))
+	if err != nil || filepath.Dir(fp) != dir {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil || !fi.Mode().IsRegular() {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	// Tokens are short-lived, so they're never cached
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	http.ServeContent(w, r, "", time.Time{}, f)
+}
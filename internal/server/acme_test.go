@@ -0,0 +1,103 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_acmeChallenge(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "index.txt", "protected")
+	writeTestFile(t, root, ".well-known/acme-challenge/served-root", "from the served root")
+
+	outside := t.TempDir()
+	writeTestFile(t, outside, "secret", "outside the challenge directory")
+
+	challenges := t.TempDir()
+	writeTestFile(t, challenges, "token-ABC_123", "token-ABC_123.key-authorization")
+	writeTestFile(t, challenges, "nested/token", "nested")
+	if err := os.Symlink(filepath.Join(outside, "secret"), filepath.Join(challenges, "escape")); err != nil {
+		t.Fatalf("unable to create symlink: %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "token served without authentication",
+			path:       "/.well-known/acme-challenge/token-ABC_123",
+			wantStatus: http.StatusOK,
+			wantBody:   "token-ABC_123.key-authorization",
+		},
+		{
+			name:       "rest of the server still requires authentication",
+			path:       "/index.txt",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "missing token",
+			path:       "/.well-known/acme-challenge/missing",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "files in the served root are not used",
+			path:       "/.well-known/acme-challenge/served-root",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no directory listing",
+			path:       "/.well-known/acme-challenge/",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no nested files",
+			path:       "/.well-known/acme-challenge/nested/token",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no path traversal",
+			path:       "/.well-known/acme-challenge/..%2f..%2fetc%2fpasswd",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "no symlinks leaving the directory",
+			path:       "/.well-known/acme-challenge/escape",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{
+				Path:             root,
+				Username:         "user",
+				Password:         "pass",
+				HideDotfiles:     true,
+				ACMEChallengeDir: challenges,
+			})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("served at the root with a path prefix", func(t *testing.T) {
+		h := newTestHandler(t, &Server{Path: root, PathPrefix: "/files/", ACMEChallengeDir: challenges})
+
+		rec := doRequest(h, http.MethodGet, "/.well-known/acme-challenge/token-ABC_123", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
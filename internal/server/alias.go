@@ -0,0 +1,86 @@
+package server
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// resolveAlias returns the directory mounted at the longest alias prefix
+// matching the requested path, along with the rest of the path within it
+func (s *Server) resolveAlias(requested string) (string, string, bool) {
+	var dir, rest, longest string
+
+	for prefix, aliasDir := range s.Aliases {
+		prefix = cleanPrefix(prefix)
+
+		if len(prefix) <= len(longest) {
+			continue
+		}
+
+		// The alias itself is requested without the trailing slash,
+		// since requested paths are cleaned
+		switch {
+		case requested == strings.TrimSuffix(prefix, "/"):
+			dir, rest, longest = aliasDir, "/", prefix
+		case strings.HasPrefix(requested, prefix):
+			dir, rest, longest = aliasDir, "/"+strings.TrimPrefix(requested, prefix), prefix
+		}
+	}
+
+	return dir, rest, longest != ""
+}
+
+// servedPath returns the absolute location on disk of the requested path,
+// within the directory of the alias it's under, or the served path
+// otherwise. Paths resolving outside of the aliased directory are rejected.
+func (s *Server) servedPath(requested string) (string, error) {
+	dir, rest, found := s.resolveAlias(requested)
+	if !found {
+		return filepath.Abs(filepath.Join(s.Path, requested))
+	}
+
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	fp := filepath.Join(root, rest)
+
+	rel, err := filepath.Rel(root, fp)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside of the aliased directory %q", requested, dir)
+	}
+
+	return fp, nil
+}
+
+// servedDir returns the directory the absolute path is in, out of the
+// served path and the directories of every alias, which are the only
+// places files are written to. When they're nested, the innermost one
+// is returned.
+func (s *Server) servedDir(p string) (string, bool) {
+	dirs := []string{s.Path}
+	for _, dir := range s.Aliases {
+		dirs = append(dirs, dir)
+	}
+
+	var found string
+	for _, dir := range dirs {
+		root, err := filepath.Abs(dir)
+		if err != nil {
+			continue
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+
+		if len(root) > len(found) {
+			found = root
+		}
+	}
+
+	return found, found != ""
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_aliases(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "home.txt", "main site")
+	writeTestFile(t, root, "docs/guide.txt", "shadowed by the alias")
+	writeTestFile(t, root, "secret.txt", "outside the alias")
+
+	docs := t.TempDir()
+	writeTestFile(t, docs, "guide.txt", "aliased guide")
+	writeTestFile(t, docs, "Readme.txt", "aliased readme")
+
+	api := t.TempDir()
+	writeTestFile(t, api, "v1.txt", "nested alias")
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "file from the main root",
+			path:       "/home.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "main site",
+		},
+		{
+			name:       "file from the alias takes precedence",
+			path:       "/docs/guide.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "aliased guide",
+		},
+		{
+			name:       "longest prefix wins",
+			path:       "/docs/api/v1.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "nested alias",
+		},
+		{
+			name:       "listing of the alias",
+			path:       "/docs/",
+			wantStatus: http.StatusOK,
+			wantBody:   "guide.txt",
+		},
+		{
+			name:         "alias without trailing slash",
+			path:         "/docs",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/",
+		},
+		{
+			name:       "dot segments can't leave the alias",
+			path:       "/docs/../secret.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "outside the alias",
+		},
+		{
+			name:       "encoded dot segments can't leave the alias",
+			path:       "/docs/%2e%2e/%2e%2e/secret.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "outside the alias",
+		},
+		{
+			name:       "missing file in the alias",
+			path:       "/docs/secret.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:         "case-insensitive lookup within the alias",
+			path:         "/docs/readme.txt",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/docs/Readme.txt",
+		},
+	}
+
+	h := newTestHandler(t, &Server{
+		Path:            root,
+		CaseInsensitive: true,
+		Aliases: map[string]string{
+			"/docs/":     docs,
+			"/docs/api/": api,
+		},
+	})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Fatalf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
+
+func Test_servedPathContainment(t *testing.T) {
+	docs := t.TempDir()
+	s := &Server{Path: t.TempDir(), Aliases: map[string]string{"/docs/": docs}}
+
+	// Requested paths are cleaned before being resolved, but
+	// uncleaned ones are still kept within the alias
+	if _, err := s.servedPath("/docs/../../etc/passwd"); err == nil {
+		t.Fatalf("expected an error for a path outside of the alias")
+	}
+
+	if _, err := s.servedPath("/docs/guide.txt"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+}
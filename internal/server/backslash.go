@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// backslashIsSeparator is whether backslashes separate directories in
+// file paths, which is only the case on Windows. It's a variable so the
+// behavior of either platform can be tested on any of them.
+var backslashIsSeparator = filepath.Separator == '\\'
+
+// normalizeBackslashes handles the backslashes in a request path, which
+// some clients on Windows send instead of forward slashes. Depending on
+// the configuration, paths with backslashes are rejected, have them turned
+// into forward slashes, or keep them as part of file names, which is only
+// possible on platforms where they aren't separators. Otherwise, on Windows,
+// a backslash would split a name into directories, letting segments like
+// "..\.." through the checks done on forward slashes. It returns false if
+// the path has to be rejected.
+func (s *Server) normalizeBackslashes(p string) (string, bool) {
+	if !strings.Contains(p, `\`) {
+		return p, true
+	}
+
+	switch s.BackslashPaths {
+	case "slash":
+		return strings.ReplaceAll(p, `\`, "/"), true
+	case "literal":
+		return p, !backslashIsSeparator
+	}
+
+	return "", false
+}
+
+// redirectBackslashes answers requests whose path has backslashes, either
+// rejecting them or redirecting them to the path with forward slashes, as
+// configured, and reports whether it did. Paths keeping their backslashes
+// as part of file names are let through.
+func (s *Server) redirectBackslashes(w http.ResponseWriter, r *http.Request) bool {
+	normalized, ok := s.normalizeBackslashes(r.URL.Path)
+	if !ok {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: paths can't contain backslashes")
+		return true
+	}
+
+	if normalized == r.URL.Path {
+		return false
+	}
+
+	// Cleaning the path also merges the leading slashes a backslash could
+	// turn into, which would otherwise make the redirect leave the host
+	location := fileURL(strings.HasSuffix(normalized, "/"), s.publicPath(r, normalized))
+	if r.URL.RawQuery != "" {
+		location += "?" + r.URL.RawQuery
+	}
+
+	http.Redirect(w, r, location, http.StatusMovedPermanently)
+	return true
+}
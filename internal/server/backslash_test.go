@@ -0,0 +1,174 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+)
+
+func Test_normalizeBackslashes(t *testing.T) {
+	tests := []struct {
+		name        string
+		mode        string
+		isSeparator bool
+		path        string
+		want        string
+		wantOK      bool
+	}{
+		{name: "no backslashes", mode: "reject", path: "/docs/file.txt", want: "/docs/file.txt", wantOK: true},
+		{name: "rejected by default", mode: "", path: `/docs\file.txt`, wantOK: false},
+		{name: "rejected", mode: "reject", path: `/docs\file.txt`, wantOK: false},
+		{name: "rejected on windows", mode: "reject", isSeparator: true, path: `/..\..\secret.txt`, wantOK: false},
+		{name: "turned into slashes", mode: "slash", path: `/docs\nested\file.txt`, want: "/docs/nested/file.txt", wantOK: true},
+		{name: "turned into slashes on windows", mode: "slash", isSeparator: true, path: `/..\..\secret.txt`, want: "/../../secret.txt", wantOK: true},
+		{name: "literal", mode: "literal", path: `/docs\file.txt`, want: `/docs\file.txt`, wantOK: true},
+		{name: "literal on windows", mode: "literal", isSeparator: true, path: `/docs\file.txt`, wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			original := backslashIsSeparator
+			backslashIsSeparator = tt.isSeparator
+			t.Cleanup(func() { backslashIsSeparator = original })
+
+			s := &Server{BackslashPaths: tt.mode}
+			got, ok := s.normalizeBackslashes(tt.path)
+			if ok != tt.wantOK {
+				t.Fatalf("expected ok to be %v, got %v", tt.wantOK, ok)
+			}
+
+			if ok && got != tt.want {
+				t.Errorf("expected path %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_backslashRequests(t *testing.T) {
+	tests := []struct {
+		name         string
+		mode         string
+		isSeparator  bool
+		prefix       string
+		external     string
+		path         string
+		wantStatus   int
+		wantLocation string
+		wantBody     string
+	}{
+		{
+			name:       "rejected by default",
+			path:       "/docs%5Cfile.txt",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "traversal rejected by default",
+			path:       "/docs/..%5C..%5Csecret.txt",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:         "redirected to forward slashes",
+			mode:         "slash",
+			path:         "/docs%5Cfile.txt?dl=1",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/file.txt?dl=1",
+		},
+		{
+			name:         "redirected to forward slashes on windows",
+			mode:         "slash",
+			isSeparator:  true,
+			path:         "/docs%5Cfile.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/file.txt",
+		},
+		{
+			name:         "leading backslashes don't leave the host",
+			mode:         "slash",
+			path:         "/%5Cevil.example/docs/file.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/evil.example/docs/file.txt",
+		},
+		{
+			name:         "redirected under the external prefix",
+			mode:         "slash",
+			prefix:       "/files/",
+			external:     "/public/",
+			path:         "/files/docs%5Cfile.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/public/docs/file.txt",
+		},
+		{
+			name:       "literal file name",
+			mode:       "literal",
+			path:       "/docs/back%5Cslash.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "literal",
+		},
+		{
+			name:        "literal file name on windows",
+			mode:        "literal",
+			isSeparator: true,
+			path:        "/docs/back%5Cslash.txt",
+			wantStatus:  http.StatusBadRequest,
+		},
+		{
+			name:       "forward slashes are unaffected",
+			path:       "/docs/file.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Files can't have a backslash in their name where it's a separator
+			if tt.wantBody == "literal" && filepath.Separator == '\\' {
+				t.Skip("backslashes can't be part of file names on this platform")
+			}
+
+			original := backslashIsSeparator
+			backslashIsSeparator = tt.isSeparator
+			t.Cleanup(func() { backslashIsSeparator = original })
+
+			root := t.TempDir()
+			writeTestFile(t, root, "docs/file.txt", "file")
+			writeTestFile(t, root, "secret.txt", "secret")
+			if filepath.Separator != '\\' {
+				writeTestFile(t, root, `docs/back\slash.txt`, "literal")
+			}
+
+			h := newTestHandler(t, &Server{Path: root, BackslashPaths: tt.mode, PathPrefix: tt.prefix, ExternalPrefix: tt.external})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if location := rec.Header().Get("Location"); location != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, location)
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_moveBackslashDestination(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/file.txt", "file")
+
+	h := newTestHandler(t, &Server{Path: root, AllowUpload: true})
+
+	req := httptest.NewRequest(methodMove, "/docs/file.txt", nil)
+	req.Header.Set("Destination", `/docs/..\..\moved.txt`)
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusBadRequest, rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"os"
+	"strings"
+)
+
+// utf8BOM is the byte order mark some editors write at the start
+// of UTF-8 text files, even though UTF-8 has no byte order
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// bomCharset returns the charset given by the byte order mark at the
+// start of the data, if any
+func bomCharset(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, utf8BOM):
+		return "utf-8"
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return "utf-16le"
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return "utf-16be"
+	}
+
+	return ""
+}
+
+// isTextContentType checks if the content type is one of text
+// that could start with a UTF-8 byte order mark
+func isTextContentType(ctype string) bool {
+	mediatype, params, err := mime.ParseMediaType(ctype)
+	if err != nil {
+		return false
+	}
+
+	if charset := params["charset"]; charset != "" && !strings.EqualFold(charset, "utf-8") {
+		return false
+	}
+
+	switch mediatype {
+	case "application/json", "application/javascript", "application/xml", "image/svg+xml":
+		return true
+	}
+
+	return strings.HasPrefix(mediatype, "text/")
+}
+
+// hasUTF8BOM checks if the file starts with a UTF-8 byte order mark,
+// without moving the position the file is read from
+func hasUTF8BOM(f *os.File) bool {
+	start := make([]byte, len(utf8BOM))
+	if _, err := f.ReadAt(start, 0); err != nil {
+		return false
+	}
+
+	return bytes.Equal(start, utf8BOM)
+}
+
+// bomSkipper reads a file past its byte order mark, as if it wasn't
+// there, so the file can still be served with range requests and its
+// size known without reading it into memory
+type bomSkipper struct {
+	io.ReadSeeker
+}
+
+// skipBOM returns a reader of the file starting after its byte order mark
+func skipBOM(rs io.ReadSeeker) (io.ReadSeeker, error) {
+	if _, err := rs.Seek(int64(len(utf8BOM)), io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	return &bomSkipper{ReadSeeker: rs}, nil
+}
+
+// Seek implements io.Seeker, with offsets relative to the end
+// of the byte order mark
+func (b *bomSkipper) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekStart {
+		offset += int64(len(utf8BOM))
+	}
+
+	pos, err := b.ReadSeeker.Seek(offset, whence)
+	if err != nil {
+		return 0, err
+	}
+
+	return pos - int64(len(utf8BOM)), nil
+}
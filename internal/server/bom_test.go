@@ -0,0 +1,102 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_stripBOM(t *testing.T) {
+	const (
+		bom     = "\xef\xbb\xbf"
+		text    = "Le caf\xc3\xa9 est tr\xc3\xa8s appr\xc3\xa9ci\xc3\xa9"
+		utf16le = "\xff\xfeh\x00i\x00"
+	)
+
+	tests := []struct {
+		name        string
+		filename    string
+		contents    string
+		stripBOM    bool
+		headers     map[string]string
+		wantStatus  int
+		wantBody    string
+		wantCharset string
+	}{
+		{
+			name:        "kept by default",
+			filename:    "notes.txt",
+			contents:    bom + text,
+			wantStatus:  http.StatusOK,
+			wantBody:    bom + text,
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "stripped from text files",
+			filename:    "notes.txt",
+			contents:    bom + text,
+			stripBOM:    true,
+			wantStatus:  http.StatusOK,
+			wantBody:    text,
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "ranges apply to the contents without it",
+			filename:    "notes.txt",
+			contents:    bom + text,
+			stripBOM:    true,
+			headers:     map[string]string{"Range": "bytes=0-5"},
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    text[:6],
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "files without one are served as they are",
+			filename:    "notes.txt",
+			contents:    text,
+			stripBOM:    true,
+			wantStatus:  http.StatusOK,
+			wantBody:    text,
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "utf-16 files are served as they are",
+			filename:    "notes.txt",
+			contents:    utf16le,
+			stripBOM:    true,
+			wantStatus:  http.StatusOK,
+			wantBody:    utf16le,
+			wantCharset: "utf-16le",
+		},
+		{
+			name:       "binary files are served as they are",
+			filename:   "data.bin",
+			contents:   bom + "\x00\x01\x02",
+			stripBOM:   true,
+			wantStatus: http.StatusOK,
+			wantBody:   bom + "\x00\x01\x02",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, tt.filename, tt.contents)
+
+			h := newTestHandler(t, &Server{Path: root, StripBOM: tt.stripBOM})
+
+			rec := doRequest(h, http.MethodGet, "/"+tt.filename, tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+
+			if tt.wantCharset != "" && !strings.HasSuffix(rec.Header().Get("Content-Type"), "; charset="+tt.wantCharset) {
+				t.Errorf("expected charset %q, got %q", tt.wantCharset, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+const canceledPrefix = "[CANCELED] >>> "
+
+// printCanceled logs a request the client abandoned before it was
+// completely served, which is not an error of the server itself
+func (s *Server) printCanceled(r *http.Request, format string, args ...any) {
+	if s.LogOutput != nil && s.logLevels.Enabled(mw.LogInfo) {
+		fmt.Fprintf(s.LogOutput, canceledPrefix+"%s %q: "+format+"\n", append([]any{r.Method, r.URL.Path}, args...)...)
+	}
+}
+
+// contextReader stops reading as soon as the context is done, so
+// large reads stop promptly once the client goes away
+type contextReader struct {
+	ctx context.Context
+	io.ReadSeeker
+}
+
+// Read implements io.Reader, checking the context before every read
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return c.ReadSeeker.Read(p)
+}
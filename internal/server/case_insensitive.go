@@ -0,0 +1,99 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// findCaseInsensitive looks for the requested path ignoring the case of
+// each segment, and returns the path with the case it has on disk. Exact
+// matches are preferred, and directories are only scanned for segments
+// that don't exist as requested. Segments matching more than one entry
+// are ambiguous, so no path is returned for them.
+func (s *Server) findCaseInsensitive(requested string) (string, bool) {
+	// Paths under an alias are only looked up within the aliased
+	// directory, keeping the alias prefix as it was requested
+	if dir, rest, found := s.resolveAlias(requested); found {
+		mount := strings.TrimSuffix(requested, strings.TrimPrefix(rest, "/"))
+
+		found, ok := s.matchSegments(dir, strings.Split(strings.Trim(rest, "/"), "/"))
+		if !ok {
+			return "", false
+		}
+
+		return path.Join(mount, found), true
+	}
+
+	segments := strings.Split(strings.Trim(requested, "/"), "/")
+
+	for _, root := range s.roots() {
+		if found, ok := s.matchSegments(root, segments); ok {
+			return found, true
+		}
+	}
+
+	return "", false
+}
+
+// matchSegments resolves the path segments within the root,
+// ignoring their case
+func (s *Server) matchSegments(root string, segments []string) (string, bool) {
+	current, matched := root, "/"
+
+	for _, segment := range segments {
+		// Keep the segment as is if it exists with that case
+		if _, err := os.Lstat(filepath.Join(current, segment)); err == nil {
+			current, matched = filepath.Join(current, segment), path.Join(matched, segment)
+			continue
+		}
+
+		entries, err := os.ReadDir(current)
+		if err != nil {
+			return "", false
+		}
+
+		var candidate string
+		for _, e := range entries {
+			if !strings.EqualFold(e.Name(), segment) || s.isFiltered(e.Name()) {
+				continue
+			}
+
+			// More than one variant matches, so there's no way
+			// to know which one the client wanted
+			if candidate != "" {
+				return "", false
+			}
+
+			candidate = e.Name()
+		}
+
+		if candidate == "" {
+			return "", false
+		}
+
+		current, matched = filepath.Join(current, candidate), path.Join(matched, candidate)
+	}
+
+	return matched, true
+}
+
+// redirectCaseInsensitive redirects to the requested path with the case
+// it has on disk, if a single match is found. The redirect is temporary,
+// since a file with the requested case could be created later on.
+func (s *Server) redirectCaseInsensitive(requested string, w http.ResponseWriter, r *http.Request) bool {
+	found, ok := s.findCaseInsensitive(requested)
+	if !ok || found == requested {
+		return false
+	}
+
+	target := fileURL(strings.HasSuffix(r.URL.Path, "/"), s.publicPrefix(r), found)
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	http.Redirect(w, r, target, http.StatusFound)
+	return true
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_caseInsensitive(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "Docs/README.md", "readme")
+	writeTestFile(t, root, "Docs/Guide.txt", "guide")
+
+	// Case variants can only coexist on case-sensitive filesystems
+	writeTestFile(t, root, "ambiguous.txt", "lower")
+	ambiguous := true
+	if _, err := os.Stat(filepath.Join(root, "AMBIGUOUS.TXT")); err == nil {
+		ambiguous = false
+	} else {
+		writeTestFile(t, root, "Ambiguous.txt", "upper")
+	}
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		wantStatus   int
+		wantLocation string
+		skip         bool
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			path:       "/docs/guide.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:         "file with a different case",
+			server:       &Server{Path: root, CaseInsensitive: true},
+			path:         "/docs/guide.txt",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/Docs/Guide.txt",
+		},
+		{
+			name:         "directory with a different case",
+			server:       &Server{Path: root, CaseInsensitive: true},
+			path:         "/DOCS/",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/Docs/",
+		},
+		{
+			name:         "query string is kept",
+			server:       &Server{Path: root, CaseInsensitive: true},
+			path:         "/docs/readme.md?raw=1",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/Docs/README.md?raw=1",
+		},
+		{
+			name:       "no match",
+			server:     &Server{Path: root, CaseInsensitive: true},
+			path:       "/docs/missing.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "ambiguous match",
+			server:     &Server{Path: root, CaseInsensitive: true},
+			path:       "/AMBIGUOUS.txt",
+			wantStatus: http.StatusNotFound,
+			skip:       !ambiguous,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.skip {
+				t.Skip("the filesystem is case-insensitive")
+			}
+
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
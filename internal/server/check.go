@@ -0,0 +1,52 @@
+package server
+
+import "errors"
+
+// Check performs every validation the server would do on startup, without
+// listening for requests: the configuration values, the redirections file,
+// the directory listing and error templates, and the banner markdown. All
+// problems found are reported together, rather than stopping at the first.
+func (s *Server) Check() error {
+	var merrs MultiError
+
+	// add flattens multiple errors so they're all reported at the same level
+	add := func(err error) {
+		if err == nil {
+			return
+		}
+
+		var me *MultiError
+		if errors.As(err, &me) {
+			merrs.Errors = append(merrs.Errors, me.Errors...)
+			return
+		}
+
+		merrs.Errors = append(merrs.Errors, err)
+	}
+
+	validationErr := s.Validate()
+	add(validationErr)
+	add(s.LoadRedirectionsIfEnabled())
+
+	templates, err := s.generateTemplates()
+	add(err)
+	s.templates = templates
+
+	add(s.loadErrorTemplate())
+
+	_, err = s.generateBannerMarkdown()
+	add(err)
+
+	// The router relies on validated settings, so it's only built when
+	// they're valid, to avoid reporting the same problem twice
+	if validationErr == nil {
+		_, err = s.router()
+		add(err)
+	}
+
+	if len(merrs.Errors) > 0 {
+		return &merrs
+	}
+
+	return nil
+}
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+)
+
+func Test_Check(t *testing.T) {
+	tests := []struct {
+		name       string
+		setup      func(t *testing.T, s *Server)
+		wantErrors int
+	}{
+		{
+			name:       "valid configuration",
+			setup:      func(t *testing.T, s *Server) {},
+			wantErrors: 0,
+		},
+		{
+			name: "missing served directory",
+			setup: func(t *testing.T, s *Server) {
+				s.Path = filepath.Join(s.Path, "missing")
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "invalid redirections file",
+			setup: func(t *testing.T, s *Server) {
+				writeTestFile(t, s.Path, redirectionsPath, "not a valid rule")
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "redirections disabled",
+			setup: func(t *testing.T, s *Server) {
+				writeTestFile(t, s.Path, redirectionsPath, "not a valid rule")
+				s.DisableRedirects = true
+			},
+			wantErrors: 0,
+		},
+		{
+			name: "unknown directory listing column",
+			setup: func(t *testing.T, s *Server) {
+				s.ListColumns = []string{"name", "owner"}
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "directory listing without the name column",
+			setup: func(t *testing.T, s *Server) {
+				s.ListColumns = []string{"size", "modtime"}
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "base URL without a scheme",
+			setup: func(t *testing.T, s *Server) {
+				s.BaseURL = "files.example.com"
+			},
+			wantErrors: 1,
+		},
+		{
+			name: "multiple problems are reported together",
+			setup: func(t *testing.T, s *Server) {
+				writeTestFile(t, s.Path, redirectionsPath, "not a valid rule")
+				s.Port = 0
+				s.TryExtensions = []string{"html"}
+			},
+			wantErrors: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Port:       5000,
+				Path:       t.TempDir(),
+				PathPrefix: "/",
+				LogOutput:  io.Discard,
+			}
+
+			tt.setup(t, s)
+
+			err := s.Check()
+			if tt.wantErrors == 0 {
+				if err != nil {
+					t.Fatalf("expected no error, got: %s", err)
+				}
+				return
+			}
+
+			merr, ok := err.(*MultiError)
+			if !ok {
+				t.Fatalf("expected a *MultiError, got: %#v", err)
+			}
+
+			if len(merr.Errors) != tt.wantErrors {
+				t.Fatalf("expected %d errors, got %d: %s", tt.wantErrors, len(merr.Errors), err)
+			}
+		})
+	}
+}
+
+func Test_CheckErrorKinds(t *testing.T) {
+	tests := []struct {
+		name    string
+		setup   func(t *testing.T, s *Server)
+		want    []error
+		notWant []error
+	}{
+		{
+			name: "missing served directory",
+			setup: func(t *testing.T, s *Server) {
+				s.Path = filepath.Join(s.Path, "missing")
+			},
+			want: []error{ErrInvalidConfig, ErrRootNotFound},
+		},
+		{
+			name: "served directory not set",
+			setup: func(t *testing.T, s *Server) {
+				s.Path = ""
+			},
+			want: []error{ErrInvalidConfig, ErrRootNotFound},
+		},
+		{
+			name: "invalid value",
+			setup: func(t *testing.T, s *Server) {
+				s.TryExtensions = []string{"html"}
+			},
+			want:    []error{ErrInvalidConfig},
+			notWant: []error{ErrRootNotFound, ErrTemplateParse},
+		},
+		{
+			name: "invalid error template",
+			setup: func(t *testing.T, s *Server) {
+				writeTestFile(t, s.Path, "error.html", "{{ .Broken ")
+				s.ErrorTemplate = filepath.Join(s.Path, "error.html")
+			},
+			want:    []error{ErrTemplateParse},
+			notWant: []error{ErrInvalidConfig},
+		},
+		{
+			name: "invalid redirections file",
+			setup: func(t *testing.T, s *Server) {
+				writeTestFile(t, s.Path, redirectionsPath, "not a valid rule")
+			},
+			want:    []error{ErrInvalidRedirections},
+			notWant: []error{ErrInvalidConfig},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{
+				Port:       5000,
+				Path:       t.TempDir(),
+				PathPrefix: "/",
+				LogOutput:  io.Discard,
+			}
+
+			tt.setup(t, s)
+
+			err := s.Check()
+			for _, want := range tt.want {
+				if !errors.Is(err, want) {
+					t.Errorf("expected error to be %q, got: %v", want, err)
+				}
+			}
+
+			for _, notWant := range tt.notWant {
+				if errors.Is(err, notWant) {
+					t.Errorf("expected error not to be %q, got: %v", notWant, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// checksumCacheTTL is how long computed checksums are kept in memory,
+// as long as the file doesn't change in the meantime
+const checksumCacheTTL = time.Hour
+
+// maxChecksumCacheEntries is the most checksums kept in memory, with the
+// oldest ones evicted to make room for new ones
+const maxChecksumCacheEntries = 10_000
+
+// checksumAlgorithms are the hashes files can be checksummed with
+var checksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// fileChecksum returns the checksum of the file with the given algorithm,
+// computing it only if it isn't cached for the current version of the file
+func (s *Server) fileChecksum(fp string, fi os.FileInfo, algorithm string, r *http.Request) (string, error) {
+	return s.fileChecksumContext(r.Context(), fp, fi, algorithm)
+}
+
+// cachedChecksum returns the checksum of the file with the given algorithm,
+// if it's cached for the current version of the file
+func (s *Server) cachedChecksum(fp string, fi os.FileInfo, algorithm string) (string, bool) {
+	sum, found := s.checksumCache.get(fp+"\x00"+algorithm, uint64(fi.Size()), fi.ModTime())
+	return string(sum), found
+}
+
+// fileChecksumContext is fileChecksum for checksums computed outside of
+// a request, which stop being computed when the context is done
+func (s *Server) fileChecksumContext(ctx context.Context, fp string, fi os.FileInfo, algorithm string) (string, error) {
+	key := fp + "\x00" + algorithm
+	if sum, found := s.cachedChecksum(fp, fi, algorithm); found {
+		return sum, nil
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := checksumAlgorithms[algorithm]()
+	if _, err := io.Copy(h, &contextReader{ctx: ctx, ReadSeeker: f}); err != nil {
+		return "", err
+	}
+
+	sum := hex.EncodeToString(h.Sum(nil))
+	s.checksumCache.set(key, uint64(fi.Size()), fi.ModTime(), checksumCacheTTL, []byte(sum))
+	return sum, nil
+}
+
+// serveChecksum answers with the checksum of the file, with the algorithm
+// given in the "checksum" query string parameter, instead of its contents.
+// It's written in the format of tools like "sha256sum", so the output can
+// be checked with them, or as JSON for clients preferring it.
+func (s *Server) serveChecksum(fp string, w http.ResponseWriter, r *http.Request) {
+	algorithm := strings.ToLower(r.URL.Query().Get("checksum"))
+	if _, found := checksumAlgorithms[algorithm]; !found {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: unknown checksum algorithm %q, use one of: md5, sha1, sha256", algorithm)
+		return
+	}
+
+	mw.SetServedPath(r, fp)
+
+	fi, err := os.Stat(fp)
+	if err != nil {
+		s.printWarning("unable to stat file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat file -- see application logs for more information")
+		return
+	}
+
+	// Hashing reads the whole file, so it counts as an expensive operation
+	release, ok := s.startExpensiveOp(w, r)
+	if !ok {
+		return
+	}
+
+	sum, err := s.fileChecksum(fp, fi, algorithm, r)
+	release()
+
+	if err != nil {
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped computing checksum of file %q: %s", fp, err)
+			return
+		}
+
+		s.printWarning("unable to compute checksum of file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to compute checksum -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+
+	if prefersJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"name":      fi.Name(),
+			"size":      fi.Size(),
+			"algorithm": algorithm,
+			"checksum":  sum,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintf(w, "%s  %s\n", sum, fi.Name())
+}
+
+// setChecksumHeader adds the SHA-256 checksum of the file to the response
+// headers, so clients can learn it with a HEAD request before downloading.
+// Hashing counts as an expensive operation, so when none can run, only a
+// checksum already cached is sent, and the file is served without it
+// otherwise.
+func (s *Server) setChecksumHeader(fp string, fi os.FileInfo, w http.ResponseWriter, r *http.Request) {
+	if sum, found := s.cachedChecksum(fp, fi, "sha256"); found {
+		w.Header().Set("X-Checksum-SHA256", sum)
+		return
+	}
+
+	release, err := s.expensiveOps.acquire(r.Context())
+	if err != nil {
+		s.printDebug("skipped the checksum header of file %q: %s", fp, err)
+		return
+	}
+
+	sum, err := s.fileChecksum(fp, fi, "sha256", r)
+	release()
+
+	if err != nil {
+		s.printWarning("unable to compute checksum of file %q: %s", fp, err)
+		return
+	}
+
+	w.Header().Set("X-Checksum-SHA256", sum)
+}
+
+// checksumTrailerWriter hashes the body of a full file response while it's
+// sent, declaring the "X-Checksum-SHA256" trailer, so the checksum can be
+// sent after the body without reading the file twice
+type checksumTrailerWriter struct {
+	http.ResponseWriter
+	hash   hash.Hash
+	status int
+}
+
+// WriteHeader declares the trailer for full responses, which are sent
+// chunked so the trailer can follow the body
+func (w *checksumTrailerWriter) WriteHeader(code int) {
+	if w.status != 0 {
+		return
+	}
+
+	w.status = code
+	if code == http.StatusOK {
+		w.Header().Set("Trailer", "X-Checksum-SHA256")
+		w.Header().Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter
+func (w *checksumTrailerWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	n, err := w.ResponseWriter.Write(b)
+	if w.status == http.StatusOK {
+		w.hash.Write(b[:n])
+	}
+
+	return n, err
+}
+
+// Unwrap returns the underlying response writer
+func (w *checksumTrailerWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// serveWithChecksumTrailer calls serve with a response writer that sends
+// the SHA-256 checksum of the file as a trailer, once the whole file was
+// sent. Partial and conditional responses have no trailer, since their
+// body isn't the whole file.
+func (s *Server) serveWithChecksumTrailer(fp string, fi os.FileInfo, w http.ResponseWriter, r *http.Request, serve func(http.ResponseWriter)) {
+	tw := &checksumTrailerWriter{ResponseWriter: w, hash: sha256.New()}
+	serve(tw)
+
+	if tw.status != http.StatusOK || r.Context().Err() != nil {
+		return
+	}
+
+	sum := hex.EncodeToString(tw.hash.Sum(nil))
+	s.checksumCache.set(fp+"\x00sha256", uint64(fi.Size()), fi.ModTime(), checksumCacheTTL, []byte(sum))
+
+	// Middlewares buffering the response, like the ETag one, only send
+	// the headers after this point, so the trailer prefix makes sure the
+	// checksum is still sent as a trailer rather than as a header
+	w.Header().Set(http.TrailerPrefix+"X-Checksum-SHA256", sum)
+}
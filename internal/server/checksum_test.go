@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_checksums(t *testing.T) {
+	const contents = "file contents to checksum"
+
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", contents)
+
+	sha256Sum := sha256.Sum256([]byte(contents))
+	sha1Sum := sha1.Sum([]byte(contents))
+	md5Sum := md5.Sum([]byte(contents))
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		headers    map[string]string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{},
+			path:       "/file.txt?checksum=sha256",
+			wantStatus: http.StatusOK,
+			wantBody:   contents,
+		},
+		{
+			name:       "sha256",
+			server:     &Server{Checksums: true},
+			path:       "/file.txt?checksum=sha256",
+			wantStatus: http.StatusOK,
+			wantBody:   hex.EncodeToString(sha256Sum[:]) + "  file.txt\n",
+		},
+		{
+			name:       "sha1",
+			server:     &Server{Checksums: true},
+			path:       "/file.txt?checksum=sha1",
+			wantStatus: http.StatusOK,
+			wantBody:   hex.EncodeToString(sha1Sum[:]) + "  file.txt\n",
+		},
+		{
+			name:       "md5 in uppercase",
+			server:     &Server{Checksums: true},
+			path:       "/file.txt?checksum=MD5",
+			wantStatus: http.StatusOK,
+			wantBody:   hex.EncodeToString(md5Sum[:]) + "  file.txt\n",
+		},
+		{
+			name:       "unknown algorithm",
+			server:     &Server{Checksums: true},
+			path:       "/file.txt?checksum=crc32",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "file served without the parameter",
+			server:     &Server{Checksums: true},
+			path:       "/file.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   contents,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Fatalf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("json", func(t *testing.T) {
+		h := newTestHandler(t, &Server{Path: root, Checksums: true})
+
+		rec := doRequest(h, http.MethodGet, "/file.txt?checksum=sha256", map[string]string{"Accept": "application/json"})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		var got struct {
+			Name      string `json:"name"`
+			Size      int64  `json:"size"`
+			Algorithm string `json:"algorithm"`
+			Checksum  string `json:"checksum"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatalf("unable to decode response: %s", err)
+		}
+
+		if got.Name != "file.txt" || got.Size != int64(len(contents)) || got.Algorithm != "sha256" || got.Checksum != hex.EncodeToString(sha256Sum[:]) {
+			t.Fatalf("unexpected checksum response: %+v", got)
+		}
+	})
+}
+
+func Test_checksumHeaders(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "original")
+
+	h := newTestHandler(t, &Server{Path: root, ChecksumHeaders: true})
+
+	want := func(contents string) string {
+		sum := sha256.Sum256([]byte(contents))
+		return hex.EncodeToString(sum[:])
+	}
+
+	rec := doRequest(h, http.MethodHead, "/file.txt", nil)
+	if got := rec.Header().Get("X-Checksum-SHA256"); got != want("original") {
+		t.Fatalf("expected checksum %q, got %q", want("original"), got)
+	}
+
+	if rec := doRequest(h, http.MethodGet, "/file.txt", nil); rec.Header().Get("X-Checksum-SHA256") != "" {
+		t.Fatalf("expected no checksum header for GET requests")
+	}
+
+	// Changing the file invalidates the cached checksum
+	fp := filepath.Join(root, "file.txt")
+	writeTestFile(t, root, "file.txt", "modified contents")
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(fp, future, future); err != nil {
+		t.Fatalf("unable to change file times: %s", err)
+	}
+
+	rec = doRequest(h, http.MethodHead, "/file.txt", nil)
+	if got := rec.Header().Get("X-Checksum-SHA256"); got != want("modified contents") {
+		t.Fatalf("expected checksum %q after the change, got %q", want("modified contents"), got)
+	}
+}
+
+func Test_checksumHeadersBusy(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "cached.txt", "cached")
+	writeTestFile(t, root, "uncached.txt", "uncached")
+
+	s := &Server{Path: root, ChecksumHeaders: true, MaxExpensiveOps: 1}
+	h := newTestHandler(t, s)
+
+	if rec := doRequest(h, http.MethodHead, "/cached.txt", nil); rec.Header().Get("X-Checksum-SHA256") == "" {
+		t.Fatalf("expected a checksum header")
+	}
+
+	// Hold the only slot, so no file can be hashed
+	release, err := s.expensiveOps.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer release()
+
+	if rec := doRequest(h, http.MethodHead, "/cached.txt", nil); rec.Header().Get("X-Checksum-SHA256") == "" {
+		t.Fatalf("expected the cached checksum to be sent while busy")
+	}
+
+	rec := doRequest(h, http.MethodHead, "/uncached.txt", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if got := rec.Header().Get("X-Checksum-SHA256"); got != "" {
+		t.Fatalf("expected no checksum header while busy, got %q", got)
+	}
+}
+
+func Test_checksumTrailers(t *testing.T) {
+	contents := strings.Repeat("file contents sent with a trailer\n", 1000)
+	sum := sha256.Sum256([]byte(contents))
+	want := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name        string
+		server      *Server
+		headers     map[string]string
+		wantStatus  int
+		wantTrailer string
+	}{
+		{
+			name:        "full response",
+			server:      &Server{ChecksumTrailers: true},
+			wantStatus:  http.StatusOK,
+			wantTrailer: want,
+		},
+		{
+			name:        "full response without etags",
+			server:      &Server{ChecksumTrailers: true, ETagDisabled: true},
+			wantStatus:  http.StatusOK,
+			wantTrailer: want,
+		},
+		{
+			name:        "compressed response has the checksum of the file",
+			server:      &Server{ChecksumTrailers: true, GzipEnabled: true},
+			headers:     map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus:  http.StatusOK,
+			wantTrailer: want,
+		},
+		{
+			name:       "partial response",
+			server:     &Server{ChecksumTrailers: true},
+			headers:    map[string]string{"Range": "bytes=0-9"},
+			wantStatus: http.StatusPartialContent,
+		},
+		{
+			name:       "disabled",
+			server:     &Server{},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", contents)
+			tt.server.Path = root
+
+			srv := httptest.NewServer(newTestHandler(t, tt.server))
+			defer srv.Close()
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/file.txt", nil)
+			if err != nil {
+				t.Fatalf("unable to create request: %s", err)
+			}
+
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			resp, err := srv.Client().Do(req)
+			if err != nil {
+				t.Fatalf("unable to send request: %s", err)
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, resp.StatusCode)
+			}
+
+			// Trailers are only available once the body was read
+			if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+				t.Fatalf("unable to read body: %s", err)
+			}
+
+			if got := resp.Header.Get("X-Checksum-SHA256"); got != "" {
+				t.Errorf("expected no checksum header, got %q", got)
+			}
+
+			if got := resp.Trailer.Get("X-Checksum-SHA256"); got != tt.wantTrailer {
+				t.Errorf("expected checksum trailer %q, got %q", tt.wantTrailer, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+	"net/http"
+	"os"
+	"unicode/utf8"
+
+	"github.com/alecthomas/chroma/v2"
+	"github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
+	"github.com/alecthomas/chroma/v2/styles"
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+const (
+	// maxCodeViewBytes is the largest file shown with syntax highlighting,
+	// larger files are served as they are
+	maxCodeViewBytes = 1 << 20
+
+	// codeViewStyle is the chroma style code is highlighted with
+	codeViewStyle = "github"
+)
+
+// isCodeViewRequest reports whether the file was requested with the
+// "view" query string parameter, to be shown highlighted
+func (s *Server) isCodeViewRequest(r *http.Request) bool {
+	return s.CodeViewer && queryFlag(r, "view")
+}
+
+// serveCodeView renders a text file as a syntax-highlighted page, with the
+// language detected from the file name, or its contents if the name isn't
+// enough. Files that aren't text, aren't valid UTF-8 or are too large to
+// highlight are served as they are instead, as are files requested while
+// too many expensive operations are running.
+func (s *Server) serveCodeView(fp string, w http.ResponseWriter, r *http.Request) {
+	mw.SetServedPath(r, fp)
+
+	f, err := os.Open(fp)
+	if err != nil {
+		s.printWarning("unable to open file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open file -- see application logs for more information")
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		s.printWarning("unable to stat file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat file -- see application logs for more information")
+		return
+	}
+
+	if fi.Size() > maxCodeViewBytes || s.templates == nil {
+		s.printDebug("file %q can't be highlighted, serving it as is", fp)
+		s.serveFile(fp, w, r)
+		return
+	}
+
+	ctype, err := s.detectContentType(f, fi)
+	if err != nil {
+		s.printWarning("unable to read file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read file -- see application logs for more information")
+		return
+	}
+
+	if !isTextContentType(ctype) {
+		s.serveFile(fp, w, r)
+		return
+	}
+
+	b, err := io.ReadAll(&contextReader{ctx: r.Context(), ReadSeeker: f})
+	if err != nil {
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped reading file %q: %s", fp, err)
+			return
+		}
+
+		s.printWarning("unable to read file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read file -- see application logs for more information")
+		return
+	}
+
+	b = bytes.TrimPrefix(b, utf8BOM)
+	if !utf8.Valid(b) {
+		s.serveFile(fp, w, r)
+		return
+	}
+
+	// Highlighting counts as an expensive operation, and when none
+	// can run, the file is served as it is instead
+	release, err := s.expensiveOps.acquire(r.Context())
+	if err != nil {
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped waiting to highlight file %q: %s", fp, err)
+			return
+		}
+
+		s.printDebug("file %q can't be highlighted now, serving it as is: %s", fp, err)
+		s.serveFile(fp, w, r)
+		return
+	}
+
+	code, css, language, err := highlightCode(fi.Name(), string(b))
+	release()
+
+	if err != nil {
+		s.printWarning("unable to highlight file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to highlight file -- see application logs for more information")
+		return
+	}
+
+	currentPath := s.publicPath(r, r.URL.Path)
+
+	content := map[string]any{
+		"DirectoryRootPath": s.publicPrefix(r),
+		"PageTitle":         s.pageTitle(r),
+		"CurrentPath":       currentPath,
+		"HideLinks":         s.HideLinks,
+		"Columns":           s.listColumns(),
+		"FileName":          fi.Name(),
+		"FileSize":          fi.Size(),
+		"FileURL":           fileURL(false, currentPath),
+		"Language":          language,
+		"Code":              code,
+		"CodeCSS":           css,
+	}
+
+	var out bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&out, "code.tmpl", content); err != nil {
+		s.printWarning("unable to render code view of file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to render code view -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+	w.Write(out.Bytes())
+}
+
+// highlightCode returns the code highlighted as HTML, along with the
+// stylesheet its classes need and the name of the language it's in
+func highlightCode(name, code string) (template.HTML, template.CSS, string, error) {
+	lexer := lexers.Match(name)
+	if lexer == nil {
+		lexer = lexers.Analyse(code)
+	}
+
+	if lexer == nil {
+		lexer = lexers.Fallback
+	}
+
+	style := styles.Get(codeViewStyle)
+	formatter := html.New(html.WithClasses(true), html.WithLineNumbers(true), html.WithLinkableLineNumbers(true, "L"))
+
+	tokens, err := chroma.Coalesce(lexer).Tokenise(nil, code)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	var body, css bytes.Buffer
+	if err := formatter.Format(&body, style, tokens); err != nil {
+		return "", "", "", err
+	}
+
+	if err := formatter.WriteCSS(&css, style); err != nil {
+		return "", "", "", err
+	}
+
+	return template.HTML(body.String()), template.CSS(css.String()), lexer.Config().Name, nil
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_codeViewer(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "main.go", "package main\n\nfunc main() {}\n")
+	writeTestFile(t, root, "large.txt", strings.Repeat("a", maxCodeViewBytes+1))
+	writeTestFile(t, root, "binary.bin", "\x00\x01\x02\x03")
+	writeTestFile(t, root, "latin1.txt", "caf\xe9")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		wantRendered bool
+	}{
+		{
+			name:   "disabled by default",
+			server: &Server{Path: root},
+			path:   "/main.go?view=1",
+		},
+		{
+			name:   "not requested",
+			server: &Server{Path: root, CodeViewer: true},
+			path:   "/main.go",
+		},
+		{
+			name:         "highlighted when requested",
+			server:       &Server{Path: root, CodeViewer: true},
+			path:         "/main.go?view=1",
+			wantRendered: true,
+		},
+		{
+			name:   "large files are served as is",
+			server: &Server{Path: root, CodeViewer: true},
+			path:   "/large.txt?view=1",
+		},
+		{
+			name:   "binary files are served as is",
+			server: &Server{Path: root, CodeViewer: true},
+			path:   "/binary.bin?view=1",
+		},
+		{
+			name:   "invalid utf-8 is served as is",
+			server: &Server{Path: root, CodeViewer: true},
+			path:   "/latin1.txt?view=1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			rendered := strings.Contains(body, `class="code-view"`)
+			if rendered != tt.wantRendered {
+				t.Fatalf("expected the code view to be rendered to be %v, got %v", tt.wantRendered, rendered)
+			}
+
+			if !tt.wantRendered {
+				return
+			}
+
+			if ctype := rec.Header().Get("Content-Type"); !strings.HasPrefix(ctype, "text/html") {
+				t.Errorf("expected an HTML content type, got %q", ctype)
+			}
+
+			for _, want := range []string{`<span class="kd">func</span>`, `href="/main.go"`, `href="/main.go?dl=1"`, `id="L3"`} {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected the code view to contain %q", want)
+				}
+			}
+		})
+	}
+}
+
+func Test_codeViewerBusy(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "main.go", "package main\n\nfunc main() {}\n")
+
+	s := &Server{Path: root, CodeViewer: true, MaxExpensiveOps: 1}
+	h := newTestHandler(t, s)
+
+	// Hold the only slot, so the file can't be highlighted
+	release, err := s.expensiveOps.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer release()
+
+	rec := doRequest(h, http.MethodGet, "/main.go?view=1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if body := rec.Body.String(); body != "package main\n\nfunc main() {}\n" {
+		t.Fatalf("expected the file to be served as is while busy, got %q", body)
+	}
+}
@@ -0,0 +1,185 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/saintfish/chardet"
+)
+
+const (
+	// sniffBytes is the amount of bytes read from the start of a file
+	// to detect its content type and charset
+	sniffBytes = 512
+
+	// defaultCharsetConfidence is the minimum confidence, out of 100,
+	// a detected charset needs to be used when none is configured
+	defaultCharsetConfidence = 50
+)
+
+// detectContentType returns the content type of the file, including its
+// charset when it can be detected. The content type comes from the file
+// extension, its magic number, or the standard library detection, in that
+// order. The file is seeked back to its start once done.
+func (s *Server) detectContentType(f *os.File, fi os.FileInfo) (string, error) {
+	data := make([]byte, sniffBytes)
+	n, err := io.ReadFull(f, data)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", err
+	}
+	data = data[:n]
+
+	ctype := s.contentTypeForFilename(fi.Name())
+
+	// Scripts without an extension, like the ones in "bin" directories,
+	// start with a shebang and are shown as their source code
+	if ctype == "" && bytes.HasPrefix(data, []byte("#!")) {
+		ctype = "text/plain"
+	}
+
+	// Files with no known extension are detected by their magic number,
+	// before falling back to the formats the standard library knows
+	if ctype == "" {
+		ctype = getContentTypeForMagic(data)
+	}
+
+	if ctype == "" {
+		if local := http.DetectContentType(data); local != "application/octet-stream" {
+			ctype = local
+		}
+	}
+
+	// Without charsets, there's no point in detecting them, and the
+	// ones added by the standard library detection are dropped too
+	if s.NoCharset {
+		ctype = withoutCharset(ctype)
+	}
+
+	// Content types detected by the standard library might
+	// include a charset already
+	if ctype != "" && !s.NoCharset && !strings.Contains(ctype, ";") {
+		charset, err := s.detectCharset(f, data, fi.Size())
+		if err != nil {
+			return "", err
+		}
+
+		if charset != "" {
+			ctype += "; charset=" + charset
+		}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("unable to seek back to the start of the file: %w", err)
+	}
+
+	return ctype, nil
+}
+
+// detectCharset returns the charset of the file based on its first bytes.
+// When the charset can't be detected confidently, or the data has only
+// ASCII characters, which are valid UTF-8 but say nothing about the rest
+// of the file, more of it is read, up to "CharsetSniffBytes", and the
+// charset is detected again.
+func (s *Server) detectCharset(f *os.File, data []byte, size int64) (string, error) {
+	truncated := int64(len(data)) < size
+
+	if !isASCII(data) || !truncated {
+		if charset, confident := s.charsetOf(data, truncated); confident || !truncated {
+			return charset, nil
+		}
+	}
+
+	if s.CharsetSniffBytes > len(data) {
+		more := make([]byte, s.CharsetSniffBytes-len(data))
+		n, err := io.ReadFull(f, more)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", err
+		}
+
+		data = append(data, more[:n]...)
+		truncated = int64(len(data)) < size
+	}
+
+	charset, _ := s.charsetOf(data, truncated)
+	return charset, nil
+}
+
+// charsetOf returns the charset of the data, and whether it was detected
+// with more confidence than the configured threshold. Valid UTF-8, and
+// data starting with a byte order mark, are always reported as such.
+func (s *Server) charsetOf(data []byte, truncated bool) (string, bool) {
+	// A byte order mark tells the charset for sure, while the
+	// detection could be thrown off by its bytes
+	if charset := bomCharset(data); charset != "" {
+		return charset, true
+	}
+
+	if isValidUTF8Prefix(data, truncated) {
+		return "utf-8", true
+	}
+
+	threshold := s.CharsetConfidence
+	if threshold <= 0 {
+		threshold = defaultCharsetConfidence
+	}
+
+	res, err := chardet.NewTextDetector().DetectBest(data)
+	if err != nil || res.Charset == "" || res.Confidence <= threshold {
+		return "", false
+	}
+
+	return res.Charset, true
+}
+
+// isASCII checks if the data only has ASCII characters
+func isASCII(data []byte) bool {
+	for _, b := range data {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isValidUTF8Prefix checks if the data is valid UTF-8. When the data is
+// only the start of the file, a multi-byte character cut in half at the
+// end isn't considered invalid.
+func isValidUTF8Prefix(data []byte, truncated bool) bool {
+	if utf8.Valid(data) {
+		return true
+	}
+
+	if !truncated {
+		return false
+	}
+
+	// A character is at most 4 bytes long, so at most
+	// the last 3 bytes can be an incomplete character
+	for i := 1; i <= utf8.UTFMax-1 && i < len(data); i++ {
+		if utf8.RuneStart(data[len(data)-i]) {
+			return !utf8.FullRune(data[len(data)-i:]) && utf8.Valid(data[:len(data)-i])
+		}
+	}
+
+	return false
+}
+
+// withoutCharset removes the charset parameter from the content type,
+// keeping any other parameter it might have
+func withoutCharset(ctype string) string {
+	mediatype, params, err := mime.ParseMediaType(ctype)
+	if err != nil || params["charset"] == "" {
+		return ctype
+	}
+
+	delete(params, "charset")
+	return mime.FormatMediaType(mediatype, params)
+}
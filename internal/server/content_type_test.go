@@ -0,0 +1,157 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_detectContentType(t *testing.T) {
+	// French text encoded as ISO-8859-1, where accented
+	// characters are single bytes that aren't valid UTF-8
+	latin1 := strings.Repeat("Le caf\xe9 est tr\xe8s appr\xe9ci\xe9 \xe0 la fen\xeatre, o\xf9 l'\xe9t\xe9 dure. ", 40)
+
+	tests := []struct {
+		name        string
+		filename    string
+		contents    string
+		sniffBytes  int
+		confidence  int
+		noCharset   bool
+		wantType    string
+		wantCharset string
+	}{
+		{
+			name:        "empty file",
+			filename:    "empty.txt",
+			contents:    "",
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "utf-8 character cut by the sniffed bytes",
+			filename:    "cut.txt",
+			contents:    strings.Repeat("a", sniffBytes-1) + "é and more text",
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "legacy charset after an ascii start",
+			filename:    "legacy.txt",
+			contents:    strings.Repeat("plain ascii text ", 40) + latin1,
+			sniffBytes:  8192,
+			confidence:  40,
+			wantType:    "text/plain",
+			wantCharset: "ISO-8859-1",
+		},
+		{
+			name:        "legacy charset below the confidence threshold",
+			filename:    "legacy.txt",
+			contents:    strings.Repeat("plain ascii text ", 40) + latin1,
+			sniffBytes:  8192,
+			confidence:  90,
+			wantType:    "text/plain",
+			wantCharset: "",
+		},
+		{
+			name:        "legacy charset not sniffed further",
+			filename:    "legacy.txt",
+			contents:    strings.Repeat("plain ascii text ", 40) + latin1,
+			sniffBytes:  sniffBytes,
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "detected content type keeps its charset",
+			filename:    "page",
+			contents:    "<!doctype html><html><body>Hello</body></html>",
+			wantType:    "text/html",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "script without extension",
+			filename:    "deploy",
+			contents:    "#!/usr/bin/env bash\nset -euo pipefail\necho \"done\"\n",
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "script with control characters",
+			filename:    "notify",
+			contents:    "#!/bin/sh\nprintf 'finished\a\x01'\n",
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "utf-8 with a byte order mark",
+			filename:    "bom.txt",
+			contents:    "\xef\xbb\xbfLe caf\xc3\xa9 est tr\xc3\xa8s appr\xc3\xa9ci\xc3\xa9",
+			wantType:    "text/plain",
+			wantCharset: "utf-8",
+		},
+		{
+			name:        "utf-16 little endian with a byte order mark",
+			filename:    "utf16le.txt",
+			contents:    "\xff\xfeh\x00e\x00l\x00l\x00o\x00 \x00w\x00o\x00r\x00l\x00d\x00",
+			wantType:    "text/plain",
+			wantCharset: "utf-16le",
+		},
+		{
+			name:        "utf-16 big endian with a byte order mark",
+			filename:    "utf16be.txt",
+			contents:    "\xfe\xff\x00h\x00e\x00l\x00l\x00o\x00 \x00w\x00o\x00r\x00l\x00d",
+			wantType:    "text/plain",
+			wantCharset: "utf-16be",
+		},
+		{
+			name:      "charset disabled",
+			filename:  "legacy.txt",
+			contents:  strings.Repeat("plain ascii text ", 40) + latin1,
+			noCharset: true,
+			wantType:  "text/plain",
+		},
+		{
+			name:      "charset disabled for detected content types",
+			filename:  "page",
+			contents:  "<!doctype html><html><body>Hello</body></html>",
+			noCharset: true,
+			wantType:  "text/html",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, tt.filename, tt.contents)
+
+			h := newTestHandler(t, &Server{Path: root, CharsetSniffBytes: tt.sniffBytes, CharsetConfidence: tt.confidence, NoCharset: tt.noCharset})
+
+			rec := doRequest(h, http.MethodGet, "/"+tt.filename, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			// The bytes read to detect the content type must be sent too
+			if rec.Body.String() != tt.contents {
+				t.Errorf("expected the full file contents to be served")
+			}
+
+			ctype := rec.Header().Get("Content-Type")
+			if got, _, _ := strings.Cut(ctype, ";"); got != tt.wantType {
+				t.Errorf("expected content type %q, got %q", tt.wantType, ctype)
+			}
+
+			if tt.wantCharset != "" && !strings.HasSuffix(ctype, "; charset="+tt.wantCharset) {
+				t.Errorf("expected charset %q, got %q", tt.wantCharset, ctype)
+			}
+
+			if (tt.wantCharset == "" || tt.noCharset) && strings.Contains(ctype, "charset=") {
+				t.Errorf("expected no charset, got %q", ctype)
+			}
+
+			if strings.Count(ctype, "charset=") > 1 {
+				t.Errorf("expected a single charset, got %q", ctype)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package server
 
+import "strings"
+
 var ctypes = []struct {
 	Extension   []string
 	ExactNames  []string
@@ -113,26 +115,67 @@ var ctypes = []struct {
 	// Programming Languages
 	{[]string{".c"}, nil, "text/x-csrc"},
 	{[]string{".css"}, nil, "text/css"},
-	{[]string{".cpp"}, nil, "text/x-c++src"},
+	{[]string{".cpp", ".cc", ".cxx"}, nil, "text/x-c++src"},
 	{[]string{".h"}, nil, "text/x-chdr"},
-	{[]string{".hpp"}, nil, "text/x-c++hdr"},
-	{[]string{".dart"}, nil, "application/dart"}, // Dart Language
+	{[]string{".hpp", ".hh", ".hxx"}, nil, "text/x-c++hdr"},
+	{[]string{".dart"}, nil, "text/plain"}, // Dart Language
 	{[]string{".go"}, nil, "text/x-go"},
 	{[]string{".java"}, nil, "text/x-java-source"},
-	{[]string{".kt"}, nil, "text/x-kotlin"},
-	{[]string{".pl"}, nil, "application/x-perl"},
-	{[]string{".py"}, nil, "text/x-python"},
-	{[]string{".rb"}, nil, "application/x-ruby"},
+	{[]string{".kt", ".kts"}, nil, "text/x-kotlin"},
+	{[]string{".pl", ".pm"}, nil, "text/plain"},
+	{[]string{".py", ".pyi", ".pyw"}, nil, "text/x-python"},
+	{[]string{".rb"}, nil, "text/plain"},
 	{[]string{".rs"}, nil, "text/rust"},
 	{[]string{".scala"}, nil, "text/x-scala"},
-	{[]string{".sh"}, nil, "application/x-sh"},
+	{[]string{".sh", ".zsh", ".fish"}, nil, "text/plain"},
 	{[]string{".swift"}, nil, "text/x-swift"},
-	{[]string{".bash"}, nil, "application/x-shellscript"},
-	{[]string{".bashrc"}, nil, "application/x-shellscript"},
-	{[]string{".js", ".mjs"}, nil, "text/javascript"},
+	{[]string{".bash"}, nil, "text/plain"},
+	{[]string{".bashrc"}, nil, "text/plain"},
+	{[]string{".js", ".mjs", ".cjs"}, nil, "text/javascript"},
 	{[]string{".jsx"}, nil, "text/jsx"},
 	{[]string{".tsx"}, nil, "text/tsx"},
 
+	// Source Code, sent as plain text so browsers display it instead of
+	// downloading it. TypeScript files are only covered by their module
+	// extensions, since ".ts" is also used by MPEG transport streams.
+	{[]string{".mts", ".cts"}, nil, "text/plain"},        // TypeScript Modules
+	{[]string{".cs", ".csx"}, nil, "text/plain"},         // C#
+	{[]string{".fs", ".fsi", ".fsx"}, nil, "text/plain"}, // F#
+	{[]string{".vb"}, nil, "text/plain"},                 // Visual Basic
+	{[]string{".php"}, nil, "text/plain"},
+	{[]string{".lua"}, nil, "text/plain"},
+	{[]string{".r"}, nil, "text/plain"},
+	{[]string{".jl"}, nil, "text/plain"},                            // Julia
+	{[]string{".ex", ".exs"}, nil, "text/plain"},                    // Elixir
+	{[]string{".erl", ".hrl"}, nil, "text/plain"},                   // Erlang
+	{[]string{".hs", ".lhs"}, nil, "text/plain"},                    // Haskell
+	{[]string{".ml", ".mli"}, nil, "text/plain"},                    // OCaml
+	{[]string{".clj", ".cljs", ".cljc", ".edn"}, nil, "text/plain"}, // Clojure
+	{[]string{".lisp", ".el", ".scm", ".rkt"}, nil, "text/plain"},   // Lisp dialects
+	{[]string{".groovy"}, nil, "text/plain"},
+	{[]string{".gradle"}, nil, "text/x-gradle"},
+	{[]string{".m", ".mm"}, nil, "text/plain"}, // Objective-C
+	{[]string{".zig"}, nil, "text/plain"},
+	{[]string{".nim"}, nil, "text/plain"},
+	{[]string{".cr"}, nil, "text/plain"}, // Crystal
+	{[]string{".elm"}, nil, "text/plain"},
+	{[]string{".sol"}, nil, "text/plain"}, // Solidity
+	{[]string{".tcl"}, nil, "text/plain"},
+	{[]string{".awk"}, nil, "text/plain"},
+	{[]string{".pas"}, nil, "text/plain"},          // Pascal
+	{[]string{".f90", ".f95"}, nil, "text/plain"},  // Fortran
+	{[]string{".asm", ".s"}, nil, "text/plain"},    // Assembly
+	{[]string{".ps1", ".psm1"}, nil, "text/plain"}, // PowerShell
+	{[]string{".bat", ".cmd"}, nil, "text/plain"},  // Windows Batch
+	{[]string{".vue", ".svelte"}, nil, "text/plain"},
+	{[]string{".proto"}, nil, "text/plain"}, // Protocol Buffers
+	{[]string{".graphql", ".gql"}, nil, "text/plain"},
+	{[]string{".tf", ".tfvars", ".hcl"}, nil, "text/plain"}, // Terraform
+	{[]string{".toml", ".cfg", ".properties"}, nil, "text/plain"},
+	{[]string{".mk", ".cmake"}, nil, "text/plain"},
+	{[]string{".diff", ".patch"}, nil, "text/plain"},
+	{[]string{".gitignore", ".gitattributes", ".editorconfig"}, nil, "text/plain"},
+
 	// Font Files
 	{[]string{".eot"}, nil, "application/vnd.ms-fontobject"},
 	{[]string{".otf"}, nil, "font/otf"},
@@ -167,6 +210,8 @@ var ctypes = []struct {
 	{nil, []string{"build.gradle"}, "text/x-gradle"},
 	{nil, []string{"requirements.txt"}, "text/plain"}, // Python Requirements File
 	{nil, []string{"Cargo.toml"}, "text/plain"},       // Rust Package Manager File
+	{nil, []string{"go.mod"}, "text/plain"},           // Go Module File
+	{nil, []string{"go.sum"}, "text/plain"},           // Go Module Checksums
 
 	// Security Files
 	{[]string{".crt"}, nil, "application/x-x509-ca-cert"},
@@ -187,7 +232,7 @@ var ctypes = []struct {
 	{[]string{".xsl"}, nil, "application/xml"},
 	{[]string{".xslt"}, nil, "application/xslt+xml"},
 	{[]string{".srt"}, nil, "application/x-subrip"},
-	{[]string{".sql"}, nil, "application/sql"},
+	{[]string{".sql"}, nil, "text/plain"},
 	{[]string{".tgz"}, nil, "application/x-gzip"},
 }
 
@@ -208,3 +253,26 @@ func getContentTypeForFilename(name string) string {
 
 	return ""
 }
+
+// contentTypeForFilename returns the content type for the file name, from
+// the configured content types if any matches, where an exact file name
+// wins over an extension, and longer extensions win over shorter ones,
+// or from the built-in table otherwise
+func (s *Server) contentTypeForFilename(name string) string {
+	if ctype, found := s.ContentTypes[name]; found {
+		return ctype
+	}
+
+	var longest string
+	for ext := range s.ContentTypes {
+		if strings.HasPrefix(ext, ".") && strings.HasSuffix(name, ext) && len(ext) > len(longest) {
+			longest = ext
+		}
+	}
+
+	if longest != "" {
+		return s.ContentTypes[longest]
+	}
+
+	return getContentTypeForFilename(name)
+}
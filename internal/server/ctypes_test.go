@@ -1,6 +1,10 @@
 package server
 
-import "testing"
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
 
 func Test_getContentTypeForExtension(t *testing.T) {
 	tests := []struct {
@@ -51,6 +55,46 @@ func Test_getContentTypeForExtension(t *testing.T) {
 			filename: "Makefile",
 			want:     "text/x-makefile",
 		},
+		{
+			filename: "main.go",
+			want:     "text/x-go",
+		},
+		{
+			filename: "app.py",
+			want:     "text/x-python",
+		},
+		{
+			filename: "lib.rs",
+			want:     "text/rust",
+		},
+		{
+			filename: "Program.cs",
+			want:     "text/plain",
+		},
+		{
+			filename: "deploy.sh",
+			want:     "text/plain",
+		},
+		{
+			filename: "index.php",
+			want:     "text/plain",
+		},
+		{
+			filename: "server.mts",
+			want:     "text/plain",
+		},
+		{
+			filename: "settings.gradle",
+			want:     "text/x-gradle",
+		},
+		{
+			filename: "go.mod",
+			want:     "text/plain",
+		},
+		{
+			filename: "segment.ts",
+			want:     "video/mp2t",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.filename, func(t *testing.T) {
@@ -81,3 +125,46 @@ func Test_findNoDuplicatesInContentTypes(t *testing.T) {
 		}
 	}
 }
+
+func Test_contentTypeOverrides(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "app.ts", "const answer: number = 42;")
+	writeTestFile(t, root, "archive.tar.gz", "archive")
+	writeTestFile(t, root, "Jenkinsfile", "pipeline {}")
+	writeTestFile(t, root, "main.go", "package main")
+
+	s := &Server{
+		Path: root,
+		ContentTypes: map[string]string{
+			".ts":         "text/plain",
+			".gz":         "application/gzip",
+			".tar.gz":     "application/x-gtar",
+			"Jenkinsfile": "text/x-groovy",
+		},
+	}
+
+	h := newTestHandler(t, s)
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{path: "/app.ts", want: "text/plain"},
+		{path: "/archive.tar.gz", want: "application/x-gtar"},
+		{path: "/Jenkinsfile", want: "text/x-groovy"},
+		{path: "/main.go", want: "text/x-go"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if got, _, _ := strings.Cut(rec.Header().Get("Content-Type"), ";"); got != tt.want {
+				t.Errorf("expected content type %q, got %q", tt.want, rec.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+)
+
+// debugStats reports runtime statistics of the server as JSON, to help
+// diagnose resource leaks, such as files or goroutines never released
+func (s *Server) debugStats(w http.ResponseWriter, r *http.Request) {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	stats := map[string]any{
+		"goroutines": runtime.NumGoroutine(),
+		"uptime":     time.Since(s.startedAt).Round(time.Second).String(),
+		"memory": map[string]any{
+			"alloc_bytes":       mem.Alloc,
+			"total_alloc_bytes": mem.TotalAlloc,
+			"sys_bytes":         mem.Sys,
+			"heap_objects":      mem.HeapObjects,
+			"gc_cycles":         mem.NumGC,
+		},
+	}
+
+	// Open files can't be counted on every platform
+	if count, ok := openFileCount(); ok {
+		stats["open_files"] = count
+	}
+
+	if s.ListingCacheTTL > 0 {
+		stats["listing_cache_entries"] = s.listingCache.len()
+	}
+
+	body, err := json.Marshal(stats)
+	if err != nil {
+		s.printWarning("unable to generate debug response: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate debug response -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(body)
+}
+
+// openFileCount returns the amount of file descriptors open by the
+// process, on platforms which list them as a directory, like Linux
+// and macOS
+func openFileCount() (int, bool) {
+	for _, dir := range []string{"/proc/self/fd", "/dev/fd"} {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+
+		// Reading the directory opens a file descriptor too,
+		// which is closed already but still listed
+		return len(entries) - 1, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,89 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+func Test_debugStats(t *testing.T) {
+	tests := []struct {
+		name       string
+		server     *Server
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{},
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "enabled",
+			server:     &Server{DebugEndpoints: true},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "protected by authentication",
+			server:     &Server{DebugEndpoints: true, Username: "admin", Password: "secret"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "authenticated",
+			server:     &Server{DebugEndpoints: true, Username: "admin", Password: "secret"},
+			headers:    map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/_/debug", tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var stats map[string]any
+			if err := json.Unmarshal(rec.Body.Bytes(), &stats); err != nil {
+				t.Fatalf("unable to decode debug response: %s", err)
+			}
+
+			for _, key := range []string{"goroutines", "memory", "uptime"} {
+				if _, found := stats[key]; !found {
+					t.Errorf("expected debug response to include %q", key)
+				}
+			}
+		})
+	}
+}
+
+func Test_serveFileClosesFiles(t *testing.T) {
+	before, ok := openFileCount()
+	if !ok {
+		t.Skip("open files can't be counted on this platform")
+	}
+
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		writeTestFile(t, root, fmt.Sprintf("dir/file-%d.txt", i), "contents")
+	}
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	for i := 0; i < 20; i++ {
+		doRequest(h, http.MethodGet, fmt.Sprintf("/dir/file-%d.txt", i), nil)
+		doRequest(h, http.MethodGet, "/dir/", nil)
+	}
+
+	// Allow for a few descriptors opened by the runtime in the meantime
+	if after, _ := openFileCount(); after > before+3 {
+		t.Errorf("expected files to be closed after serving them, open files went from %d to %d", before, after)
+	}
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"sync"
+	"time"
+)
+
+// diskUsageTTL is how long a disk usage reading is reused before
+// querying the filesystem again
+const diskUsageTTL = 10 * time.Second
+
+// diskUsage holds the space information of the filesystem backing
+// the served directory
+type diskUsage struct {
+	TotalBytes uint64 `json:"total_bytes"`
+	FreeBytes  uint64 `json:"free_bytes"`
+	UsedBytes  uint64 `json:"used_bytes"`
+}
+
+// diskUsageCache caches the last disk usage reading for a short
+// period of time to avoid a syscall per request
+type diskUsageCache struct {
+	mu        sync.Mutex
+	usage     diskUsage
+	fetchedAt time.Time
+}
+
+// get returns the disk usage for the given path, reusing the
+// last reading if it's still fresh
+func (c *diskUsageCache) get(path string) (diskUsage, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetchedAt.IsZero() && time.Since(c.fetchedAt) < diskUsageTTL {
+		return c.usage, nil
+	}
+
+	usage, err := getDiskUsage(path)
+	if err != nil {
+		return diskUsage{}, err
+	}
+
+	c.usage = usage
+	c.fetchedAt = time.Now()
+	return usage, nil
+}
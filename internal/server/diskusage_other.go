@@ -0,0 +1,13 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package server
+
+import (
+	"errors"
+	"runtime"
+)
+
+// getDiskUsage is not supported on this platform
+func getDiskUsage(path string) (diskUsage, error) {
+	return diskUsage{}, errors.New("disk usage reporting is not supported on " + runtime.GOOS)
+}
@@ -0,0 +1,27 @@
+//go:build linux || darwin || freebsd
+
+package server
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// getDiskUsage returns the space information of the filesystem
+// where the given path is located
+func getDiskUsage(path string) (diskUsage, error) {
+	var stat unix.Statfs_t
+	if err := unix.Statfs(path, &stat); err != nil {
+		return diskUsage{}, fmt.Errorf("unable to stat filesystem for %q: %w", path, err)
+	}
+
+	total := uint64(stat.Blocks) * uint64(stat.Bsize)
+	free := uint64(stat.Bavail) * uint64(stat.Bsize)
+
+	return diskUsage{
+		TotalBytes: total,
+		FreeBytes:  free,
+		UsedBytes:  total - uint64(stat.Bfree)*uint64(stat.Bsize),
+	}, nil
+}
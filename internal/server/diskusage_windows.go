@@ -0,0 +1,29 @@
+//go:build windows
+
+package server
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows"
+)
+
+// getDiskUsage returns the space information of the volume
+// where the given path is located
+func getDiskUsage(path string) (diskUsage, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return diskUsage{}, fmt.Errorf("invalid path %q: %w", path, err)
+	}
+
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, &total, &totalFree); err != nil {
+		return diskUsage{}, fmt.Errorf("unable to get disk space for %q: %w", path, err)
+	}
+
+	return diskUsage{
+		TotalBytes: total,
+		FreeBytes:  free,
+		UsedBytes:  total - totalFree,
+	}, nil
+}
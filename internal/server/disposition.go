@@ -0,0 +1,75 @@
+package server
+
+import (
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+)
+
+// contentDisposition returns how the browser should handle the file:
+// "attachment" to download it, "inline" to display it, or an empty
+// string to leave it up to the browser. The "dl" query string parameter
+// takes precedence over the configured extensions, so "?dl" downloads
+// any file and "?dl=0", or "?inline", displays it instead. Files larger
+// than the inline size limit are downloaded, unless their extension is
+// configured to be displayed.
+func (s *Server) contentDisposition(name string, size int64, r *http.Request) string {
+	if r.URL.Query().Has("dl") {
+		if queryFlag(r, "dl") {
+			return "attachment"
+		}
+
+		return "inline"
+	}
+
+	if queryFlag(r, "inline") {
+		return "inline"
+	}
+
+	tooLarge := s.MaxInlineBytes > 0 && size > s.MaxInlineBytes
+
+	ext := filepath.Ext(name)
+	if ext == "" {
+		if tooLarge {
+			return "attachment"
+		}
+
+		return ""
+	}
+
+	for _, e := range s.ForceDownloadExtensions {
+		if strings.EqualFold(e, ext) {
+			return "attachment"
+		}
+	}
+
+	for _, e := range s.ForceInlineExtensions {
+		if strings.EqualFold(e, ext) {
+			return "inline"
+		}
+	}
+
+	if tooLarge {
+		return "attachment"
+	}
+
+	return ""
+}
+
+// setContentDisposition sets the "Content-Disposition" header for the
+// file, if its disposition was configured or requested, keeping the
+// name of the file for downloads
+func (s *Server) setContentDisposition(name string, size int64, w http.ResponseWriter, r *http.Request) {
+	disposition := s.contentDisposition(name, size, r)
+	if disposition == "" {
+		return
+	}
+
+	if v := mime.FormatMediaType(disposition, map[string]string{"filename": name}); v != "" {
+		w.Header().Set("Content-Disposition", v)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", disposition)
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_contentDisposition(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "release.zip", "archive")
+	writeTestFile(t, root, "manual.PDF", "document")
+	writeTestFile(t, root, "notes.txt", "notes")
+	writeTestFile(t, root, "résumé.txt", "accents")
+
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{
+			name: "forced download",
+			path: "/release.zip",
+			want: `attachment; filename=release.zip`,
+		},
+		{
+			name: "forced inline, case insensitive",
+			path: "/manual.PDF",
+			want: `inline; filename=manual.PDF`,
+		},
+		{
+			name: "no configured disposition",
+			path: "/notes.txt",
+			want: "",
+		},
+		{
+			name: "download requested",
+			path: "/notes.txt?dl",
+			want: `attachment; filename=notes.txt`,
+		},
+		{
+			name: "query parameter overrides forced download",
+			path: "/release.zip?dl=0",
+			want: `inline; filename=release.zip`,
+		},
+		{
+			name: "query parameter overrides forced inline",
+			path: "/manual.PDF?dl=1",
+			want: `attachment; filename=manual.PDF`,
+		},
+		{
+			name: "non-ascii names are encoded",
+			path: "/r%C3%A9sum%C3%A9.txt?dl",
+			want: `attachment; filename*=utf-8''r%C3%A9sum%C3%A9.txt`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{
+				Path:                    root,
+				ForceDownloadExtensions: []string{".zip", ".exe"},
+				ForceInlineExtensions:   []string{".pdf"},
+			})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Disposition"); got != tt.want {
+				t.Fatalf("expected Content-Disposition %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_maxInlineBytes(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "small.txt", "small")
+	writeTestFile(t, root, "large.txt", strings.Repeat("large file ", 10))
+	writeTestFile(t, root, "large", strings.Repeat("large file ", 10))
+	writeTestFile(t, root, "large.pdf", strings.Repeat("large file ", 10))
+
+	tests := []struct {
+		name       string
+		path       string
+		headers    map[string]string
+		wantStatus int
+		want       string
+	}{
+		{
+			name:       "small files are left to the browser",
+			path:       "/small.txt",
+			wantStatus: http.StatusOK,
+			want:       "",
+		},
+		{
+			name:       "large files are downloaded",
+			path:       "/large.txt",
+			wantStatus: http.StatusOK,
+			want:       `attachment; filename=large.txt`,
+		},
+		{
+			name:       "large files without extension are downloaded",
+			path:       "/large",
+			wantStatus: http.StatusOK,
+			want:       `attachment; filename=large`,
+		},
+		{
+			name:       "inline requested",
+			path:       "/large.txt?inline",
+			wantStatus: http.StatusOK,
+			want:       `inline; filename=large.txt`,
+		},
+		{
+			name:       "inline requested with the download parameter",
+			path:       "/large.txt?dl=0",
+			wantStatus: http.StatusOK,
+			want:       `inline; filename=large.txt`,
+		},
+		{
+			name:       "forced inline extensions are displayed",
+			path:       "/large.pdf",
+			wantStatus: http.StatusOK,
+			want:       `inline; filename=large.pdf`,
+		},
+		{
+			name:       "ranges keep working",
+			path:       "/large.txt",
+			headers:    map[string]string{"Range": "bytes=0-4"},
+			wantStatus: http.StatusPartialContent,
+			want:       `attachment; filename=large.txt`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{
+				Path:                  root,
+				MaxInlineBytes:        50,
+				ForceInlineExtensions: []string{".pdf"},
+			})
+
+			rec := doRequest(h, http.MethodGet, tt.path, tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Disposition"); got != tt.want {
+				t.Fatalf("expected Content-Disposition %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
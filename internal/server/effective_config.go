@@ -0,0 +1,101 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// redacted replaces secrets in the effective configuration
+const redacted = "[redacted]"
+
+// configField is a setting of the effective configuration
+type configField struct {
+	name  string
+	value string
+}
+
+// effectiveConfig returns the settings that most affect what the server
+// does, resolved the way the server uses them, with secrets redacted
+func (s *Server) effectiveConfig() []configField {
+	root := s.Path
+	if abs, err := filepath.Abs(s.Path); err == nil {
+		root = abs
+	}
+
+	address := net.JoinHostPort("", strconv.Itoa(s.Port))
+	switch {
+	case s.Listener != nil:
+		address = s.Listener.Addr().String()
+	case s.Addr != "":
+		address = s.Addr
+	}
+
+	prefix := s.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+
+	auth := "none"
+	switch {
+	case s.IsBasicAuthEnabled():
+		auth = "basic"
+	case s.JWTSigningKey != "":
+		auth = "jwt"
+	}
+
+	compression := "none"
+	if s.GzipEnabled {
+		compression = strings.Join(s.compressionAlgorithms(), ",")
+	}
+
+	fields := []configField{
+		{"root", strconv.Quote(root)},
+		{"prefix", strconv.Quote(prefix)},
+		{"address", strconv.Quote(address)},
+		{"listing", strconv.FormatBool(!s.DisableDirectoryList)},
+		{"markdown", strconv.FormatBool(!s.DisableMarkdown)},
+		{"auth", auth},
+	}
+
+	if s.IsBasicAuthEnabled() {
+		fields = append(fields, configField{"username", strconv.Quote(s.Username)}, configField{"password", redacted})
+	}
+
+	if s.JWTSigningKey != "" {
+		fields = append(fields, configField{"jwt_key", redacted})
+	}
+
+	if s.ShareSecret != "" {
+		fields = append(fields, configField{"share_secret", redacted})
+	}
+
+	return append(fields,
+		configField{"compression", compression},
+		configField{"uploads", strconv.FormatBool(s.AllowUpload)},
+		configField{"cors", strconv.FormatBool(s.CorsEnabled)},
+	)
+}
+
+// printEffectiveConfig logs the effective configuration as a single line
+// of "key=value" pairs, at the info level
+func (s *Server) printEffectiveConfig() {
+	if level, err := mw.ParseLogLevel(s.LogLevel); s.LogLevel != "" && err == nil && level > mw.LogInfo {
+		return
+	}
+
+	var sb strings.Builder
+	for i, field := range s.effectiveConfig() {
+		if i > 0 {
+			sb.WriteByte(' ')
+		}
+
+		fmt.Fprintf(&sb, "%s=%s", field.name, field.value)
+	}
+
+	fmt.Fprintln(s.LogOutput, startupPrefix, "Effective configuration:", sb.String())
+}
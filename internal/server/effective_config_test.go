@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_printEffectiveConfig(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name        string
+		server      *Server
+		wantContain []string
+		wantMissing []string
+	}{
+		{
+			name:   "defaults",
+			server: &Server{Path: root, Port: 5000},
+			wantContain: []string{
+				"root=" + strconv.Quote(root),
+				`prefix="/"`,
+				`address=":5000"`,
+				"listing=true",
+				"markdown=true",
+				"auth=none",
+				"compression=none",
+			},
+		},
+		{
+			name:        "basic authentication is redacted",
+			server:      &Server{Path: root, Port: 5000, Username: "admin", Password: "hunter2"},
+			wantContain: []string{"auth=basic", `username="admin"`, "password=[redacted]"},
+			wantMissing: []string{"hunter2"},
+		},
+		{
+			name:        "jwt key is redacted",
+			server:      &Server{Path: root, Addr: "127.0.0.1:8080", JWTSigningKey: "very-secret-key"},
+			wantContain: []string{"auth=jwt", "jwt_key=[redacted]", `address="127.0.0.1:8080"`},
+			wantMissing: []string{"very-secret-key"},
+		},
+		{
+			name:        "relative root is resolved",
+			server:      &Server{Path: ".", Port: 5000, GzipEnabled: true, CompressionAlgorithms: []string{"zstd", "gzip"}},
+			wantContain: []string{"compression=zstd,gzip"},
+			wantMissing: []string{`root="."`},
+		},
+		{
+			name:        "not logged above the info level",
+			server:      &Server{Path: root, Port: 5000, LogLevel: "warn"},
+			wantMissing: []string{"Effective configuration"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.server.LogOutput = &buf
+			tt.server.printEffectiveConfig()
+
+			out := buf.String()
+			for _, want := range tt.wantContain {
+				if !strings.Contains(out, want) {
+					t.Errorf("expected output to contain %q, got %q", want, out)
+				}
+			}
+
+			for _, missing := range tt.wantMissing {
+				if strings.Contains(out, missing) {
+					t.Errorf("expected output to not contain %q, got %q", missing, out)
+				}
+			}
+		})
+	}
+}
@@ -2,12 +2,57 @@ package server
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"unicode"
 
 	"github.com/go-playground/validator/v10"
 )
 
+var (
+	// ErrInvalidConfig is wrapped by the errors of configuration values
+	// that didn't pass validation
+	ErrInvalidConfig = errors.New("invalid configuration")
+
+	// ErrRootNotFound is wrapped by the error of a served directory
+	// that isn't set, doesn't exist or isn't a directory
+	ErrRootNotFound = errors.New("served directory not found")
+
+	// ErrTemplateParse is wrapped by the errors of templates, like the
+	// custom error page or the directory listing layouts, that can't
+	// be parsed
+	ErrTemplateParse = errors.New("unable to parse template")
+
+	// ErrInvalidRedirections is wrapped by the errors of redirection
+	// rules, from the redirections file or the settings, that can't
+	// be parsed
+	ErrInvalidRedirections = errors.New("invalid redirections")
+)
+
+// kindError marks an error as being of one of the kinds above, so
+// callers can tell problems apart with errors.Is, while keeping the
+// message of the error as is
+type kindError struct {
+	kind error
+	err  error
+}
+
+// Error implements the error interface
+func (k *kindError) Error() string {
+	return k.err.Error()
+}
+
+// Unwrap returns both the kind of the error and the error itself
+func (k *kindError) Unwrap() []error {
+	return []error{k.kind, k.err}
+}
+
+// withKind marks the error as being of the given kind
+func withKind(kind, err error) error {
+	return &kindError{kind: kind, err: err}
+}
+
 // MultiError is a collection of errors
 type MultiError struct {
 	Errors []error
@@ -41,6 +86,12 @@ func (m *MultiError) Error() string {
 	return "multiple errors occurred:\n" + b.String()
 }
 
+// Unwrap returns the collected errors, so errors.Is and errors.As
+// look into each of them
+func (m *MultiError) Unwrap() []error {
+	return m.Errors
+}
+
 // ValidationError is an error validating a flag value
 type ValidationError struct {
 	Field string
@@ -59,6 +110,20 @@ func (v *ValidationError) Error() string {
 		humanMsg = fmt.Sprintf("value must be less than %s (for numbers) or smaller than %s characters (for text)", v.Param, v.Param)
 	case "ispathprefix":
 		humanMsg = "must start and end with a forward slash, and include within alphanumeric, dashes or underscores, or additional forward slashes"
+	case "startswith":
+		humanMsg = fmt.Sprintf("must start with %q", v.Param)
+	case "hostname_port":
+		humanMsg = "must be a host and port, such as \"127.0.0.1:5000\" or \":5000\""
+	case "file":
+		humanMsg = "must be an existing file"
+	case "listcolumns":
+		humanMsg = fmt.Sprintf("must include \"name\" and only contain the columns: %s", strings.Join(listColumns, ", "))
+	case "oneof":
+		humanMsg = fmt.Sprintf("must be one of: %s", strings.Join(strings.Fields(v.Param), ", "))
+	case "http_url":
+		humanMsg = "must be an absolute URL, such as \"https://files.example.com\""
+	case "cidr":
+		humanMsg = "must be a CIDR range, such as \"10.0.0.0/8\" or \"fd00::/8\""
 	case "excluded_with":
 		humanMsg = fmt.Sprintf("cannot be used in conjunction with %s", v.Param)
 	default:
@@ -71,6 +136,16 @@ func (v *ValidationError) Error() string {
 	)
 }
 
+// Unwrap returns the kinds of problem the error is: always an invalid
+// configuration, and a missing served directory for the "path" field
+func (v *ValidationError) Unwrap() []error {
+	if v.Field == "path" {
+		return []error{ErrInvalidConfig, ErrRootNotFound}
+	}
+
+	return []error{ErrInvalidConfig}
+}
+
 // FieldToValidationError converts a validator.FieldError from
 // the validator v10 package to a local ValidationError
 func FieldToValidationError(field validator.FieldError) *ValidationError {
@@ -0,0 +1,117 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// loadErrorTemplate parses the custom error template, if one is configured
+func (s *Server) loadErrorTemplate() error {
+	if s.ErrorTemplate == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(s.ErrorTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to read error template %q: %w", s.ErrorTemplate, err)
+	}
+
+	tpl, err := template.New("error").Parse(string(b))
+	if err != nil {
+		return withKind(ErrTemplateParse, fmt.Errorf("unable to parse error template %q: %w", s.ErrorTemplate, err))
+	}
+
+	s.errorTemplate = tpl
+	return nil
+}
+
+// httpError writes an error response with the given status code. The message
+// is shown to the client, so it must never include the underlying error,
+// which should be logged instead. Clients preferring JSON get the message
+// as a JSON document. Otherwise, if a custom error template is configured,
+// the message is rendered within it, or it's sent as plain text. The
+// content type is always set explicitly, so the body is never sniffed.
+func (s *Server) httpError(statusCode int, w http.ResponseWriter, r *http.Request, format string, args ...any) {
+	// Messages without arguments are sent as they are, so a literal "%"
+	// doesn't end up rendered as a formatting error
+	message := format
+	if len(args) > 0 {
+		message = fmt.Sprintf(format, args...)
+	}
+
+	// Like http.Error, drop the length of the content that was going to
+	// be served, and since every response below sets its content type,
+	// make sure clients don't sniff a different one from the body
+	w.Header().Del("Content-Length")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if prefersJSON(r) {
+		body, err := json.Marshal(map[string]any{
+			"error":  message,
+			"status": statusCode,
+		})
+
+		if err == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(statusCode)
+			w.Write(body)
+			return
+		}
+
+		s.printWarning("unable to render JSON error, falling back to plain text: %s", err)
+	}
+
+	if s.errorTemplate != nil {
+		var buf bytes.Buffer
+		err := s.errorTemplate.Execute(&buf, map[string]any{
+			"StatusCode": statusCode,
+			"StatusText": http.StatusText(statusCode),
+			"Message":    message,
+			"PageTitle":  s.PageTitle,
+		})
+
+		if err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(statusCode)
+			w.Write(buf.Bytes())
+			return
+		}
+
+		s.printWarning("unable to render error template, falling back to plain text: %s", err)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(statusCode)
+	fmt.Fprint(w, message)
+}
+
+// prefersJSON checks if the client accepts JSON with a higher preference
+// than HTML, based on the quality values of its "Accept" header. Wildcards
+// are ignored, so browsers, which accept anything, keep getting HTML.
+func prefersJSON(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+
+	var jsonQ, htmlQ float64
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := mw.QualityValue(params)
+		switch strings.ToLower(strings.TrimSpace(mediaType)) {
+		case "application/json":
+			jsonQ = max(jsonQ, q)
+		case "text/html":
+			htmlQ = max(htmlQ, q)
+		}
+	}
+
+	return jsonQ > 0 && jsonQ > htmlQ
+}
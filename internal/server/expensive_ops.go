@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// errTooBusy is returned when no slot for an expensive
+// operation frees up within the configured wait time
+var errTooBusy = errors.New("too many expensive operations in progress")
+
+// opLimiter bounds how many expensive operations, such as converting
+// images or generating feeds, run at once, shared across all of them.
+// A nil limiter doesn't limit anything.
+type opLimiter struct {
+	slots chan struct{}
+	wait  time.Duration
+}
+
+// newOpLimiter creates a limiter allowing up to max operations at once,
+// where further operations wait up to the given time for a slot. It
+// returns nil if max isn't positive.
+func newOpLimiter(max int, wait time.Duration) *opLimiter {
+	if max <= 0 {
+		return nil
+	}
+
+	return &opLimiter{slots: make(chan struct{}, max), wait: wait}
+}
+
+// acquire waits for a slot, returning the function that releases it.
+// It fails if the context is done, or with errTooBusy if the wait
+// time elapses first.
+func (l *opLimiter) acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	release := func() { <-l.slots }
+
+	// Take a free slot right away if there's one
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if l.wait <= 0 {
+		return nil, errTooBusy
+	}
+
+	timer := time.NewTimer(l.wait)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return release, nil
+	case <-timer.C:
+		return nil, errTooBusy
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startExpensiveOp waits for a slot to run an expensive operation for the
+// request, returning the function that releases it. If no slot is available
+// in time, it answers with a 503 error, or nothing if the client went away,
+// and reports false.
+func (s *Server) startExpensiveOp(w http.ResponseWriter, r *http.Request) (func(), bool) {
+	release, err := s.expensiveOps.acquire(r.Context())
+	if err == nil {
+		return release, true
+	}
+
+	if errors.Is(err, errTooBusy) {
+		s.printWarning("rejected request to %q: %s", r.URL.Path, err)
+		w.Header().Set("Retry-After", "5")
+		s.httpError(http.StatusServiceUnavailable, w, r, "503 service unavailable: the server is busy, try again later")
+		return nil, false
+	}
+
+	s.printCanceled(r, "stopped waiting for an expensive operation: %s", err)
+	return nil, false
+}
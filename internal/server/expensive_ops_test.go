@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_opLimiterBoundsConcurrency(t *testing.T) {
+	const limit, workers = 3, 20
+
+	l := newOpLimiter(limit, time.Minute)
+
+	var running, peak atomic.Int64
+	var wg sync.WaitGroup
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+				return
+			}
+			defer release()
+
+			current := running.Add(1)
+			for {
+				p := peak.Load()
+				if current <= p || peak.CompareAndSwap(p, current) {
+					break
+				}
+			}
+
+			time.Sleep(5 * time.Millisecond)
+			running.Add(-1)
+		}()
+	}
+
+	wg.Wait()
+
+	if got := peak.Load(); got > limit {
+		t.Fatalf("expected at most %d operations at once, got %d", limit, got)
+	}
+}
+
+func Test_opLimiterWait(t *testing.T) {
+	tests := []struct {
+		name    string
+		wait    time.Duration
+		cancel  bool
+		wantErr error
+	}{
+		{name: "no wait", wait: 0, wantErr: errTooBusy},
+		{name: "wait elapses", wait: 10 * time.Millisecond, wantErr: errTooBusy},
+		{name: "request canceled", wait: time.Minute, cancel: true, wantErr: context.Canceled},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newOpLimiter(1, tt.wait)
+
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			defer release()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			if tt.cancel {
+				cancel()
+			}
+			defer cancel()
+
+			if _, err := l.acquire(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	t.Run("released slots are reused", func(t *testing.T) {
+		l := newOpLimiter(1, 0)
+
+		for i := 0; i < 3; i++ {
+			release, err := l.acquire(context.Background())
+			if err != nil {
+				t.Fatalf("unexpected error on attempt %d: %s", i+1, err)
+			}
+			release()
+		}
+	})
+
+	t.Run("no limit", func(t *testing.T) {
+		var l *opLimiter
+
+		for i := 0; i < 3; i++ {
+			if _, err := l.acquire(context.Background()); err != nil {
+				t.Fatalf("unexpected error on attempt %d: %s", i+1, err)
+			}
+		}
+	})
+}
+
+func Test_expensiveOpsBusy(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "contents")
+
+	s := &Server{Path: root, DirectoryFeeds: true, MaxExpensiveOps: 1}
+	h := newTestHandler(t, s)
+
+	if rec := doRequest(h, http.MethodGet, "/?format=rss", nil); rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	// Hold the only slot, so the next feed can't be generated
+	release, err := s.expensiveOps.acquire(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer release()
+
+	rec := doRequest(h, http.MethodGet, "/?format=rss", nil)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rec.Code)
+	}
+
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Fatalf("expected a Retry-After header")
+	}
+}
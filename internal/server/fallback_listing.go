@@ -0,0 +1,60 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"os"
+)
+
+// writeTracker records whether anything was written through it, so a
+// failed template can still be replaced if it failed before any output
+type writeTracker struct {
+	io.Writer
+	written bool
+}
+
+// Write implements io.Writer
+func (t *writeTracker) Write(p []byte) (int, error) {
+	if len(p) > 0 {
+		t.written = true
+	}
+
+	return t.Writer.Write(p)
+}
+
+// serveFallbackListing renders the directory listing as plain HTML, with
+// no templates, styles or scripts involved, for when the templates failed
+// to load or render. It's a degraded listing, but it keeps the files
+// reachable instead of answering with an error.
+func (s *Server) serveFallbackListing(content map[string]any, w http.ResponseWriter) {
+	currentPath, _ := content["CurrentPath"].(string)
+	files, _ := content["Files"].([]os.FileInfo)
+
+	var b bytes.Buffer
+	title := html.EscapeString(currentPath)
+	fmt.Fprintf(&b, "<!doctype html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n<title>Index of %s</title>\n</head>\n<body>\n<h1>Index of %s</h1>\n<ul>\n", title, title)
+
+	if isRoot, _ := content["IsRoot"].(bool); !isRoot {
+		if parent, _ := content["UpDirectory"].(string); parent != "" {
+			fmt.Fprintf(&b, "<li><a href=\"%s\">../</a></li>\n", html.EscapeString(parent))
+		}
+	}
+
+	for _, fi := range files {
+		name := fi.Name()
+		if fi.IsDir() {
+			name += "/"
+		}
+
+		fmt.Fprintf(&b, "<li><a href=\"%s\">%s</a></li>\n", html.EscapeString(fileURL(fi.IsDir(), currentPath, fi.Name())), html.EscapeString(name))
+	}
+
+	b.WriteString("</ul>\n</body>\n</html>\n")
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(b.Bytes())
+}
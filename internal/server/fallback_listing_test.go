@@ -0,0 +1,102 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_fallbackListing(t *testing.T) {
+	layouts := t.TempDir()
+	writeTestFile(t, layouts, "broken.tmpl", `<h1>{{ .CurrentPath }}</h1>{{ template "missing" . }}`)
+
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/a&b.txt", "file")
+	writeTestFile(t, root, "docs/nested/file.txt", "file")
+	writeTestFile(t, root, "docs/.layout", "broken")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		noTemplates  bool
+		path         string
+		wantFallback bool
+	}{
+		{
+			name:   "templates render the listing",
+			server: &Server{},
+			path:   "/",
+		},
+		{
+			name:         "missing templates",
+			server:       &Server{},
+			noTemplates:  true,
+			path:         "/docs/",
+			wantFallback: true,
+		},
+		{
+			name:         "missing templates with streamed listings",
+			server:       &Server{StreamListing: true},
+			noTemplates:  true,
+			path:         "/docs/",
+			wantFallback: true,
+		},
+		{
+			name:         "broken custom layout",
+			server:       &Server{Layouts: map[string]string{"broken": filepath.Join(layouts, "broken.tmpl")}},
+			path:         "/docs/",
+			wantFallback: true,
+		},
+		{
+			name:         "broken custom layout rendered in memory",
+			server:       &Server{Layouts: map[string]string{"broken": filepath.Join(layouts, "broken.tmpl")}, ListingCacheTTL: time.Minute},
+			path:         "/docs/",
+			wantFallback: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			tt.server.Path = root
+			tt.server.LogOutput = &logs
+
+			h := newTestHandler(t, tt.server)
+			if tt.noTemplates {
+				tt.server.templates = nil
+			}
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+
+			body := rec.Body.String()
+			fallback := strings.Contains(body, "<h1>Index of ")
+			if fallback != tt.wantFallback {
+				t.Fatalf("expected the fallback listing to be rendered to be %v, got %v: %s", tt.wantFallback, fallback, body)
+			}
+
+			if !tt.wantFallback {
+				return
+			}
+
+			for _, want := range []string{`<a href="/docs/a&amp;b.txt">a&amp;b.txt</a>`, `<a href="/docs/nested/">nested/</a>`, `<a href="/">../</a>`} {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected the fallback listing to contain %q", want)
+				}
+			}
+
+			if strings.Contains(body, "stylesheet") {
+				t.Errorf("expected the fallback listing to not load any stylesheet")
+			}
+
+			if !tt.noTemplates && !strings.Contains(logs.String(), errorPrefix) {
+				t.Errorf("expected the template error to be logged, got: %s", logs.String())
+			}
+		})
+	}
+}
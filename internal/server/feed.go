@@ -0,0 +1,149 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// defaultFeedItems is the amount of files included in
+// a directory feed when no limit is configured
+const defaultFeedItems = 20
+
+// rssFeed is an RSS 2.0 document
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+// rssChannel is the channel of an RSS feed, describing the directory
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []rssItem `xml:"item"`
+}
+
+// rssItem is a file in a directory feed
+type rssItem struct {
+	Title   string  `xml:"title"`
+	Link    string  `xml:"link"`
+	GUID    rssGUID `xml:"guid"`
+	PubDate string  `xml:"pubDate"`
+}
+
+// rssGUID is the unique identifier of a feed item
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// isFeedRequest checks if the directory listing was requested as a feed
+func (s *Server) isFeedRequest(r *http.Request) bool {
+	return s.DirectoryFeeds && r.URL.Query().Get("format") == "rss"
+}
+
+// serveFeed renders the most recently modified files in the directory as
+// an RSS feed, so tools can subscribe to directories that accumulate files
+// over time. Subdirectories aren't included, and links are absolute, as
+// feed readers require.
+func (s *Server) serveFeed(requestedPath string, overlays []string, w http.ResponseWriter, r *http.Request) {
+	if s.DisableDirectoryList {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	// Feeds stat every file in the directory to find the latest ones
+	release, ok := s.startExpensiveOp(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	list, err := os.ReadDir(requestedPath)
+	if err != nil {
+		s.printWarning("unable to read directory %q: %s", requestedPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read directory -- see application logs for more information")
+		return
+	}
+
+	list, _ = s.readOverlays(list, overlays)
+
+	files := make([]os.FileInfo, 0, len(list))
+	for _, f := range list {
+		if f.IsDir() || s.isHidden(f.Name()) {
+			continue
+		}
+
+		fi, err := f.Info()
+		if err != nil {
+			s.printWarning("unable to stat file %q: %s", filepath.Join(requestedPath, f.Name()), err)
+			continue
+		}
+
+		files = append(files, fi)
+	}
+
+	// Newest files first, up to the configured limit
+	sort.SliceStable(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+
+	limit := s.FeedMaxItems
+	if limit <= 0 {
+		limit = defaultFeedItems
+	}
+
+	if len(files) > limit {
+		files = files[:limit]
+	}
+
+	dirURL := s.baseURL(r) + fileURL(true, s.publicPath(r, r.URL.Path))
+
+	title := s.PageTitle
+	if title == "" {
+		title = s.publicPath(r, r.URL.Path)
+	}
+
+	feed := rssFeed{
+		Version: "2.0",
+		Channel: rssChannel{
+			Title:       title,
+			Link:        dirURL,
+			Description: "Latest files in " + s.publicPath(r, r.URL.Path),
+			Items:       make([]rssItem, 0, len(files)),
+		},
+	}
+
+	if len(files) > 0 {
+		feed.Channel.LastBuildDate = files[0].ModTime().UTC().Format(time.RFC1123Z)
+	}
+
+	for _, fi := range files {
+		link := dirURL + fileURL(false, fi.Name())
+
+		feed.Channel.Items = append(feed.Channel.Items, rssItem{
+			Title:   fi.Name(),
+			Link:    link,
+			GUID:    rssGUID{IsPermaLink: true, Value: link},
+			PubDate: fi.ModTime().UTC().Format(time.RFC1123Z),
+		})
+	}
+
+	body, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		s.printWarning("unable to generate feed for directory %q: %s", requestedPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate directory feed -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/rss+xml; charset=utf-8")
+	s.setListingCacheControl(w)
+	w.Write([]byte(xml.Header))
+	w.Write(body)
+}
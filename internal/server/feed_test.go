@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/xml"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_directoryFeeds(t *testing.T) {
+	root := t.TempDir()
+	now := time.Now()
+
+	for i, name := range []string{"oldest.txt", "middle.txt", "newest file.txt"} {
+		writeTestFile(t, root, name, "contents")
+
+		modTime := now.Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(root, name), modTime, modTime); err != nil {
+			t.Fatalf("unable to change modification time of %q: %s", name, err)
+		}
+	}
+
+	writeTestFile(t, root, "sub/nested.txt", "nested")
+	writeTestFile(t, root, "_redirects", "")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		headers    map[string]string
+		wantStatus int
+		wantItems  []string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "newest files first",
+			server:     &Server{Path: root, DirectoryFeeds: true},
+			wantStatus: http.StatusOK,
+			wantItems:  []string{"http://example.com/newest%20file.txt", "http://example.com/middle.txt", "http://example.com/oldest.txt"},
+		},
+		{
+			name:       "limited items",
+			server:     &Server{Path: root, DirectoryFeeds: true, FeedMaxItems: 1},
+			wantStatus: http.StatusOK,
+			wantItems:  []string{"http://example.com/newest%20file.txt"},
+		},
+		{
+			name:       "configured base URL",
+			server:     &Server{Path: root, DirectoryFeeds: true, FeedMaxItems: 1, BaseURL: "https://files.example.org/"},
+			wantStatus: http.StatusOK,
+			wantItems:  []string{"https://files.example.org/newest%20file.txt"},
+		},
+		{
+			name:       "trusted proxy headers",
+			server:     &Server{Path: root, DirectoryFeeds: true, FeedMaxItems: 1, TrustProxy: true},
+			headers:    map[string]string{"X-Forwarded-Proto": "https", "X-Forwarded-Host": "proxy.example.org"},
+			wantStatus: http.StatusOK,
+			wantItems:  []string{"https://proxy.example.org/newest%20file.txt"},
+		},
+		{
+			name:       "directory listing disabled",
+			server:     &Server{Path: root, DirectoryFeeds: true, DisableDirectoryList: true},
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/?format=rss", tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantItems == nil {
+				if got := rec.Header().Get("Content-Type"); got == "application/rss+xml; charset=utf-8" {
+					t.Errorf("expected no feed to be rendered")
+				}
+				return
+			}
+
+			var feed rssFeed
+			if err := xml.Unmarshal(rec.Body.Bytes(), &feed); err != nil {
+				t.Fatalf("unable to decode feed: %s", err)
+			}
+
+			if len(feed.Channel.Items) != len(tt.wantItems) {
+				t.Fatalf("expected %d items, got %d", len(tt.wantItems), len(feed.Channel.Items))
+			}
+
+			for i, want := range tt.wantItems {
+				if got := feed.Channel.Items[i].Link; got != want {
+					t.Errorf("expected item %d to link to %q, got %q", i, want, got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !unix
+
+package server
+
+import "os"
+
+// fileOwnerSupported reports whether file owners can be shown
+// in the directory listing on this platform
+const fileOwnerSupported = false
+
+// fileOwner is not supported on this platform
+func fileOwner(fi os.FileInfo) string {
+	return ""
+}
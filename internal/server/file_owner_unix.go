@@ -0,0 +1,68 @@
+//go:build unix
+
+package server
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"sync"
+	"syscall"
+)
+
+// fileOwnerSupported reports whether file owners can be shown
+// in the directory listing on this platform
+const fileOwnerSupported = true
+
+// ownerNames caches the user and group names already looked up,
+// since the same few owners are repeated across most files
+var ownerNames sync.Map
+
+// fileOwner returns the names of the user and group owning the file,
+// formatted as "user:group", falling back to their numeric IDs when
+// they can't be looked up
+func fileOwner(fi os.FileInfo) string {
+	st, ok := fi.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+
+	uid := strconv.FormatUint(uint64(st.Uid), 10)
+	gid := strconv.FormatUint(uint64(st.Gid), 10)
+
+	owner := lookupOwnerName("u"+uid, uid, func(id string) (string, error) {
+		u, err := user.LookupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return u.Username, nil
+	})
+
+	group := lookupOwnerName("g"+gid, gid, func(id string) (string, error) {
+		g, err := user.LookupGroupId(id)
+		if err != nil {
+			return "", err
+		}
+
+		return g.Name, nil
+	})
+
+	return owner + ":" + group
+}
+
+// lookupOwnerName returns the cached name for the given ID, or looks
+// it up and caches it, using the ID itself if the lookup fails
+func lookupOwnerName(key, id string, lookup func(string) (string, error)) string {
+	if name, found := ownerNames.Load(key); found {
+		return name.(string)
+	}
+
+	name, err := lookup(id)
+	if err != nil {
+		name = id
+	}
+
+	ownerNames.Store(key, name)
+	return name
+}
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"cmp"
 	"io/fs"
+	"net/http"
+	"os"
+	"slices"
 	"strings"
 )
 
@@ -10,17 +14,126 @@ type foldersFirst []fs.DirEntry
 func (f foldersFirst) Len() int      { return len(f) }
 func (f foldersFirst) Swap(i, j int) { f[i], f[j] = f[j], f[i] }
 func (f foldersFirst) Less(i, j int) bool {
-	if f[i].IsDir() && f[j].IsDir() {
-		return strings.ToLower(f[i].Name()) < strings.ToLower(f[j].Name())
+	return listedBefore(f[i].IsDir(), f[i].Name(), f[j].IsDir(), f[j].Name())
+}
+
+// listedBefore reports whether the first entry is listed before the second
+// one: directories go first, then entries are sorted by name regardless of
+// case, and names only differing in case are sorted by their exact name
+func listedBefore(aDir bool, aName string, bDir bool, bName string) bool {
+	if aDir != bDir {
+		return aDir
 	}
 
-	if !f[i].IsDir() && f[j].IsDir() {
-		return false
+	if a, b := strings.ToLower(aName), strings.ToLower(bName); a != b {
+		return a < b
 	}
 
-	if f[i].IsDir() && !f[j].IsDir() {
-		return true
+	return aName < bName
+}
+
+// listingSort is a way directory listings can be sorted, comparing two
+// files by a single property
+type listingSort struct {
+	compare func(a, b os.FileInfo) int
+
+	// dirsFirst lists directories before files regardless of the order
+	dirsFirst bool
+}
+
+// listingSorts are the properties directory listings can be sorted by.
+// Sorting by name or size keeps directories first, like the default
+// listing, while sorting by modification time mixes them with files, so
+// the most recent entry is always at the top or bottom.
+var listingSorts = map[string]listingSort{
+	"name": {compare: compareNames, dirsFirst: true},
+	"size": {compare: func(a, b os.FileInfo) int {
+		return cmp.Compare(a.Size(), b.Size())
+	}, dirsFirst: true},
+	"modtime": {compare: func(a, b os.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	}},
+}
+
+// compareNames compares file names the same way listedBefore does
+func compareNames(a, b os.FileInfo) int {
+	if c := strings.Compare(strings.ToLower(a.Name()), strings.ToLower(b.Name())); c != 0 {
+		return c
 	}
 
-	return strings.ToLower(f[i].Name()) < strings.ToLower(f[j].Name())
+	return strings.Compare(a.Name(), b.Name())
+}
+
+// listingOrder returns the property and direction the listing is sorted
+// by: the ones given in the "sort" and "order" query string parameters,
+// if valid, or the configured defaults otherwise
+func (s *Server) listingOrder(r *http.Request) (string, bool) {
+	by, order := s.DefaultSort, s.DefaultOrder
+
+	if v := r.URL.Query().Get("sort"); v != "" {
+		if _, found := listingSorts[v]; found {
+			by = v
+		}
+	}
+
+	if v := r.URL.Query().Get("order"); v == "asc" || v == "desc" {
+		order = v
+	}
+
+	if _, found := listingSorts[by]; !found {
+		by = "name"
+	}
+
+	return by, order == "desc"
+}
+
+// sortListing sorts the files of a directory listing by the given
+// property, breaking ties by name
+func sortListing(files []os.FileInfo, by string, desc bool) {
+	ls := listingSorts[by]
+
+	slices.SortStableFunc(files, func(a, b os.FileInfo) int {
+		if ls.dirsFirst && a.IsDir() != b.IsDir() {
+			if a.IsDir() {
+				return -1
+			}
+			return 1
+		}
+
+		c := ls.compare(a, b)
+		if c == 0 {
+			c = compareNames(a, b)
+		}
+
+		if desc {
+			return -c
+		}
+		return c
+	})
+}
+
+// groupDotfiles moves the dotfiles after every other entry, keeping the
+// order within each group, and returns the position of the first dotfile,
+// or -1 if there are none
+func groupDotfiles(files []os.FileInfo) int {
+	slices.SortStableFunc(files, func(a, b os.FileInfo) int {
+		aDot, bDot := isDotfile(a.Name()), isDotfile(b.Name())
+		if aDot == bDot {
+			return 0
+		}
+
+		if aDot {
+			return 1
+		}
+		return -1
+	})
+
+	return slices.IndexFunc(files, func(fi os.FileInfo) bool {
+		return isDotfile(fi.Name())
+	})
+}
+
+// isDotfile checks if the name starts with a dot
+func isDotfile(name string) bool {
+	return strings.HasPrefix(name, ".")
 }
@@ -0,0 +1,179 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_listingSort(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "b-medium.log", strings.Repeat("m", 50))
+	writeTestFile(t, root, "a-large.log", strings.Repeat("l", 100))
+	writeTestFile(t, root, "c-small.log", "s")
+	writeTestFile(t, root, "build-1/output.txt", "output")
+	writeTestFile(t, root, "build-2/output.txt", "output")
+
+	// Spread the modification times so the newest entries are,
+	// in order, the second build, the small log and the first build
+	now := time.Now()
+	for name, age := range map[string]time.Duration{
+		"build-2":      1 * time.Hour,
+		"c-small.log":  2 * time.Hour,
+		"build-1":      3 * time.Hour,
+		"a-large.log":  4 * time.Hour,
+		"b-medium.log": 5 * time.Hour,
+	} {
+		if err := os.Chtimes(filepath.Join(root, name), now.Add(-age), now.Add(-age)); err != nil {
+			t.Fatalf("unable to set modification time of %q: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		server *Server
+		path   string
+		want   []string
+	}{
+		{
+			name:   "default order",
+			server: &Server{},
+			path:   "/",
+			want:   []string{"build-1", "build-2", "a-large.log", "b-medium.log", "c-small.log"},
+		},
+		{
+			name:   "name descending",
+			server: &Server{DefaultOrder: "desc"},
+			path:   "/",
+			want:   []string{"build-2", "build-1", "c-small.log", "b-medium.log", "a-large.log"},
+		},
+		{
+			name:   "size keeps directories first",
+			server: &Server{DefaultSort: "size"},
+			path:   "/",
+			want:   []string{"build-1", "build-2", "c-small.log", "b-medium.log", "a-large.log"},
+		},
+		{
+			name:   "newest first",
+			server: &Server{DefaultSort: "modtime", DefaultOrder: "desc"},
+			path:   "/",
+			want:   []string{"build-2", "c-small.log", "build-1", "a-large.log", "b-medium.log"},
+		},
+		{
+			name:   "request overrides the default",
+			server: &Server{DefaultSort: "modtime", DefaultOrder: "desc"},
+			path:   "/?sort=name&order=asc",
+			want:   []string{"build-1", "build-2", "a-large.log", "b-medium.log", "c-small.log"},
+		},
+		{
+			name:   "invalid parameters are ignored",
+			server: &Server{DefaultSort: "size", DefaultOrder: "desc"},
+			path:   "/?sort=owner&order=random",
+			want:   []string{"build-2", "build-1", "a-large.log", "b-medium.log", "c-small.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			last := -1
+			for _, name := range tt.want {
+				i := strings.Index(body, `data-name="`+name+`"`)
+				if i < 0 {
+					t.Fatalf("expected %q to be listed", name)
+				}
+
+				if i < last {
+					t.Fatalf("expected entries in order %v, but %q is out of place", tt.want, name)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func Test_groupDotfiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, ".env", "env")
+	writeTestFile(t, root, "app.conf", "conf")
+	writeTestFile(t, root, ".git/HEAD", "ref")
+	writeTestFile(t, root, "src/main.go", "package main")
+	writeTestFile(t, root, "zeta.txt", "zeta")
+
+	tests := []struct {
+		name          string
+		server        *Server
+		want          []string
+		wantSeparator bool
+	}{
+		{
+			name:   "mixed by default",
+			server: &Server{},
+			want:   []string{".git", "src", ".env", "app.conf", "zeta.txt"},
+		},
+		{
+			name:          "grouped after other entries",
+			server:        &Server{GroupDotfiles: true},
+			want:          []string{"src", "app.conf", "zeta.txt", ".git", ".env"},
+			wantSeparator: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			last := -1
+			for _, name := range tt.want {
+				i := strings.Index(body, `data-name="`+name+`"`)
+				if i < 0 {
+					t.Fatalf("expected %q to be listed", name)
+				}
+
+				if i < last {
+					t.Fatalf("expected entries in order %v, but %q is out of place", tt.want, name)
+				}
+				last = i
+			}
+
+			separator := strings.Index(body, `class="dotfiles"`)
+			if (separator >= 0) != tt.wantSeparator {
+				t.Fatalf("expected separator to be shown: %v", tt.wantSeparator)
+			}
+
+			if tt.wantSeparator && (separator < strings.Index(body, `data-name="zeta.txt"`) || separator > strings.Index(body, `data-name=".git"`)) {
+				t.Errorf("expected separator between regular files and dotfiles")
+			}
+		})
+	}
+}
+
+func Test_groupDotfilesWithoutDotfiles(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "app.conf", "conf")
+
+	h := newTestHandler(t, &Server{Path: root, GroupDotfiles: true})
+
+	rec := doRequest(h, http.MethodGet, "/", nil)
+	if strings.Contains(rec.Body.String(), `class="dotfiles"`) {
+		t.Fatalf("expected no separator without dotfiles")
+	}
+}
@@ -1,15 +1,32 @@
 package server
 
+import "strings"
+
+// wellKnownDir is the directory used by protocols such as ACME to serve
+// files at well-known locations, which is never blocked from access
+const wellKnownDir = ".well-known"
+
 var forbiddenMatches = []string{
 	"_redirects",
 }
 
 var (
-	forbiddenPrefixes = []string{}
+	forbiddenPrefixes = []string{uploadTempPrefix}
 	forbiddenSuffixes = []string{}
 )
 
+// isFiltered checks if the file is blocked from access: filtered files
+// can't be requested directly and aren't shown in directory listings
 func (s *Server) isFiltered(filename string) bool {
+	if filename == wellKnownDir {
+		return false
+	}
+
+	// Layout markers are only meaningful when layouts are configured
+	if filename == layoutMarker && len(s.Layouts) > 0 {
+		return true
+	}
+
 	// Adds the config prefix to the list of forbidden prefixes
 	allPrefixes := append(s.forbiddenPrefixes, s.ConfigFilePrefix)
 
@@ -50,3 +67,14 @@ func (s *Server) isFiltered(filename string) bool {
 
 	return false
 }
+
+// isHidden checks if the file should be left out of directory listings.
+// Besides filtered files, this includes dotfiles when they're hidden,
+// which can still be requested directly by their URL.
+func (s *Server) isHidden(filename string) bool {
+	if s.isFiltered(filename) {
+		return true
+	}
+
+	return s.HideDotfiles && strings.HasPrefix(filename, ".")
+}
@@ -37,6 +37,12 @@ func Test_isFiltered(t *testing.T) {
 			match:    []string{"test.txt2"},
 			want:     false,
 		},
+		{
+			name:     "well-known directory never filtered",
+			filename: ".well-known",
+			prefix:   []string{"."},
+			want:     false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+const (
+	// gzipPreviewLines is how many decompressed lines previews show
+	gzipPreviewLines = 100
+
+	// gzipPreviewBytes is how many decompressed bytes previews read at
+	// most, so files with very long lines, or no lines at all, can't be
+	// used to decompress large amounts of data
+	gzipPreviewBytes = 1 << 20
+)
+
+// isGzipPreview reports whether the request asks for a preview of a
+// gzip-compressed file, with the "preview" query string parameter
+func (s *Server) isGzipPreview(fp string, r *http.Request) bool {
+	return s.GzipPreviews && strings.HasSuffix(fp, gzipExtension) && queryFlag(r, "preview")
+}
+
+// serveGzipPreview answers with the first lines of the decompressed
+// contents of a gzip-compressed file as plain text, decompressing only
+// as much of the file as needed to read them
+func (s *Server) serveGzipPreview(fp string, w http.ResponseWriter, r *http.Request) {
+	mw.SetServedPath(r, fp)
+
+	f, err := os.Open(fp)
+	if err != nil {
+		s.printWarning("unable to open file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open file -- see application logs for more information")
+		return
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(&contextReader{ctx: r.Context(), ReadSeeker: f})
+	if err != nil {
+		s.printWarning("unable to decompress file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to decompress file -- see application logs for more information")
+		return
+	}
+	defer gz.Close()
+
+	// The preview depends on the contents, which are untrusted, so
+	// browsers must not sniff them into something else than text
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	body := bufio.NewReader(io.LimitReader(gz, gzipPreviewBytes))
+	for i := 0; i < gzipPreviewLines; i++ {
+		line, err := body.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Long lines are sent in pieces, each counting as a line
+			err = nil
+		}
+
+		if len(line) > 0 {
+			if _, err := w.Write(line); err != nil {
+				return
+			}
+		}
+
+		if err == io.EOF {
+			return
+		}
+
+		if err != nil {
+			if ctxErr := r.Context().Err(); ctxErr != nil {
+				s.printCanceled(r, "stopped previewing file %q: %s", fp, ctxErr)
+				return
+			}
+
+			// The headers were sent already, so the preview can
+			// only be cut short
+			s.printWarning("unable to decompress file %q: %s", fp, err)
+			return
+		}
+	}
+}
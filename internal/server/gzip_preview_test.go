@@ -0,0 +1,140 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeGzipTestFile(t *testing.T, root, name, contents string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(contents)); err != nil {
+		t.Fatalf("unable to compress %q: %s", name, err)
+	}
+
+	if err := gz.Close(); err != nil {
+		t.Fatalf("unable to compress %q: %s", name, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, name), buf.Bytes(), 0o644); err != nil {
+		t.Fatalf("unable to write %q: %s", name, err)
+	}
+}
+
+func Test_gzipPreview(t *testing.T) {
+	var lines strings.Builder
+	for i := 1; i <= 500; i++ {
+		fmt.Fprintf(&lines, "log line %d\n", i)
+	}
+
+	root := t.TempDir()
+	writeGzipTestFile(t, root, "app.log.gz", lines.String())
+	writeGzipTestFile(t, root, "short.log.gz", "first\nsecond")
+	writeGzipTestFile(t, root, "huge.log.gz", strings.Repeat("a", 4*gzipPreviewBytes))
+	writeTestFile(t, root, "broken.log.gz", "not compressed")
+	writeTestFile(t, root, "plain.log", "plain\n")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		wantStatus int
+		check      func(t *testing.T, body string)
+	}{
+		{
+			name:       "first lines of a long file",
+			server:     &Server{GzipPreviews: true},
+			path:       "/app.log.gz?preview=1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body string) {
+				if got := strings.Count(body, "\n"); got != gzipPreviewLines {
+					t.Errorf("expected %d lines, got %d", gzipPreviewLines, got)
+				}
+
+				if !strings.HasPrefix(body, "log line 1\n") || !strings.HasSuffix(body, "log line 100\n") {
+					t.Errorf("expected the first %d lines, got %q...", gzipPreviewLines, body[:50])
+				}
+			},
+		},
+		{
+			name:       "whole short file",
+			server:     &Server{GzipPreviews: true},
+			path:       "/short.log.gz?preview",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body string) {
+				if body != "first\nsecond" {
+					t.Errorf("expected the whole file, got %q", body)
+				}
+			},
+		},
+		{
+			name:       "bounded decompression without lines",
+			server:     &Server{GzipPreviews: true},
+			path:       "/huge.log.gz?preview=1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body string) {
+				if len(body) == 0 || len(body) > gzipPreviewBytes {
+					t.Errorf("expected at most %d bytes, got %d", gzipPreviewBytes, len(body))
+				}
+			},
+		},
+		{
+			name:       "invalid gzip file",
+			server:     &Server{GzipPreviews: true},
+			path:       "/broken.log.gz?preview=1",
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "uncompressed files are served as they are",
+			server:     &Server{GzipPreviews: true},
+			path:       "/plain.log?preview=1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body string) {
+				if body != "plain\n" {
+					t.Errorf("expected the file contents, got %q", body)
+				}
+			},
+		},
+		{
+			name:       "disabled",
+			server:     &Server{},
+			path:       "/short.log.gz?preview=1",
+			wantStatus: http.StatusOK,
+			check: func(t *testing.T, body string) {
+				if !strings.HasPrefix(body, "\x1f\x8b") {
+					t.Errorf("expected the compressed file, got %q", body)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusOK && tt.server.GzipPreviews && strings.HasSuffix(strings.Split(tt.path, "?")[0], gzipExtension) {
+				if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+					t.Errorf("expected content type %q, got %q", "text/plain; charset=utf-8", got)
+				}
+			}
+
+			if tt.check != nil {
+				tt.check(t, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,232 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// gzipRangeBufferBytes is the largest decompressed size of a gzip file
+// that's decompressed into memory to serve ranges of it. Larger files
+// are decompressed again, up to the range, for every request.
+const gzipRangeBufferBytes = 8 << 20
+
+// gzipSizeCacheTTL is how long the decompressed sizes of large gzip files
+// are kept in memory, so they're only decompressed in full once
+const gzipSizeCacheTTL = time.Hour
+
+// maxGzipSizeCacheEntries is the most decompressed sizes kept in memory,
+// with the oldest ones evicted to make room for new ones
+const maxGzipSizeCacheEntries = 10_000
+
+// gzipSeeker reads a gzip file decompressed, as an io.ReadSeeker. Seeking
+// only records the new position: reads after it decompress the file up to
+// it, from the start of the file when seeking backwards. The decompressed
+// size is found by decompressing the whole file once, the first time
+// it's needed, unless it's already known.
+type gzipSeeker struct {
+	ctx  context.Context
+	f    io.ReadSeeker
+	gz   *gzip.Reader
+	pos  int64
+	read int64
+	size int64
+}
+
+// newGzipSeeker returns a decompressing reader of the gzip file, which
+// must be at its start, given its decompressed size, or -1 if unknown
+func newGzipSeeker(ctx context.Context, f io.ReadSeeker, size int64) (*gzipSeeker, error) {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return &gzipSeeker{ctx: ctx, f: f, gz: gz, size: size}, nil
+}
+
+// Read implements io.Reader
+func (g *gzipSeeker) Read(p []byte) (int, error) {
+	if err := g.moveTo(g.pos); err != nil {
+		return 0, err
+	}
+
+	if err := g.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	n, err := g.gz.Read(p)
+	g.read += int64(n)
+	g.pos = g.read
+	return n, err
+}
+
+// Seek implements io.Seeker
+func (g *gzipSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += g.pos
+	case io.SeekEnd:
+		size, err := g.length()
+		if err != nil {
+			return 0, err
+		}
+
+		offset += size
+	default:
+		return 0, errors.New("invalid whence")
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative position")
+	}
+
+	g.pos = offset
+	return offset, nil
+}
+
+// length returns the decompressed size of the file
+func (g *gzipSeeker) length() (int64, error) {
+	if g.size >= 0 {
+		return g.size, nil
+	}
+
+	if err := g.moveTo(-1); err != nil {
+		return 0, err
+	}
+
+	return g.size, nil
+}
+
+// moveTo decompresses the file up to the position, or all of it when
+// the position is negative, rewinding first if the position was already
+// decompressed past
+func (g *gzipSeeker) moveTo(pos int64) error {
+	if pos >= 0 && pos < g.read {
+		if _, err := g.f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+
+		if err := g.gz.Reset(g.f); err != nil {
+			return err
+		}
+
+		g.read = 0
+	}
+
+	var buf []byte
+	for pos < 0 || g.read < pos {
+		if err := g.ctx.Err(); err != nil {
+			return err
+		}
+
+		if buf == nil {
+			buf = make([]byte, 32<<10)
+		}
+
+		chunk := buf
+		if pos >= 0 && pos-g.read < int64(len(chunk)) {
+			chunk = chunk[:pos-g.read]
+		}
+
+		n, err := g.gz.Read(chunk)
+		g.read += int64(n)
+
+		if errors.Is(err, io.EOF) {
+			g.size = g.read
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serveGzippedRange serves the ranges requested of a gzip file, which
+// the client wants decompressed. Small files are decompressed into memory,
+// while larger ones are decompressed up to the requested range.
+// Decompressing counts as an expensive operation.
+func (s *Server) serveGzippedRange(gzPath string, f *os.File, fi os.FileInfo, w http.ResponseWriter, r *http.Request) {
+	release, ok := s.startExpensiveOp(w, r)
+	if !ok {
+		return
+	}
+	defer release()
+
+	content, err := s.gzippedRangeContent(gzPath, f, fi, r)
+	if err != nil {
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped serving file %q: %s", gzPath, err)
+			return
+		}
+
+		s.printWarning("unable to decompress file %q: %s", gzPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to decompress file -- see application logs for more information")
+		return
+	}
+
+	if size, err := content.Seek(0, io.SeekEnd); err == nil {
+		normalizeRange(r, size)
+
+		if _, large := content.(*gzipSeeker); large {
+			s.gzipSizeCache.set(gzPath, uint64(fi.Size()), fi.ModTime(), gzipSizeCacheTTL, []byte(strconv.FormatInt(size, 10)))
+		}
+	}
+
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		s.printWarning("unable to decompress file %q: %s", gzPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to decompress file -- see application logs for more information")
+		return
+	}
+
+	http.ServeContent(w, r, "", fi.ModTime(), content)
+
+	if err := r.Context().Err(); err != nil {
+		s.printCanceled(r, "stopped serving file %q: %s", gzPath, err)
+	}
+}
+
+// gzippedRangeContent returns the decompressed contents of the gzip file,
+// from memory if they fit in the buffer, or decompressed as they're read.
+// Files already known not to fit aren't decompressed into memory again.
+func (s *Server) gzippedRangeContent(gzPath string, f *os.File, fi os.FileInfo, r *http.Request) (io.ReadSeeker, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	if cached, found := s.gzipSizeCache.get(gzPath, uint64(fi.Size()), fi.ModTime()); found {
+		if size, err := strconv.ParseInt(string(cached), 10, 64); err == nil {
+			return newGzipSeeker(r.Context(), f, size)
+		}
+	}
+
+	gz, err := gzip.NewReader(&contextReader{ctx: r.Context(), ReadSeeker: f})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(io.LimitReader(gz, gzipRangeBufferBytes+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) <= gzipRangeBufferBytes {
+		return bytes.NewReader(data), nil
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("unable to seek back to the start of the file: %w", err)
+	}
+
+	return newGzipSeeker(r.Context(), f, -1)
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_gzipSeeker(t *testing.T) {
+	var contents strings.Builder
+	for i := 0; contents.Len() < 200<<10; i++ {
+		fmt.Fprintf(&contents, "line %d\n", i)
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(contents.String()))
+	zw.Close()
+
+	g, err := newGzipSeeker(context.Background(), bytes.NewReader(compressed.Bytes()), -1)
+	if err != nil {
+		t.Fatalf("unable to create reader: %s", err)
+	}
+
+	size, err := g.Seek(0, io.SeekEnd)
+	if err != nil {
+		t.Fatalf("unable to seek to the end: %s", err)
+	}
+
+	if size != int64(contents.Len()) {
+		t.Fatalf("expected size %d, got %d", contents.Len(), size)
+	}
+
+	// Seek forwards and backwards, reading a few bytes each time
+	for _, offset := range []int64{150 << 10, 10, 100 << 10, 0, size - 5} {
+		if _, err := g.Seek(offset, io.SeekStart); err != nil {
+			t.Fatalf("unable to seek to %d: %s", offset, err)
+		}
+
+		buf := make([]byte, 5)
+		if _, err := io.ReadFull(g, buf); err != nil {
+			t.Fatalf("unable to read at %d: %s", offset, err)
+		}
+
+		if want := contents.String()[offset : offset+5]; string(buf) != want {
+			t.Errorf("expected %q at %d, got %q", want, offset, buf)
+		}
+	}
+}
+
+func Test_gzippedRangeSizeCache(t *testing.T) {
+	contents := strings.Repeat("a", gzipRangeBufferBytes+1)
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(contents))
+	zw.Close()
+
+	root := t.TempDir()
+	writeTestFile(t, root, "large.txt.gz", compressed.String())
+
+	s := &Server{Path: root, ServeGzipped: true}
+	h := newTestHandler(t, s)
+
+	// The second request gets the decompressed size from the cache
+	for i := 0; i < 2; i++ {
+		rec := doRequest(h, http.MethodGet, "/large.txt", map[string]string{"Range": "bytes=-5"})
+		if rec.Code != http.StatusPartialContent {
+			t.Fatalf("expected status %d, got %d", http.StatusPartialContent, rec.Code)
+		}
+
+		if want := fmt.Sprintf("bytes %d-%d/%d", len(contents)-5, len(contents)-1, len(contents)); rec.Header().Get("Content-Range") != want {
+			t.Fatalf("expected content range %q, got %q", want, rec.Header().Get("Content-Range"))
+		}
+
+		if got := s.gzipSizeCache.len(); got != 1 {
+			t.Fatalf("expected the decompressed size to be cached, got %d entries", got)
+		}
+	}
+}
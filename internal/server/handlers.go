@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -9,44 +10,123 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
-	"unicode/utf8"
+	"time"
 
-	"github.com/saintfish/chardet"
+	"github.com/patrickdappollonio/http-server/internal/mw"
 )
 
 const (
-	logFormat   = `{http_method} "{url}" -- {proto} {status_code} {status_text} (served in {duration}; {bytes_written} bytes)`
-	specialPath = "_"
+	logFormat           = `{http_method} "{url}" -- {proto} {status_code} {status_text} (served in {duration}; {bytes_written} bytes)`
+	logFormatServedPath = ` -- served path: {served_path}`
+	specialPath         = "_"
 )
 
 // showOrRender is the main handler for the server. It will either render the
 // content requested or show a directory listing.
 func (s *Server) showOrRender(w http.ResponseWriter, r *http.Request) {
-	relpath := filepath.Join(s.Path, strings.TrimPrefix(r.URL.Path, s.PathPrefix))
+	// Backslashes are handled the same way on every platform,
+	// before they can be taken as separators on Windows
+	if s.redirectBackslashes(w, r) {
+		return
+	}
+
+	// The path is decoded already, but it isn't cleaned by the router, so
+	// it's cleaned here as an absolute path to make sure dot segments can't
+	// resolve to a location outside of the served directory
+	requested := path.Clean("/" + strings.TrimPrefix(r.URL.Path, s.requestPrefix(r)))
+
+	// Reject paths nested deeper than allowed before touching the disk
+	if s.MaxPathDepth > 0 && pathDepth(requested) > s.MaxPathDepth {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: path is nested too deep")
+		return
+	}
 
-	// Generate an absolute path off a relative one
-	currentPath, err := filepath.Abs(relpath)
+	// Configured redirections apply before looking for the path on disk
+	if s.applyRedirectRules(requested, w, r) {
+		return
+	}
+
+	// Generate an absolute path off a relative one, within the aliased
+	// directory if the path is under an alias, which is also where
+	// changes are written to when overlays are in use
+	basePath, err := s.servedPath(requested)
 	if err != nil {
 		fmt.Fprintln(s.LogOutput, "error generating absolute path:", err)
-		httpError(http.StatusInternalServerError, w, "internal error generating full paths -- see application logs for details")
+		s.httpError(http.StatusInternalServerError, w, r, "internal error generating full paths -- see application logs for details")
+		return
+	}
+
+	// WebDAV requests work on paths that might not exist yet,
+	// so they're handled before checking the path
+	if r.Method == methodMkcol {
+		mw.SetServedPath(r, basePath)
+		s.makeCollection(basePath, w, r)
+		return
+	}
+
+	if r.Method == methodMove {
+		mw.SetServedPath(r, basePath)
+		s.move(basePath, w, r)
 		return
 	}
 
+	// Files are read from the overlay with the highest precedence
+	// that has them, if any
+	currentPath := s.resolveOverlay(requested, basePath)
+
+	// Keep track of the resolved path for the access log
+	mw.SetServedPath(r, currentPath)
+
 	// Stat the current path
 	info, err := os.Stat(currentPath)
 	if err != nil {
+		// If the served directory itself is gone, every request would fail,
+		// so report the server as unavailable rather than a generic error
+		if !s.isRootAvailable() {
+			s.rootUnavailableError(w, r)
+			return
+		}
+
 		// If the path doesn't exist, return the 404 error but also print in the log
 		// of the app the full path to the given location
 		if os.IsNotExist(err) {
+			// Serve the generated directory index, unless a real
+			// file with the same name exists
+			if s.isIndexJSON(requested) {
+				s.serveIndexJSON(path.Dir(requested), w, r)
+				return
+			}
+
+			// Before giving up, check if the path exists with one of the
+			// configured extensions, to support "pretty" URLs
+			if found := s.findWithExtension(r.URL.Path, requested, basePath); found != "" {
+				s.serveFile(found, w, r)
+				return
+			}
+
+			// Serve a gzip-compressed copy of the file, if there's one
+			if s.ServeGzipped {
+				if gzPath := s.findGzipped(currentPath); gzPath != "" {
+					s.serveGzipped(gzPath, w, r)
+					return
+				}
+			}
+
+			// Redirect to the path with the case it has on disk, if
+			// case-insensitive matching is enabled
+			if s.CaseInsensitive && s.redirectCaseInsensitive(requested, w, r) {
+				return
+			}
+
 			s.printWarning("attempted to access non-existent path: %s", currentPath)
-			httpError(http.StatusNotFound, w, "404 not found")
+			s.httpError(http.StatusNotFound, w, r, "404 not found")
 			return
 		}
 
 		// If it's any other kind of error, return the 500 error and log the actual error
 		// to the app log
 		s.printWarning("unable to stat directory %q: %s", currentPath, err)
-		httpError(http.StatusInternalServerError, w, "unable to stat directory -- see application logs for more information")
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat directory -- see application logs for more information")
 		return
 	}
 
@@ -54,52 +134,253 @@ func (s *Server) showOrRender(w http.ResponseWriter, r *http.Request) {
 	if info.IsDir() {
 		// Check if the path doesn't ends in a slash, and redirect accordingly
 		if !strings.HasSuffix(r.URL.Path, "/") {
-			http.Redirect(w, r, r.URL.Path+"/", http.StatusMovedPermanently)
+			http.Redirect(w, r, fileURL(true, s.publicPath(r, r.URL.Path)), http.StatusMovedPermanently)
 			return
 		}
 
-		s.walk(currentPath, w, r)
+		// Directories can be created, and files uploaded, only
+		// within directories
+		if r.Method == http.MethodPost {
+			if name, found := mkdirName(r); found {
+				s.mkdir(basePath, name, w, r)
+				return
+			}
+
+			s.upload(basePath, w, r)
+			return
+		}
+
+		// Show the upload form if requested
+		if s.AllowUpload && r.URL.Query().Has("upload") {
+			s.uploadForm(w, r)
+			return
+		}
+
+		// Render the listing as a feed if requested
+		if s.isFeedRequest(r) {
+			s.serveFeed(currentPath, s.overlayDirs(requested, currentPath), w, r)
+			return
+		}
+
+		// Render the media files in the directory as a playlist if requested
+		if s.isPlaylistRequest(r) {
+			s.servePlaylist(currentPath, s.overlayDirs(requested, currentPath), w, r)
+			return
+		}
+
+		// Send the changes to the directory as they happen if requested
+		if s.isWatchRequest(r) {
+			s.watchDirectory(currentPath, s.overlayDirs(requested, currentPath), w, r)
+			return
+		}
+
+		// Serve the configured landing page for the root only, in
+		// place of its index file or listing
+		if requested == "/" && s.RootDocument != "" && !queryFlag(r, "noindex") && s.serveRootDocument(w, r) {
+			return
+		}
+
+		s.serveResolved(currentPath, info, w, r, func(w http.ResponseWriter, r *http.Request) {
+			s.walk(currentPath, s.overlayDirs(requested, currentPath), w, r)
+		})
+		return
+	}
+
+	// Files are never requested with a trailing slash, so when the path
+	// ends in one, redirect to the canonical location without it
+	if strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, fileURL(false, s.publicPath(r, r.URL.Path)), http.StatusMovedPermanently)
 		return
 	}
 
+	// Only directories accept uploads
+	if r.Method == http.MethodPost {
+		w.Header().Set("Allow", "GET, HEAD")
+		s.httpError(http.StatusMethodNotAllowed, w, r, "405 method not allowed: files can only be uploaded into directories")
+		return
+	}
+
+	// Checked before any of the ways of serving the file below, since
+	// they all give away its contents
+	if s.refuseWritable(currentPath, info, w, r) {
+		return
+	}
+
+	// Show the file with its syntax highlighted if requested
+	if s.isCodeViewRequest(r) {
+		s.serveCodeView(currentPath, w, r)
+		return
+	}
+
+	// Show the first lines of a compressed file if requested
+	if s.isGzipPreview(currentPath, r) {
+		s.serveGzipPreview(currentPath, w, r)
+		return
+	}
+
+	// Answer with the checksum of the file instead of its contents
+	if s.Checksums && r.URL.Query().Has("checksum") {
+		s.serveChecksum(currentPath, w, r)
+		return
+	}
+
+	// Convert images to another format if requested, falling back
+	// to the original file if it can't be decoded
+	if s.ImageConversion && r.URL.Query().Has("format") {
+		if s.serveConvertedImage(currentPath, w, r) {
+			return
+		}
+	}
+
 	// If the path is not a directory, then it's a file, so we can render it
-	s.serveFile(currentPath, w, r)
+	s.serveResolved(currentPath, info, w, r, func(w http.ResponseWriter, r *http.Request) {
+		s.serveFile(currentPath, w, r)
+	})
 }
 
-func (s *Server) walk(requestedPath string, w http.ResponseWriter, r *http.Request) {
+// refuseWritable answers with a 403 error if the file is writable by its
+// group or others and refusing such files is enabled, reporting whether
+// it did. Files anyone could have changed might not be what they're meant
+// to be, so none of their contents are served, in any form.
+func (s *Server) refuseWritable(fp string, fi os.FileInfo, w http.ResponseWriter, r *http.Request) bool {
+	if !s.RefuseWorldWritable || !isGroupOrWorldWritable(fi) {
+		return false
+	}
+
+	s.printWarning("refusing to serve file %q, which is writable by its group or others (%s)", fp, fi.Mode().Perm())
+	s.httpError(http.StatusForbidden, w, r, "403 forbidden")
+	return true
+}
+
+// findWithExtension returns the path to a file matching the requested
+// path plus one of the configured extensions, so a request to "/about"
+// can be served from "about.html". Requests that already have an extension
+// or look like a directory are not retried, so missing assets are not masked.
+// With overlays, each directory is tried in order of precedence.
+func (s *Server) findWithExtension(urlPath, requested, fullPath string) string {
+	if strings.HasSuffix(urlPath, "/") || path.Ext(urlPath) != "" {
+		return ""
+	}
+
+	paths := []string{fullPath}
+	if len(s.Roots) > 0 {
+		if layers, err := s.layerPaths(requested); err == nil {
+			paths = layers
+		}
+	}
+
+	for _, p := range paths {
+		for _, ext := range s.TryExtensions {
+			candidate := p + ext
+
+			// Skip files that are not allowed to be accessed
+			if s.isFiltered(filepath.Base(candidate)) {
+				continue
+			}
+
+			if info, err := os.Stat(candidate); err == nil && !info.IsDir() {
+				return candidate
+			}
+		}
+	}
+
+	return ""
+}
+
+// serveRootDocument serves the configured root document, a path within
+// the served directory, reporting whether it was served. If it doesn't
+// exist, or isn't a file, the root is served as usual.
+func (s *Server) serveRootDocument(w http.ResponseWriter, r *http.Request) bool {
+	requested := path.Clean("/" + s.RootDocument)
+	if s.isFiltered(path.Base(requested)) {
+		s.printWarning("root document %q is a filtered file, serving the root as usual", s.RootDocument)
+		return false
+	}
+
+	basePath, err := s.servedPath(requested)
+	if err != nil {
+		s.printWarning("unable to generate absolute path for root document %q: %s", s.RootDocument, err)
+		return false
+	}
+
+	fp := s.resolveOverlay(requested, basePath)
+	if info, err := os.Stat(fp); err != nil || info.IsDir() {
+		s.printWarning("root document %q is not a file, serving the root as usual", fp)
+		return false
+	}
+
+	s.serveFile(fp, w, r)
+	return true
+}
+
+func (s *Server) walk(requestedPath string, overlays []string, w http.ResponseWriter, r *http.Request) {
 	// Append index.html or index.htm to the path and see if the index
-	// file exists, if so, return it instead
-	for _, index := range []string{"index.html", "index.htm"} {
-		indexPath := filepath.Join(requestedPath, index)
-		if _, err := os.Stat(indexPath); err == nil {
-			s.serveFile(indexPath, w, r)
-			return
+	// file exists, if so, return it instead, unless the request asked
+	// for the listing with the "noindex" query string parameter. With
+	// index templating, the index is rendered with the listing data
+	// instead, so the directory is still read below.
+	var indexTemplate string
+	if s.DisableDirectoryList || !queryFlag(r, "noindex") {
+	findIndex:
+		for _, dir := range append([]string{requestedPath}, overlays...) {
+			for _, index := range []string{"index.html", "index.htm"} {
+				indexPath := filepath.Join(dir, index)
+				if _, err := os.Stat(indexPath); err == nil {
+					if !s.IndexTemplating {
+						s.serveFile(indexPath, w, r)
+						return
+					}
+
+					indexTemplate = indexPath
+					break findIndex
+				}
+			}
 		}
 	}
 
 	// Check if directory listing is disabled, if so,
 	// return here with a 404 error
-	if s.DisableDirectoryList {
-		httpError(http.StatusNotFound, w, "404 not found")
+	if s.DisableDirectoryList && indexTemplate == "" {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	// Check if the listing should be streamed instead of
+	// being read and rendered all at once
+	if s.StreamListing && indexTemplate == "" && s.templates != nil {
+		s.streamListing(requestedPath, overlays, w, r)
 		return
 	}
 
 	// Open the directory path and read all files
 	dir, err := os.Open(requestedPath)
 	if err != nil {
+		// Check if the served directory is gone
+		if !s.isRootAvailable() {
+			s.rootUnavailableError(w, r)
+			return
+		}
+
 		// If the directory doesn't exist, render an appropriate message
 		if os.IsNotExist(err) {
 			s.printWarning("attempted to access non-existent path: %s", requestedPath)
-			httpError(http.StatusNotFound, w, "404 not found")
+			s.httpError(http.StatusNotFound, w, r, "404 not found")
 			return
 		}
 
 		// Otherwise handle it generically speaking
 		s.printWarning("unable to open directory %q: %s", requestedPath, err)
-		httpError(http.StatusInternalServerError, w, "unable to open directory -- see application logs for more information")
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open directory -- see application logs for more information")
 		return
 	}
 
+	// Keep the directory modification time, used to invalidate
+	// cached listings when files are added or removed
+	var dirModTime time.Time
+	if dirInfo, err := dir.Stat(); err == nil {
+		dirModTime = dirInfo.ModTime()
+	}
+
 	// Read all files in the directory then close the directory
 	list, err := dir.ReadDir(-1)
 	dir.Close()
@@ -107,129 +388,305 @@ func (s *Server) walk(requestedPath string, w http.ResponseWriter, r *http.Reque
 	// Handle error on readdir call
 	if err != nil {
 		s.printWarning("unable to read directory %q: %s", requestedPath, err)
-		httpError(http.StatusInternalServerError, w, "unable to read directory -- see application logs for more information")
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read directory -- see application logs for more information")
 		return
 	}
 
+	// Merge the entries of the same directory in every overlay, which
+	// also changes the listing when they're modified
+	list, from := s.readOverlays(list, overlays)
+	for _, dir := range overlays {
+		if dirInfo, err := os.Stat(dir); err == nil && dirInfo.ModTime().After(dirModTime) {
+			dirModTime = dirInfo.ModTime()
+		}
+	}
+
 	// Render the directory listing
 	sort.Sort(foldersFirst(list))
 
+	// Only show the files matching the filter, if one was given
+	filter := s.listingFilter(r)
+
 	// Generate a list of FileInfo objects
 	files := make([]os.FileInfo, 0, len(list))
 	for _, f := range list {
+		// Stop early if the client is gone, since nobody
+		// will see the listing anyways
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped listing directory %q: %s", requestedPath, err)
+			return
+		}
+
 		fi, err := f.Info()
 		if err != nil {
 			s.printWarning("unable to stat file %q: %s", f.Name(), err)
-			httpError(http.StatusInternalServerError, w, "unable to stat file %q -- see application logs for more information", f.Name())
+			s.httpError(http.StatusInternalServerError, w, r, "unable to stat file %q -- see application logs for more information", f.Name())
 			return
 		}
 
-		// Check if file starts with config prefix
-		if s.isFiltered(fi.Name()) {
+		// Skip filtered files, dotfiles if they're hidden, and
+		// files not matching the filter given in the request
+		if s.isHidden(fi.Name()) || !matchesListingFilter(filter, fi) {
 			continue
 		}
 
+		// Keep track of files coming from overlays
+		if dir, found := from[fi.Name()]; found {
+			fi = layeredFileInfo{FileInfo: fi, dir: dir}
+		}
+
 		files = append(files, fi)
 	}
 
-	// Find if among the files there's a markdown readme
+	// Entries are listed by name, with directories first, unless
+	// another order was configured or requested
+	if by, desc := s.listingOrder(r); by != "name" || desc {
+		sortListing(files, by, desc)
+	}
+
+	// Dotfiles are listed after every other entry, if enabled,
+	// with the listing marking where they start
+	dotfilesFrom := -1
+	if s.GroupDotfiles {
+		dotfilesFrom = groupDotfiles(files)
+	}
+
+	// Links are generated using the path prefix the client sees,
+	// in case the server is running behind a reverse proxy
+	prefix := s.publicPrefix(r)
+	currentPath := s.publicPath(r, r.URL.Path)
+
+	// Bare listings, and directories with a custom layout, are rendered
+	// with a different template, so they're cached separately
+	bare := s.isBareListing(r)
+	tpl, templates := "app.tmpl", s.templates
+	var layout string
+	if bare {
+		tpl = "bare.tmpl"
+	} else if layout = s.listingLayout(requestedPath, r); layout != "" {
+		tpl, templates = layoutTemplate, s.layouts[layout]
+	}
+
+	// Serve a previously rendered listing if caching is enabled
+	// and the directory hasn't changed since then
+	var hash uint64
+	if s.ListingCacheTTL > 0 && indexTemplate == "" {
+		hash = hashListing(files, prefix, currentPath, tpl, layout, filter, requestedPageTitle(r))
+		if body, found := s.listingCache.get(requestedPath, hash, dirModTime); found {
+			s.setListingCacheControl(w)
+			w.Write(body)
+			return
+		}
+	}
+
+	// Find if among the files there's a markdown readme, which
+	// bare listings never include
 	var markdownContent bytes.Buffer
-	if err := s.generateMarkdown(requestedPath, files, &markdownContent); err != nil {
-		s.printWarning("unable to generate markdown: %s", err)
-		httpError(http.StatusInternalServerError, w, "unable to generate markdown for current directory -- see application logs for more information")
-		return
+	if !bare {
+		if err := s.generateMarkdown(requestedPath, prefix, files, &markdownContent); err != nil {
+			s.printWarning("unable to generate markdown: %s", err)
+			s.httpError(http.StatusInternalServerError, w, r, "unable to generate markdown for current directory -- see application logs for more information")
+			return
+		}
 	}
 
 	// Define the parent directory
-	parent := getParentURL(s.PathPrefix, r.URL.Path)
+	parent := getParentURL(prefix, currentPath)
 
 	// Render the directory listing
 	content := map[string]any{
-		"DirectoryRootPath": s.PathPrefix,
-		"PageTitle":         s.PageTitle,
-		"CurrentPath":       r.URL.Path,
+		"DirectoryRootPath": prefix,
+		"PageTitle":         s.pageTitle(r),
+		"CurrentPath":       currentPath,
 		"CacheBuster":       s.cacheBuster,
 		"Files":             files,
 		"RequestedPath":     requestedPath,
-		"IsRoot":            s.PathPrefix == r.URL.Path,
+		"IsRoot":            s.requestPrefix(r) == r.URL.Path,
 		"UpDirectory":       parent,
 		"HideLinks":         s.HideLinks,
+		"Columns":           s.listColumns(),
+		"AllowUpload":       s.AllowUpload,
 		"MarkdownContent":   markdownContent.String(),
 		"MarkdownBeforeDir": s.MarkdownBeforeDir,
+		"Filter":            filter,
+		"DotfilesFrom":      dotfilesFrom,
 	}
 
-	if err := s.templates.ExecuteTemplate(w, "app.tmpl", content); err != nil {
-		s.printWarning("unable to render directory listing: %s", err)
-		httpError(http.StatusInternalServerError, w, "unable to render directory listing -- see application logs for more information")
+	// Index files rendered as templates get the same data as the
+	// listing, and are never cached, since they can change at any time
+	if indexTemplate != "" {
+		s.serveIndexTemplate(indexTemplate, content, w, r)
 		return
 	}
+
+	// Without templates, the files are still listed, as plain HTML
+	if templates == nil {
+		s.serveFallbackListing(content, w)
+		return
+	}
+
+	// Without caching or render limits, the listing is written
+	// straight to the client
+	if s.ListingCacheTTL <= 0 && !s.hasListingLimits() {
+		s.setListingCacheControl(w)
+
+		// The plain listing can only replace the template if it
+		// failed before writing anything to the client
+		tw := &writeTracker{Writer: w}
+		if err := templates.ExecuteTemplate(tw, tpl, content); err != nil {
+			s.printError("unable to render directory listing %q with template %q: %s", requestedPath, tpl, err)
+			if !tw.written {
+				s.serveFallbackListing(content, w)
+			}
+		}
+		return
+	}
+
+	// Otherwise it's rendered in memory first, within the limits, so
+	// it can still be rejected if it grows too large or slow
+	body := s.newListingRender()
+	started := time.Now()
+	if err := templates.ExecuteTemplate(body, tpl, content); err != nil {
+		if errors.Is(err, errListingLimit) {
+			s.printWarning("aborted rendering directory listing %q after %s and %d bytes: over the configured render limits", requestedPath, time.Since(started).Round(time.Millisecond), body.Len())
+			s.httpError(http.StatusInternalServerError, w, r, "directory listing is too large to render -- see application logs for more information")
+			return
+		}
+
+		s.printError("unable to render directory listing %q with template %q: %s", requestedPath, tpl, err)
+		s.serveFallbackListing(content, w)
+		return
+	}
+
+	// Listings still waiting for checksums aren't cached, so
+	// they show up as soon as they're computed
+	if s.ListingCacheTTL > 0 && !s.checksumsPending(requestedPath, files) {
+		s.listingCache.set(requestedPath, hash, dirModTime, s.ListingCacheTTL, body.Bytes())
+	}
+
+	s.setListingCacheControl(w)
+	w.Write(body.Bytes())
+}
+
+// isBareListing checks if the directory listing should be rendered as a
+// plain list of links, either because it's configured that way or because
+// the request asked for it with the "bare" query string parameter
+func (s *Server) isBareListing(r *http.Request) bool {
+	if s.BareListing {
+		return true
+	}
+
+	return queryFlag(r, "bare")
+}
+
+// queryFlag checks if the query string parameter is set to anything
+// other than "0" or "false", including being set without a value
+func queryFlag(r *http.Request, name string) bool {
+	query := r.URL.Query()
+	if !query.Has(name) {
+		return false
+	}
+
+	switch query.Get(name) {
+	case "0", "false":
+		return false
+	default:
+		return true
+	}
+}
+
+// setListingCacheControl sets the "Cache-Control" header configured for
+// directory listings, which is independent of the one used for files
+func (s *Server) setListingCacheControl(w http.ResponseWriter) {
+	if s.ListingCacheControl != "" {
+		w.Header().Set("Cache-Control", s.ListingCacheControl)
+	}
 }
 
 // serveFile serves a file with the appropriate headers, including support
 // for ETag and Last-Modified headers, as well as range requests.
 func (s *Server) serveFile(fp string, w http.ResponseWriter, r *http.Request) {
+	mw.SetServedPath(r, fp)
+
 	f, err := os.Open(fp)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.printWarning("unable to open file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open file -- see application logs for more information")
 		return
 	}
 	defer f.Close()
 
 	fi, err := f.Stat()
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		s.printWarning("unable to stat file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat file -- see application logs for more information")
 		return
 	}
 
-	var ctype string
-	if local := getContentTypeForFilename(filepath.Base(fp)); local != "" {
-		ctype = local
+	if s.refuseWritable(fp, fi, w, r) {
+		return
 	}
 
-	var data [512]byte
-	if _, err := f.Read(data[:]); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	ctype, err := s.detectContentType(f, fi)
+	if err != nil {
+		s.printWarning("unable to read file %q: %s", fp, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read file -- see application logs for more information")
 		return
 	}
 
-	if ctype == "" {
-		if local := http.DetectContentType(data[:]); local != "application/octet-stream" {
-			ctype = local
-		}
+	if ctype != "" {
+		w.Header().Set("Content-Type", ctype)
 	}
 
-	charset := ""
-	if utf8.Valid(data[:]) {
-		charset = "utf-8"
+	// Tell the browser whether to download or display the file
+	s.setContentDisposition(fi.Name(), fi.Size(), w, r)
+
+	// Leave out the byte order mark of UTF-8 text files, if enabled
+	stripBOM := s.StripBOM && isTextContentType(ctype) && hasUTF8BOM(f)
+
+	// Let clients probing the file learn its checksum, which is only
+	// the one of the response when the full file is sent
+	if s.ChecksumHeaders && r.Method == http.MethodHead && !stripBOM {
+		s.setChecksumHeader(fp, fi, w, r)
 	}
 
-	if charset == "" {
-		res, err := chardet.NewTextDetector().DetectBest(data[:])
-		if err == nil && res.Confidence > 50 && res.Charset != "" {
-			charset = res.Charset
-		}
+	// Ignore malformed ranges and reject unsatisfiable ones consistently
+	normalizeRange(r, fi.Size())
+
+	// Let the reverse proxy send the file, if configured to
+	if s.AccelRedirect != "" && s.delegateFile(fp, fi, w) {
+		return
 	}
 
-	if ctype != "" && ctype != "application/octet-stream" {
-		if charset != "" {
-			ctype += "; charset=" + charset
+	// Stop reading the file if the client goes away mid-transfer
+	reader, release := s.fileReader(f)
+	defer release()
+	content := &contextReader{ctx: r.Context(), ReadSeeker: reader}
+
+	if stripBOM {
+		withoutBOM, err := skipBOM(reader)
+		if err != nil {
+			s.printWarning("unable to read file %q: %s", fp, err)
+			s.httpError(http.StatusInternalServerError, w, r, "unable to read file -- see application logs for more information")
+			return
 		}
 
-		w.Header().Set("Content-Type", ctype)
+		content.ReadSeeker = withoutBOM
 	}
 
-	http.ServeContent(w, r, fi.Name(), fi.ModTime(), f)
-}
-
-// healthCheck is a simple health check endpoint that returns 200 OK
-func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
-}
+	// Send the checksum of the file after its contents, for clients
+	// supporting chunked responses, if enabled
+	if s.ChecksumTrailers && r.Method == http.MethodGet && r.ProtoAtLeast(1, 1) && !stripBOM {
+		s.serveWithChecksumTrailer(fp, fi, w, r, func(w http.ResponseWriter) {
+			http.ServeContent(w, r, fi.Name(), fi.ModTime(), content)
+		})
+	} else {
+		http.ServeContent(w, r, fi.Name(), fi.ModTime(), content)
+	}
 
-func httpError(statusCode int, w http.ResponseWriter, format string, args ...any) {
-	w.WriteHeader(statusCode)
-	fmt.Fprintf(w, format, args...)
+	if err := r.Context().Err(); err != nil {
+		s.printCanceled(r, "stopped serving file %q: %s", fp, err)
+	}
 }
 
 func getParentURL(base string, loc string) string {
@@ -245,3 +702,15 @@ func getParentURL(base string, loc string) string {
 
 	return s + "/"
 }
+
+// pathDepth returns the amount of non-empty segments in the path
+func pathDepth(p string) int {
+	depth := 0
+	for _, segment := range strings.Split(p, "/") {
+		if segment != "" {
+			depth++
+		}
+	}
+
+	return depth
+}
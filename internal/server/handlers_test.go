@@ -0,0 +1,1449 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestHandler prepares the given server for tests, filling in sane
+// defaults, and returns its router
+func newTestHandler(t *testing.T, s *Server) http.Handler {
+	t.Helper()
+
+	if s.Path == "" {
+		s.Path = t.TempDir()
+	}
+
+	if s.PathPrefix == "" {
+		s.PathPrefix = "/"
+	}
+
+	if s.LogOutput == nil {
+		s.LogOutput = io.Discard
+	}
+
+	templates, err := s.generateTemplates()
+	if err != nil {
+		t.Fatalf("unable to generate templates: %s", err)
+	}
+	s.templates = templates
+
+	if err := s.loadErrorTemplate(); err != nil {
+		t.Fatalf("unable to load error template: %s", err)
+	}
+
+	router, err := s.router()
+	if err != nil {
+		t.Fatalf("unable to generate router: %s", err)
+	}
+
+	return router
+}
+
+// writeTestFile creates a file with the given contents, including
+// any missing parent directories
+func writeTestFile(t testing.TB, root, name, contents string) {
+	t.Helper()
+
+	fp := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(fp), 0o755); err != nil {
+		t.Fatalf("unable to create directory for %q: %s", name, err)
+	}
+
+	if err := os.WriteFile(fp, []byte(contents), 0o644); err != nil {
+		t.Fatalf("unable to write file %q: %s", name, err)
+	}
+}
+
+// doRequest sends a request with the given method and path to the handler
+func doRequest(h http.Handler, method, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func Test_rootRemovedAtRuntime(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "root")
+	writeTestFile(t, root, "file.txt", "hello")
+
+	s := &Server{Path: root}
+	h := newTestHandler(t, s)
+
+	paths := []string{"/file.txt", "/", "/missing.txt"}
+
+	for _, p := range paths {
+		if rec := doRequest(h, http.MethodGet, p, nil); rec.Code == http.StatusServiceUnavailable {
+			t.Fatalf("GET %s: unexpected 503 before removing the root", p)
+		}
+	}
+
+	if err := os.Rename(root, root+"-moved"); err != nil {
+		t.Fatalf("unable to rename root: %s", err)
+	}
+
+	for _, p := range paths {
+		if rec := doRequest(h, http.MethodGet, p, nil); rec.Code != http.StatusServiceUnavailable {
+			t.Errorf("GET %s: expected status %d while the root is missing, got %d", p, http.StatusServiceUnavailable, rec.Code)
+		}
+	}
+
+	if err := os.Rename(root+"-moved", root); err != nil {
+		t.Fatalf("unable to restore root: %s", err)
+	}
+
+	rec := doRequest(h, http.MethodGet, "/file.txt", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("GET /file.txt: expected status %d after restoring the root, got %d", http.StatusOK, rec.Code)
+	}
+
+	if body := rec.Body.String(); body != "hello" {
+		t.Errorf("GET /file.txt: expected body %q, got %q", "hello", body)
+	}
+}
+
+func Test_headRangeRequests(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "0123456789abcdefghijklmnopqrstuvwxyz")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	tests := []struct {
+		name        string
+		rangeHeader string
+		wantStatus  int
+		wantLength  string
+		wantRange   string
+		wantBody    string
+	}{
+		{
+			name:        "first bytes",
+			rangeHeader: "bytes=0-9",
+			wantStatus:  http.StatusPartialContent,
+			wantLength:  "10",
+			wantRange:   "bytes 0-9/36",
+			wantBody:    "0123456789",
+		},
+		{
+			name:        "suffix range",
+			rangeHeader: "bytes=-5",
+			wantStatus:  http.StatusPartialContent,
+			wantLength:  "5",
+			wantRange:   "bytes 31-35/36",
+			wantBody:    "vwxyz",
+		},
+		{
+			name:        "open ended range",
+			rangeHeader: "bytes=30-",
+			wantStatus:  http.StatusPartialContent,
+			wantLength:  "6",
+			wantRange:   "bytes 30-35/36",
+			wantBody:    "uvwxyz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			headers := map[string]string{"Range": tt.rangeHeader}
+			head := doRequest(h, http.MethodHead, "/file.txt", headers)
+			get := doRequest(h, http.MethodGet, "/file.txt", headers)
+
+			for method, rec := range map[string]*httptest.ResponseRecorder{"HEAD": head, "GET": get} {
+				if rec.Code != tt.wantStatus {
+					t.Errorf("%s: expected status %d, got %d", method, tt.wantStatus, rec.Code)
+				}
+
+				if got := rec.Header().Get("Accept-Ranges"); got != "bytes" {
+					t.Errorf("%s: expected Accept-Ranges %q, got %q", method, "bytes", got)
+				}
+
+				if got := rec.Header().Get("Content-Length"); got != tt.wantLength {
+					t.Errorf("%s: expected Content-Length %q, got %q", method, tt.wantLength, got)
+				}
+
+				if got := rec.Header().Get("Content-Range"); got != tt.wantRange {
+					t.Errorf("%s: expected Content-Range %q, got %q", method, tt.wantRange, got)
+				}
+			}
+
+			if head.Body.Len() != 0 {
+				t.Errorf("HEAD: expected no body, got %q", head.Body.String())
+			}
+
+			if got := get.Body.String(); got != tt.wantBody {
+				t.Errorf("GET: expected body %q, got %q", tt.wantBody, got)
+			}
+		})
+	}
+}
+
+func Test_tryExtensions(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "about.html", "about page")
+	writeTestFile(t, root, "contact.htm", "contact page")
+	writeTestFile(t, root, "about/team.txt", "team")
+	writeTestFile(t, root, "docs.html", "docs page")
+
+	tests := []struct {
+		name       string
+		extensions []string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "extensionless URL served from html file",
+			extensions: []string{".html", ".htm"},
+			path:       "/docs",
+			wantStatus: http.StatusOK,
+			wantBody:   "docs page",
+		},
+		{
+			name:       "second extension is tried",
+			extensions: []string{".html", ".htm"},
+			path:       "/contact",
+			wantStatus: http.StatusOK,
+			wantBody:   "contact page",
+		},
+		{
+			name:       "directories take precedence",
+			extensions: []string{".html", ".htm"},
+			path:       "/about",
+			wantStatus: http.StatusMovedPermanently,
+		},
+		{
+			name:       "missing asset with extension is not masked",
+			extensions: []string{".html", ".htm"},
+			path:       "/docs.css",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "disabled when no extensions are configured",
+			extensions: nil,
+			path:       "/docs",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{Path: root, TryExtensions: tt.extensions})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_externalPrefix(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/guide.txt", "guide")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		headers      map[string]string
+		wantLinks    []string
+		wantLocation string
+	}{
+		{
+			name:      "links use the internal prefix by default",
+			server:    &Server{PathPrefix: "/internal/"},
+			path:      "/internal/docs/",
+			wantLinks: []string{`href="/internal/docs/guide.txt"`, `href="/internal/"`, `href="/internal/_/`},
+		},
+		{
+			name:      "links use the external prefix",
+			server:    &Server{PathPrefix: "/internal/", ExternalPrefix: "/public/"},
+			path:      "/internal/docs/",
+			wantLinks: []string{`href="/public/docs/guide.txt"`, `href="/public/"`, `href="/public/_/`},
+		},
+		{
+			name:      "external prefix with root internal prefix",
+			server:    &Server{ExternalPrefix: "/public/"},
+			path:      "/docs/",
+			wantLinks: []string{`href="/public/docs/guide.txt"`, `href="/public/"`},
+		},
+		{
+			name:      "forwarded prefix ignored without trusting the proxy",
+			server:    &Server{PathPrefix: "/internal/"},
+			path:      "/internal/docs/",
+			headers:   map[string]string{"X-Forwarded-Prefix": "/proxied"},
+			wantLinks: []string{`href="/internal/docs/guide.txt"`},
+		},
+		{
+			name:      "forwarded prefix honored when trusting the proxy",
+			server:    &Server{PathPrefix: "/internal/", ExternalPrefix: "/public/", TrustProxy: true},
+			path:      "/internal/docs/",
+			headers:   map[string]string{"X-Forwarded-Prefix": "/proxied"},
+			wantLinks: []string{`href="/proxied/docs/guide.txt"`, `href="/proxied/"`},
+		},
+		{
+			name:         "directory redirect uses the external prefix",
+			server:       &Server{PathPrefix: "/internal/", ExternalPrefix: "/public/"},
+			path:         "/internal/docs",
+			wantLocation: "/public/docs/",
+		},
+		{
+			name:         "forwarded prefix is cleaned before use",
+			server:       &Server{TrustProxy: true},
+			path:         "/docs",
+			headers:      map[string]string{"X-Forwarded-Prefix": "//evil.example.com/../x"},
+			wantLocation: "/x/docs/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, tt.headers)
+
+			if tt.wantLocation != "" {
+				if got := rec.Header().Get("Location"); got != tt.wantLocation {
+					t.Fatalf("expected redirect to %q, got %q (status %d)", tt.wantLocation, got, rec.Code)
+				}
+				return
+			}
+
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			for _, link := range tt.wantLinks {
+				if !strings.Contains(rec.Body.String(), link) {
+					t.Errorf("expected body to contain %s", link)
+				}
+			}
+		})
+	}
+}
+
+func Test_listingCache(t *testing.T) {
+	root := t.TempDir()
+
+	tests := []struct {
+		name      string
+		ttl       time.Duration
+		change    func(t *testing.T, s *Server)
+		wantTitle string
+	}{
+		{
+			name:      "cached listing is served while valid",
+			ttl:       time.Minute,
+			change:    func(t *testing.T, s *Server) { s.PageTitle = "Second" },
+			wantTitle: "First",
+		},
+		{
+			name:      "caching disabled",
+			ttl:       0,
+			change:    func(t *testing.T, s *Server) { s.PageTitle = "Second" },
+			wantTitle: "Second",
+		},
+		{
+			name:      "expired listing is rendered again",
+			ttl:       time.Nanosecond,
+			change:    func(t *testing.T, s *Server) { s.PageTitle = "Second"; time.Sleep(time.Millisecond) },
+			wantTitle: "Second",
+		},
+		{
+			name: "changed file set invalidates the cache",
+			ttl:  time.Minute,
+			change: func(t *testing.T, s *Server) {
+				s.PageTitle = "Second"
+				writeTestFile(t, s.Path, "second.txt", "second")
+			},
+			wantTitle: "Second",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(root, strings.ReplaceAll(tt.name, " ", "-"))
+			writeTestFile(t, dir, "file.txt", "contents")
+
+			s := &Server{Path: dir, PageTitle: "First", ListingCacheTTL: tt.ttl}
+			h := newTestHandler(t, s)
+
+			if rec := doRequest(h, http.MethodGet, "/", nil); rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			tt.change(t, s)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if want := "<title>" + tt.wantTitle; !strings.Contains(rec.Body.String(), want) {
+				t.Errorf("expected listing to contain %q", want)
+			}
+		})
+	}
+}
+
+func Test_errorTemplate(t *testing.T) {
+	templates := t.TempDir()
+	writeTestFile(t, templates, "error.html", `<h1>{{ .StatusCode }} {{ .StatusText }}</h1><p>{{ .Message }}</p>`)
+	writeTestFile(t, templates, "broken.html", `<h1>{{ index .Message 1000 }}</h1>`)
+
+	tests := []struct {
+		name            string
+		template        string
+		accept          string
+		wantContentType string
+		wantBody        string
+	}{
+		{
+			name:            "plain text without a template",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "404 not found",
+		},
+		{
+			name:            "custom template",
+			template:        filepath.Join(templates, "error.html"),
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<h1>404 Not Found</h1><p>404 not found</p>",
+		},
+		{
+			name:            "falls back to plain text if the template fails",
+			template:        filepath.Join(templates, "broken.html"),
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "404 not found",
+		},
+		{
+			name:            "JSON for clients preferring it",
+			template:        filepath.Join(templates, "error.html"),
+			accept:          "application/json",
+			wantContentType: "application/json",
+			wantBody:        `{"error":"404 not found","status":404}`,
+		},
+		{
+			name:            "HTML for clients preferring it over JSON",
+			template:        filepath.Join(templates, "error.html"),
+			accept:          "text/html, application/json;q=0.9",
+			wantContentType: "text/html; charset=utf-8",
+			wantBody:        "<h1>404 Not Found</h1><p>404 not found</p>",
+		},
+		{
+			name:            "wildcards don't count as JSON",
+			accept:          "*/*",
+			wantContentType: "text/plain; charset=utf-8",
+			wantBody:        "404 not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{ErrorTemplate: tt.template})
+
+			var headers map[string]string
+			if tt.accept != "" {
+				headers = map[string]string{"Accept": tt.accept}
+			}
+
+			rec := doRequest(h, http.MethodGet, "/missing.txt", headers)
+			if rec.Code != http.StatusNotFound {
+				t.Fatalf("expected status %d, got %d", http.StatusNotFound, rec.Code)
+			}
+
+			if rec.Header().Get("Content-Type") != tt.wantContentType {
+				t.Errorf("expected content type %q, got %q", tt.wantContentType, rec.Header().Get("Content-Type"))
+			}
+
+			if rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_errorContentType(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		message    string
+	}{
+		{
+			name:       "not found",
+			statusCode: http.StatusNotFound,
+			message:    "404 not found",
+		},
+		{
+			name:       "internal server error",
+			statusCode: http.StatusInternalServerError,
+			message:    "unable to open file -- see application logs for more information",
+		},
+		{
+			name:       "message with a percent sign and no arguments",
+			statusCode: http.StatusBadRequest,
+			message:    "400 bad request: 100% invalid, like %q",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{LogOutput: io.Discard}
+
+			// Headers set for the file that was going to be served
+			// must not leak into the error response
+			rec := httptest.NewRecorder()
+			rec.Header().Set("Content-Type", "application/pdf")
+			rec.Header().Set("Content-Length", "123456")
+
+			s.httpError(tt.statusCode, rec, httptest.NewRequest(http.MethodGet, "/file.pdf", nil), tt.message)
+
+			if rec.Code != tt.statusCode {
+				t.Fatalf("expected status %d, got %d", tt.statusCode, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+				t.Errorf("expected content type %q, got %q", "text/plain; charset=utf-8", got)
+			}
+
+			if got := rec.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+				t.Errorf("expected X-Content-Type-Options %q, got %q", "nosniff", got)
+			}
+
+			if got := rec.Header().Get("Content-Length"); got != "" {
+				t.Errorf("expected no Content-Length header, got %q", got)
+			}
+
+			if rec.Body.String() != tt.message {
+				t.Errorf("expected body %q, got %q", tt.message, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_canceledRequests(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/file.txt", strings.Repeat("contents ", 1000))
+
+	tests := []struct {
+		name    string
+		server  *Server
+		path    string
+		wantLog string
+	}{
+		{
+			name:    "directory listing",
+			server:  &Server{},
+			path:    "/docs/",
+			wantLog: "stopped listing directory",
+		},
+		{
+			name:    "streamed directory listing",
+			server:  &Server{StreamListing: true},
+			path:    "/docs/",
+			wantLog: "stopped streaming directory",
+		},
+		{
+			name:    "file",
+			server:  &Server{},
+			path:    "/docs/file.txt",
+			wantLog: "stopped serving file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			tt.server.Path = root
+			tt.server.LogOutput = &logs
+			h := newTestHandler(t, tt.server)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil).WithContext(ctx)
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if strings.Contains(rec.Body.String(), "file.txt") || strings.Contains(rec.Body.String(), "contents") {
+				t.Errorf("expected no content to be served for a canceled request")
+			}
+
+			if !strings.Contains(logs.String(), canceledPrefix) || !strings.Contains(logs.String(), tt.wantLog) {
+				t.Errorf("expected logs to report the canceled request with %q, got:\n%s", tt.wantLog, logs.String())
+			}
+		})
+	}
+}
+
+func Test_listColumns(t *testing.T) {
+	tests := []struct {
+		name        string
+		columns     []string
+		showMode    bool
+		stream      bool
+		wantColumns []string
+	}{
+		{
+			name:        "default columns",
+			wantColumns: []string{"name", "size", "date"},
+		},
+		{
+			name:        "name only",
+			columns:     []string{"name"},
+			wantColumns: []string{"name"},
+		},
+		{
+			name:        "columns in the configured order",
+			columns:     []string{"mode", "name", "modtime"},
+			wantColumns: []string{"mode", "name", "date"},
+		},
+		{
+			name:        "file modes added to the default columns",
+			showMode:    true,
+			wantColumns: []string{"name", "size", "date", "mode"},
+		},
+		{
+			name:        "file modes already in the columns",
+			columns:     []string{"mode", "name"},
+			showMode:    true,
+			wantColumns: []string{"mode", "name"},
+		},
+		{
+			name:        "streamed listing",
+			columns:     []string{"name", "mode"},
+			stream:      true,
+			wantColumns: []string{"name", "mode"},
+		},
+	}
+
+	reColumn := regexp.MustCompile(`<span class="(name|size|date|mode|owner)">`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", "contents")
+
+			// The file owner is shown next to the mode where supported
+			if tt.showMode && fileOwnerSupported {
+				tt.wantColumns = append(tt.wantColumns, "owner")
+			}
+
+			s := &Server{Path: root, ListColumns: tt.columns, ShowMode: tt.showMode, StreamListing: tt.stream}
+			h := newTestHandler(t, s)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			// Grab the columns of the row for the file
+			body := rec.Body.String()
+			start := strings.Index(body, `data-name="file.txt"`)
+			if start < 0 {
+				t.Fatalf("expected listing to contain the file")
+			}
+
+			row, _, _ := strings.Cut(body[start:], "</a>")
+
+			var got []string
+			for _, m := range reColumn.FindAllStringSubmatch(row, -1) {
+				got = append(got, m[1])
+			}
+
+			if !reflect.DeepEqual(got, tt.wantColumns) {
+				t.Errorf("expected columns %v, got %v", tt.wantColumns, got)
+			}
+		})
+	}
+}
+
+func Test_hideDotfiles(t *testing.T) {
+	tests := []struct {
+		name   string
+		stream bool
+	}{
+		{name: "rendered listing"},
+		{name: "streamed listing", stream: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "visible.txt", "visible")
+			writeTestFile(t, root, ".hidden.txt", "hidden")
+			writeTestFile(t, root, ".well-known/acme-challenge/token", "challenge")
+
+			s := &Server{Path: root, HideDotfiles: true, StreamListing: tt.stream}
+			h := newTestHandler(t, s)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, `data-name="visible.txt"`) {
+				t.Errorf("expected listing to contain visible.txt")
+			}
+
+			for _, name := range []string{".hidden.txt", ".well-known"} {
+				if strings.Contains(body, `data-name="`+name+`"`) {
+					t.Errorf("expected listing to hide %s", name)
+				}
+			}
+
+			// Hidden files are still served when requested directly
+			for path, want := range map[string]string{
+				"/.hidden.txt":                      "hidden",
+				"/.well-known/acme-challenge/token": "challenge",
+			} {
+				rec := doRequest(h, http.MethodGet, path, nil)
+				if rec.Code != http.StatusOK {
+					t.Errorf("expected status %d for %s, got %d", http.StatusOK, path, rec.Code)
+					continue
+				}
+
+				if rec.Body.String() != want {
+					t.Errorf("expected body %q for %s, got %q", want, path, rec.Body.String())
+				}
+			}
+		})
+	}
+}
+
+func Test_maxPathDepth(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxDepth   int
+		prefix     string
+		path       string
+		wantStatus int
+	}{
+		{
+			name:       "unlimited by default",
+			path:       "/a/b/c/file.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "path within the limit",
+			maxDepth:   4,
+			path:       "/a/b/c/file.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "path over the limit",
+			maxDepth:   3,
+			path:       "/a/b/c/file.txt",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "path prefix isn't counted",
+			maxDepth:   4,
+			prefix:     "/files/",
+			path:       "/files/a/b/c/file.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "empty segments aren't counted",
+			maxDepth:   2,
+			path:       "/a/b/",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "a/b/c/file.txt", "contents")
+
+			s := &Server{Path: root, PathPrefix: tt.prefix, MaxPathDepth: tt.maxDepth}
+			h := newTestHandler(t, s)
+
+			if rec := doRequest(h, http.MethodGet, tt.path, nil); rec.Code != tt.wantStatus {
+				t.Errorf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func Test_fileURL(t *testing.T) {
+	names := []string{
+		"hash#tag.txt",
+		"with spaces.txt",
+		"percent%done.txt",
+		"question?.txt",
+		"ünïcødé 文件.txt",
+	}
+
+	root := t.TempDir()
+	for _, name := range names {
+		writeTestFile(t, root, "dir #1/"+name, name)
+	}
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	// The directory itself has a special character in its name
+	rec := doRequest(h, http.MethodGet, "/", nil)
+	dirURL := findFileURL(t, rec.Body.String(), "dir #1")
+	if dirURL != "/dir%20%231/" {
+		t.Fatalf("expected directory link %q, got %q", "/dir%20%231/", dirURL)
+	}
+
+	rec = doRequest(h, http.MethodGet, dirURL, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d for the directory listing, got %d", http.StatusOK, rec.Code)
+	}
+
+	listing := rec.Body.String()
+	for _, name := range names {
+		t.Run(name, func(t *testing.T) {
+			link := findFileURL(t, listing, name)
+
+			// Following the link must lead back to the same file
+			rec := doRequest(h, http.MethodGet, link, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d for link %q, got %d", http.StatusOK, link, rec.Code)
+			}
+
+			if rec.Body.String() != name {
+				t.Errorf("expected link %q to serve %q, got %q", link, name, rec.Body.String())
+			}
+		})
+	}
+}
+
+// findFileURL returns the link to the given file in a directory listing
+func findFileURL(t *testing.T, body, name string) string {
+	t.Helper()
+
+	re := regexp.MustCompile(`<a href="([^"]*)" data-name="` + regexp.QuoteMeta(html.EscapeString(name)) + `"`)
+	m := re.FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("expected listing to contain a link to %q", name)
+	}
+
+	return html.UnescapeString(m[1])
+}
+
+func Test_specialCharacterPaths(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "a%20b.txt", "percent")
+	writeTestFile(t, root, "a b.txt", "space")
+	writeTestFile(t, root, "c+d.txt", "plus")
+	writeTestFile(t, root, "c d.txt", "not plus")
+	writeTestFile(t, root, "e%20f/index.html", "directory")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "literal percent sign",
+			path:       "/a%2520b.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "percent",
+		},
+		{
+			name:       "encoded space",
+			path:       "/a%20b.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "space",
+		},
+		{
+			name:       "literal plus sign",
+			path:       "/c+d.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "plus",
+		},
+		{
+			name:       "encoded plus sign",
+			path:       "/c%2Bd.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "plus",
+		},
+		{
+			name:         "directory redirect keeps the encoding",
+			path:         "/e%2520f",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/e%2520f/",
+		},
+		{
+			name:         "index redirect keeps the encoding",
+			path:         "/e%2520f/index.html",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/e%2520f/",
+		},
+		{
+			name:       "directory with a percent sign",
+			path:       "/e%2520f/",
+			wantStatus: http.StatusOK,
+			wantBody:   "directory",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
+
+func Test_pathTraversal(t *testing.T) {
+	parent := t.TempDir()
+	root := filepath.Join(parent, "root")
+	writeTestFile(t, parent, "outside.txt", "secret")
+	writeTestFile(t, root, "inside.txt", "public")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "parent directory segments",
+			path:       "/../outside.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "nested parent directory segments",
+			path:       "/a/../../outside.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "encoded parent directory segments",
+			path:       "/%2e%2e/outside.txt",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "dot segments within the served directory",
+			path:       "/a/../inside.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "public",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_listingCacheControl(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *Server
+		path   string
+		want   string
+	}{
+		{
+			name:   "listing without a value",
+			server: &Server{},
+			path:   "/",
+			want:   "",
+		},
+		{
+			name:   "listing",
+			server: &Server{ListingCacheControl: "max-age=10"},
+			path:   "/",
+			want:   "max-age=10",
+		},
+		{
+			name:   "cached listing",
+			server: &Server{ListingCacheControl: "max-age=10", ListingCacheTTL: time.Minute},
+			path:   "/",
+			want:   "max-age=10",
+		},
+		{
+			name:   "streamed listing",
+			server: &Server{ListingCacheControl: "max-age=10", StreamListing: true},
+			path:   "/",
+			want:   "max-age=10",
+		},
+		{
+			name:   "files aren't affected",
+			server: &Server{ListingCacheControl: "max-age=10"},
+			path:   "/file.txt",
+			want:   "",
+		},
+		{
+			name:   "index files aren't affected",
+			server: &Server{ListingCacheControl: "max-age=10"},
+			path:   "/site/",
+			want:   "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", "contents")
+			writeTestFile(t, root, "site/index.html", "<h1>Hello</h1>")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			// Request twice, so cached listings are served from memory
+			for i := 0; i < 2; i++ {
+				rec := doRequest(h, http.MethodGet, tt.path, nil)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+				}
+
+				if got := rec.Header().Get("Cache-Control"); got != tt.want {
+					t.Errorf("expected Cache-Control %q, got %q", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_bareListing(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *Server
+		path     string
+		wantBare bool
+	}{
+		{
+			name:     "full listing by default",
+			server:   &Server{},
+			path:     "/",
+			wantBare: false,
+		},
+		{
+			name:     "bare listing requested",
+			server:   &Server{},
+			path:     "/?bare=1",
+			wantBare: true,
+		},
+		{
+			name:     "bare listing explicitly not requested",
+			server:   &Server{},
+			path:     "/?bare=0",
+			wantBare: false,
+		},
+		{
+			name:     "bare listing configured",
+			server:   &Server{BareListing: true},
+			path:     "/",
+			wantBare: true,
+		},
+		{
+			name:     "cached bare listing",
+			server:   &Server{ListingCacheTTL: time.Minute},
+			path:     "/?bare",
+			wantBare: true,
+		},
+		{
+			name:     "streamed bare listing",
+			server:   &Server{StreamListing: true},
+			path:     "/?bare",
+			wantBare: true,
+		},
+	}
+
+	reLink := regexp.MustCompile(`<li><a href="([^"]+)">`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "b.txt", "b")
+			writeTestFile(t, root, "A.txt", "a")
+			writeTestFile(t, root, "folder/file.txt", "file")
+			writeTestFile(t, root, ".http-server.yaml", "port: 80")
+
+			tt.server.Path = root
+			tt.server.ConfigFilePrefix = ".http-server"
+			h := newTestHandler(t, tt.server)
+
+			// Request a full listing first, so it's cached if enabled
+			doRequest(h, http.MethodGet, "/", nil)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			isBare := !strings.Contains(body, "<link") && !strings.Contains(body, "<script")
+			if isBare != tt.wantBare {
+				t.Fatalf("expected bare listing: %v, got: %v", tt.wantBare, isBare)
+			}
+
+			if !tt.wantBare {
+				return
+			}
+
+			var links []string
+			for _, m := range reLink.FindAllStringSubmatch(body, -1) {
+				links = append(links, m[1])
+			}
+
+			// Streamed listings are unsorted, so only the files are checked
+			want := []string{"/folder/", "/A.txt", "/b.txt"}
+			if tt.server.StreamListing {
+				sort.Strings(links)
+				want = []string{"/A.txt", "/b.txt", "/folder/"}
+			}
+
+			if !reflect.DeepEqual(links, want) {
+				t.Errorf("expected links %v, got %v", want, links)
+			}
+		})
+	}
+}
+
+func Test_fileTrailingSlash(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefix       string
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "file with a trailing slash",
+			path:         "/file.txt/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/file.txt",
+		},
+		{
+			name:         "nested file with a trailing slash",
+			path:         "/docs/my%20notes.txt/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/my%20notes.txt",
+		},
+		{
+			name:         "file with a trailing slash under a path prefix",
+			prefix:       "/files/",
+			path:         "/files/file.txt/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/files/file.txt",
+		},
+		{
+			name:       "file without a trailing slash",
+			path:       "/file.txt",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", "contents")
+			writeTestFile(t, root, "docs/my notes.txt", "notes")
+
+			h := newTestHandler(t, &Server{Path: root, PathPrefix: tt.prefix})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
+
+func Test_serverHeader(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "contents")
+
+	tests := []struct {
+		name   string
+		header string
+		method string
+		path   string
+		want   string
+	}{
+		{name: "file", header: "http-server", path: "/file.txt", want: "http-server"},
+		{name: "listing", header: "http-server", path: "/", want: "http-server"},
+		{name: "error", header: "http-server", path: "/missing.txt", want: "http-server"},
+		{name: "health check", header: "http-server", path: "/_/health", want: "http-server"},
+		{name: "disallowed method", header: "http-server", method: http.MethodDelete, path: "/file.txt", want: "http-server"},
+		{name: "custom value", header: "files.example.com", path: "/file.txt", want: "files.example.com"},
+		{name: "suppressed on files", path: "/file.txt"},
+		{name: "suppressed on errors", path: "/missing.txt"},
+		{name: "suppressed on the health check", path: "/_/health"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{Path: root, ServerHeader: tt.header})
+
+			method := http.MethodGet
+			if tt.method != "" {
+				method = tt.method
+			}
+
+			rec := doRequest(h, method, tt.path, nil)
+
+			got, found := rec.Header()["Server"]
+			if tt.want == "" {
+				if found {
+					t.Fatalf("expected no Server header, got %q", got)
+				}
+				return
+			}
+
+			if v := rec.Header().Get("Server"); v != tt.want {
+				t.Fatalf("expected Server header %q, got %q", tt.want, v)
+			}
+		})
+	}
+}
+
+func Test_noIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *Server
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{
+			name:     "index served by default",
+			server:   &Server{},
+			path:     "/site/",
+			wantCode: http.StatusOK,
+			wantBody: "<h1>Hello</h1>",
+		},
+		{
+			name:     "listing requested",
+			server:   &Server{},
+			path:     "/site/?noindex=1",
+			wantCode: http.StatusOK,
+			wantBody: "style.css",
+		},
+		{
+			name:     "listing requested without a value",
+			server:   &Server{},
+			path:     "/site/?noindex",
+			wantCode: http.StatusOK,
+			wantBody: "style.css",
+		},
+		{
+			name:     "listing explicitly not requested",
+			server:   &Server{},
+			path:     "/site/?noindex=false",
+			wantCode: http.StatusOK,
+			wantBody: "<h1>Hello</h1>",
+		},
+		{
+			name:     "index still served when listing is disabled",
+			server:   &Server{DisableDirectoryList: true},
+			path:     "/site/?noindex=1",
+			wantCode: http.StatusOK,
+			wantBody: "<h1>Hello</h1>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "site/index.html", "<h1>Hello</h1>")
+			writeTestFile(t, root, "site/style.css", "body {}")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantCode {
+				t.Fatalf("expected status %d, got %d", tt.wantCode, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_rootDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *Server
+		path     string
+		wantBody string
+	}{
+		{
+			name:     "root document served for the root",
+			server:   &Server{RootDocument: "dashboard.html"},
+			path:     "/",
+			wantBody: "dashboard",
+		},
+		{
+			name:     "root document served in place of the index",
+			server:   &Server{RootDocument: "pages/dashboard.html"},
+			path:     "/",
+			wantBody: "nested dashboard",
+		},
+		{
+			name:     "root document under a path prefix",
+			server:   &Server{RootDocument: "dashboard.html", PathPrefix: "/files/"},
+			path:     "/files/",
+			wantBody: "dashboard",
+		},
+		{
+			name:     "subdirectories are listed as usual",
+			server:   &Server{RootDocument: "dashboard.html"},
+			path:     "/pages/",
+			wantBody: "dashboard.html",
+		},
+		{
+			name:     "index served without a root document",
+			server:   &Server{},
+			path:     "/",
+			wantBody: "home",
+		},
+		{
+			name:     "missing root document falls back to the index",
+			server:   &Server{RootDocument: "missing.html"},
+			path:     "/",
+			wantBody: "home",
+		},
+		{
+			name:     "root document can't leave the served directory",
+			server:   &Server{RootDocument: "../outside.html"},
+			path:     "/",
+			wantBody: "home",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parent := t.TempDir()
+			writeTestFile(t, parent, "outside.html", "outside")
+
+			root := filepath.Join(parent, "root")
+			writeTestFile(t, root, "index.html", "home")
+			writeTestFile(t, root, "dashboard.html", "dashboard")
+			writeTestFile(t, root, "pages/dashboard.html", "nested dashboard")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
+
+func Test_redirectDuplicateSlashes(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "docs/guide.txt", "guide")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "file",
+			server:       &Server{RedirectDuplicateSlashes: true},
+			path:         "/docs//guide.txt",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/guide.txt",
+		},
+		{
+			name:         "directory",
+			server:       &Server{RedirectDuplicateSlashes: true},
+			path:         "//docs///",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/",
+		},
+		{
+			name:         "under a path prefix",
+			server:       &Server{RedirectDuplicateSlashes: true, PathPrefix: "/files/"},
+			path:         "/files//docs/guide.txt?dl",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/files/docs/guide.txt?dl",
+		},
+		{
+			name:       "server paths are skipped",
+			server:     &Server{RedirectDuplicateSlashes: true},
+			path:       "/_//health",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "disabled",
+			server:     &Server{},
+			path:       "/docs//guide.txt",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
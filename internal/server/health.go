@@ -0,0 +1,123 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// defaultHealthBody and defaultHealthContentType are what the health check
+// responds with, unless configured otherwise
+const (
+	defaultHealthBody        = "OK"
+	defaultHealthContentType = "text/plain; charset=utf-8"
+)
+
+// healthChanges keeps track of when the fields of the extended health
+// check last changed, which is sent as the modification date of the
+// response so clients can validate it with "If-Modified-Since"
+type healthChanges struct {
+	mu         sync.Mutex
+	hash       [sha1.Size]byte
+	modifiedAt time.Time
+}
+
+// modified returns the time the fields with the given hash were first
+// seen. Dates in HTTP headers have a precision of a second, so every
+// change moves the date forward by at least a second, otherwise fields
+// changing twice within the same second would look unmodified.
+func (c *healthChanges) modified(hash [sha1.Size]byte) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.modifiedAt.IsZero() || hash != c.hash {
+		now := time.Now().Truncate(time.Second)
+		if !now.After(c.modifiedAt) {
+			now = c.modifiedAt.Add(time.Second)
+		}
+
+		c.hash, c.modifiedAt = hash, now
+	}
+
+	return c.modifiedAt
+}
+
+// healthCheck is a simple health check endpoint that returns 200 OK, with
+// a configurable body that defaults to the plain text "OK". When
+// the extended health check is enabled, it returns a JSON document instead
+// which supports conditional requests so pollers can get cheap 304s.
+func (s *Server) healthCheck(w http.ResponseWriter, r *http.Request) {
+	if !s.ExtendedHealthCheck {
+		body, contentType := s.HealthBody, s.HealthContentType
+		if body == "" {
+			body = defaultHealthBody
+		}
+
+		if contentType == "" {
+			contentType = defaultHealthContentType
+		}
+
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(body))
+		return
+	}
+
+	// Collect the fields that describe the state of the server, and
+	// which are used to compute the ETag of the response
+	fields := map[string]any{
+		"status":     "ok",
+		"version":    s.version,
+		"started_at": s.startedAt.UTC().Format(time.RFC3339),
+	}
+
+	// Report the space available in the filesystem backing the
+	// served directory, so operators can catch a filling disk
+	if usage, err := s.diskUsage.get(s.Path); err == nil {
+		fields["disk"] = usage
+	} else {
+		s.printWarning("unable to get disk usage for health check: %s", err)
+	}
+
+	// Add any custom fields, without overwriting the built-in ones
+	if s.HealthResponse != nil {
+		for key, value := range s.HealthResponse() {
+			if _, found := fields[key]; !found {
+				fields[key] = value
+			}
+		}
+	}
+
+	stable, err := json.Marshal(fields)
+	if err != nil {
+		s.printWarning("unable to generate health check response: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate health check response -- see application logs for more information")
+		return
+	}
+
+	// The uptime changes on every request, so it's added after
+	// computing the ETag, otherwise no request would ever match
+	fields["uptime"] = time.Since(s.startedAt).Round(time.Second).String()
+
+	body, err := json.Marshal(fields)
+	if err != nil {
+		s.printWarning("unable to generate health check response: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate health check response -- see application logs for more information")
+		return
+	}
+
+	hash := sha1.Sum(stable)
+	w.Header().Set("Etag", fmt.Sprintf("%q", hex.EncodeToString(hash[:])))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	// Let the standard library handle HEAD requests, as well as the
+	// "If-None-Match" and "If-Modified-Since" headers, using the last
+	// time the fields changed as the modification date
+	http.ServeContent(w, r, "", s.healthChanges.modified(hash), bytes.NewReader(body))
+}
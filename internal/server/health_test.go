@@ -0,0 +1,120 @@
+package server
+
+import (
+	"crypto/sha1"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func Test_healthCheckBody(t *testing.T) {
+	tests := []struct {
+		name            string
+		server          *Server
+		wantBody        string
+		wantContentType string
+	}{
+		{
+			name:            "default",
+			server:          &Server{},
+			wantBody:        "OK",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+		{
+			name:            "custom body",
+			server:          &Server{HealthBody: `{"status":"ok"}`, HealthContentType: "application/json"},
+			wantBody:        `{"status":"ok"}`,
+			wantContentType: "application/json",
+		},
+		{
+			name:            "custom body with the default content type",
+			server:          &Server{HealthBody: "healthy"},
+			wantBody:        "healthy",
+			wantContentType: "text/plain; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = t.TempDir()
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/_/health", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if got := rec.Body.String(); got != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, got)
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != tt.wantContentType {
+				t.Errorf("expected content type %q, got %q", tt.wantContentType, got)
+			}
+		})
+	}
+}
+
+func Test_extendedHealthCheckConditional(t *testing.T) {
+	h := newTestHandler(t, &Server{Path: t.TempDir(), ExtendedHealthCheck: true})
+
+	rec := doRequest(h, http.MethodGet, "/_/health", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	etag, lastModified := rec.Header().Get("Etag"), rec.Header().Get("Last-Modified")
+	if etag == "" || lastModified == "" {
+		t.Fatalf("expected both Etag and Last-Modified headers, got %q and %q", etag, lastModified)
+	}
+
+	tests := []struct {
+		name       string
+		headers    map[string]string
+		wantStatus int
+	}{
+		{
+			name:       "matching etag",
+			headers:    map[string]string{"If-None-Match": etag},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "different etag",
+			headers:    map[string]string{"If-None-Match": `"other"`},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "not modified since",
+			headers:    map[string]string{"If-Modified-Since": lastModified},
+			wantStatus: http.StatusNotModified,
+		},
+		{
+			name:       "modified since",
+			headers:    map[string]string{"If-Modified-Since": time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, "/_/health", tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func Test_healthChangesModified(t *testing.T) {
+	var c healthChanges
+
+	first := c.modified([sha1.Size]byte{1})
+	if got := c.modified([sha1.Size]byte{1}); !got.Equal(first) {
+		t.Errorf("expected unchanged fields to keep the date %v, got %v", first, got)
+	}
+
+	// Changes within the same second still move the date forward
+	if got := c.modified([sha1.Size]byte{2}); !got.After(first) {
+		t.Errorf("expected changed fields to have a date after %v, got %v", first, got)
+	}
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+
+	// Register the decoder for GIF sources, which can
+	// be converted but aren't offered as a target
+	_ "image/gif"
+
+	// Register the decoder for WebP sources
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// defaultImageQuality is the quality used to encode
+	// lossy formats when none is requested
+	defaultImageQuality = 85
+
+	// maxImagePixels is the largest image, in pixels, that will be
+	// decoded for conversion, so a small file claiming huge dimensions
+	// can't be used to exhaust the server memory
+	maxImagePixels = 50_000_000
+
+	// maxImageCacheBytes is the most memory, in bytes, converted images
+	// can take in the cache, with the ones closest to expiring evicted
+	// to make room for new ones
+	maxImageCacheBytes = 64 << 20
+)
+
+// imageFormats are the formats images can be converted to,
+// along with the content type they're served with
+var imageFormats = map[string]string{
+	"jpeg": "image/jpeg",
+	"jpg":  "image/jpeg",
+	"png":  "image/png",
+	"webp": "image/webp",
+}
+
+// imageConversion is a conversion requested with
+// the "format" and "quality" query string parameters
+type imageConversion struct {
+	format  string
+	quality int
+}
+
+// parseImageConversion reads the requested conversion from the query string
+func parseImageConversion(r *http.Request) (imageConversion, error) {
+	query := r.URL.Query()
+
+	conv := imageConversion{
+		format:  strings.ToLower(query.Get("format")),
+		quality: defaultImageQuality,
+	}
+
+	if _, found := imageFormats[conv.format]; !found {
+		return conv, fmt.Errorf("unknown image format %q, use one of: jpeg, png, webp", conv.format)
+	}
+
+	if q := query.Get("quality"); q != "" {
+		quality, err := strconv.Atoi(q)
+		if err != nil || quality < 1 || quality > 100 {
+			return conv, fmt.Errorf("quality must be a number between 1 and 100, got %q", q)
+		}
+
+		conv.quality = quality
+	}
+
+	// Both names produce the same image, and since PNG and WebP are
+	// encoded losslessly, the quality doesn't change them either, so
+	// neither is cached twice
+	if conv.format == "jpg" {
+		conv.format = "jpeg"
+	}
+
+	if conv.format == "png" || conv.format == "webp" {
+		conv.quality = 0
+	}
+
+	return conv, nil
+}
+
+// serveConvertedImage serves the image at the given path converted to the
+// format requested in the query string. It reports whether the response
+// was handled: files that can't be decoded as images are left for the
+// caller to serve as they are.
+func (s *Server) serveConvertedImage(fp string, w http.ResponseWriter, r *http.Request) bool {
+	conv, err := parseImageConversion(r)
+	if err != nil {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: %s", err)
+		return true
+	}
+
+	fi, err := os.Stat(fp)
+	if err != nil {
+		return false
+	}
+
+	// Converted images are cached until the source changes
+	key := fmt.Sprintf("%s\x00%s\x00%d", fp, conv.format, conv.quality)
+	body, found := s.imageCache.get(key, uint64(fi.Size()), fi.ModTime())
+
+	if !found {
+		release, ok := s.startExpensiveOp(w, r)
+		if !ok {
+			return true
+		}
+
+		body, err = s.convertImage(fp, conv)
+		release()
+
+		if err != nil {
+			s.printWarning("unable to convert image %q, serving the original: %s", fp, err)
+			return false
+		}
+
+		if s.ImageCacheTTL > 0 {
+			s.imageCache.set(key, uint64(fi.Size()), fi.ModTime(), s.ImageCacheTTL, body)
+		}
+	}
+
+	w.Header().Set("Content-Type", imageFormats[conv.format])
+
+	// Let the standard library handle HEAD requests, as
+	// well as the "If-Modified-Since" header
+	http.ServeContent(w, r, "", fi.ModTime(), bytes.NewReader(body))
+	return true
+}
+
+// convertImage decodes the image at the given path and encodes it again
+// in the requested format
+func (s *Server) convertImage(fp string, conv imageConversion) ([]byte, error) {
+	f, err := os.Open(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	// Check the dimensions before decoding the whole image
+	cfg, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Width*cfg.Height > maxImagePixels {
+		return nil, fmt.Errorf("image is too large to convert: %dx%d pixels", cfg.Width, cfg.Height)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	switch imageFormats[conv.format] {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: conv.quality})
+	case "image/png":
+		err = png.Encode(&buf, img)
+	case "image/webp":
+		err = nativewebp.Encode(&buf, img, nil)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/HugoSmits86/nativewebp"
+	"golang.org/x/image/webp"
+)
+
+func Test_imageConversion(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 4, 4))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 4; y++ {
+			img.Set(x, y, color.RGBA{R: 255, A: 255})
+		}
+	}
+
+	var source bytes.Buffer
+	if err := png.Encode(&source, img); err != nil {
+		t.Fatalf("unable to encode test image: %s", err)
+	}
+
+	var webpSource bytes.Buffer
+	if err := nativewebp.Encode(&webpSource, img, nil); err != nil {
+		t.Fatalf("unable to encode test image: %s", err)
+	}
+
+	root := t.TempDir()
+	writeTestFile(t, root, "image.png", source.String())
+	writeTestFile(t, root, "image.webp", webpSource.String())
+	writeTestFile(t, root, "broken.png", "not really an image")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		wantStatus int
+		wantType   string
+		decode     func(*bytes.Reader) (image.Image, error)
+		wantBody   string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			path:       "/image.png?format=jpeg",
+			wantStatus: http.StatusOK,
+			wantType:   "image/png",
+			wantBody:   source.String(),
+		},
+		{
+			name:       "convert to jpeg",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/image.png?format=jpeg&quality=50",
+			wantStatus: http.StatusOK,
+			wantType:   "image/jpeg",
+			decode:     func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) },
+		},
+		{
+			name:       "convert with caching",
+			server:     &Server{Path: root, ImageConversion: true, ImageCacheTTL: time.Minute},
+			path:       "/image.png?format=jpg",
+			wantStatus: http.StatusOK,
+			wantType:   "image/jpeg",
+			decode:     func(r *bytes.Reader) (image.Image, error) { return jpeg.Decode(r) },
+		},
+		{
+			name:       "convert to webp",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/image.png?format=webp",
+			wantStatus: http.StatusOK,
+			wantType:   "image/webp",
+			decode:     func(r *bytes.Reader) (image.Image, error) { return webp.Decode(r) },
+		},
+		{
+			name:       "convert from webp",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/image.webp?format=png",
+			wantStatus: http.StatusOK,
+			wantType:   "image/png",
+			decode:     func(r *bytes.Reader) (image.Image, error) { return png.Decode(r) },
+		},
+		{
+			name:       "unknown format",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/image.png?format=bmp",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid quality",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/image.png?format=jpeg&quality=101",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "undecodable source is served as is",
+			server:     &Server{Path: root, ImageConversion: true},
+			path:       "/broken.png?format=jpeg",
+			wantStatus: http.StatusOK,
+			wantBody:   "not really an image",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			// Request twice, so cached conversions are served too
+			for i := 0; i < 2; i++ {
+				rec := doRequest(h, http.MethodGet, tt.path, nil)
+				if rec.Code != tt.wantStatus {
+					t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+				}
+
+				if tt.wantType != "" && rec.Header().Get("Content-Type") != tt.wantType {
+					t.Errorf("expected content type %q, got %q", tt.wantType, rec.Header().Get("Content-Type"))
+				}
+
+				if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+					t.Errorf("expected the original file to be served")
+				}
+
+				if tt.decode != nil {
+					converted, err := tt.decode(bytes.NewReader(rec.Body.Bytes()))
+					if err != nil {
+						t.Fatalf("unable to decode converted image: %s", err)
+					}
+
+					if got := converted.Bounds(); got != img.Bounds() {
+						t.Errorf("expected converted image bounds %v, got %v", img.Bounds(), got)
+					}
+				}
+			}
+		})
+	}
+}
+
+func Test_imageCacheKeys(t *testing.T) {
+	var source bytes.Buffer
+	if err := png.Encode(&source, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("unable to encode test image: %s", err)
+	}
+
+	root := t.TempDir()
+	writeTestFile(t, root, "image.png", source.String())
+
+	s := &Server{Path: root, ImageConversion: true, ImageCacheTTL: time.Minute}
+	h := newTestHandler(t, s)
+
+	// Names of the same format, and qualities of a lossless
+	// one, produce the same image, so they share an entry
+	for _, path := range []string{"/image.png?format=jpg", "/image.png?format=jpeg", "/image.png?format=png&quality=10", "/image.png?format=png&quality=90"} {
+		if rec := doRequest(h, http.MethodGet, path, nil); rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	}
+
+	if got := s.imageCache.len(); got != 2 {
+		t.Fatalf("expected 2 cached images, got %d", got)
+	}
+}
+
+func Test_listingCacheMaxBytes(t *testing.T) {
+	c := listingCache{maxBytes: 10}
+	now := time.Now()
+
+	c.set("first", 0, now, time.Minute, []byte("12345"))
+	c.set("second", 0, now, 2*time.Minute, []byte("12345"))
+	c.set("third", 0, now, 3*time.Minute, []byte("12345"))
+	c.set("too-large", 0, now, time.Minute, []byte("12345678901"))
+
+	if _, found := c.get("first", 0, now); found {
+		t.Errorf("expected the entry closest to expiring to be evicted")
+	}
+
+	for _, key := range []string{"second", "third"} {
+		if _, found := c.get(key, 0, now); !found {
+			t.Errorf("expected entry %q to be cached", key)
+		}
+	}
+
+	if _, found := c.get("too-large", 0, now); found {
+		t.Errorf("expected entries larger than the limit not to be cached")
+	}
+
+	if c.size != 10 {
+		t.Errorf("expected 10 bytes cached, got %d", c.size)
+	}
+}
+
+func Test_listingCacheMaxEntries(t *testing.T) {
+	c := listingCache{maxEntries: 2}
+	now := time.Now()
+
+	for _, key := range []string{"first", "second", "third"} {
+		c.set(key, 0, now, time.Minute, []byte(key))
+	}
+
+	if _, found := c.get("first", 0, now); found {
+		t.Errorf("expected the oldest entry to be evicted")
+	}
+
+	if got := c.len(); got != 2 {
+		t.Errorf("expected 2 cached entries, got %d", got)
+	}
+}
+
+func Test_listingCacheExpiredEntries(t *testing.T) {
+	var c listingCache
+	now := time.Now()
+
+	c.set("expired", 0, now, -time.Minute, []byte("12345"))
+	c.set("fresh", 0, now, time.Minute, []byte("12345"))
+
+	// Expired entries are removed as new ones are stored,
+	// even if they're never requested again
+	if got := c.len(); got != 1 {
+		t.Errorf("expected 1 cached entry, got %d", got)
+	}
+
+	if c.size != 5 {
+		t.Errorf("expected 5 bytes cached, got %d", c.size)
+	}
+}
@@ -0,0 +1,120 @@
+package server
+
+import (
+	"container/heap"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// indexCursor is a position in a directory listing, after which the next
+// batch of entries starts. It's written as the name of the last entry of
+// the previous batch, with a trailing slash for directories, since those
+// are listed first.
+type indexCursor struct {
+	name  string
+	isDir bool
+}
+
+// parseIndexCursor reads a cursor given in the "after" query string parameter
+func parseIndexCursor(s string) indexCursor {
+	name, isDir := strings.CutSuffix(s, "/")
+	return indexCursor{name: name, isDir: isDir}
+}
+
+// String returns the cursor as it's given in the "after" parameter
+func (c indexCursor) String() string {
+	if c.isDir {
+		return c.name + "/"
+	}
+
+	return c.name
+}
+
+// indexPagination returns how many entries to include in the index, where
+// zero means all of them, and the cursor they start after, if any
+func (s *Server) indexPagination(r *http.Request) (int, *indexCursor, error) {
+	limit := s.IndexJSONPageSize
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			return 0, nil, fmt.Errorf("the limit must be a positive number, got %q", v)
+		}
+
+		limit = n
+	}
+
+	if !r.URL.Query().Has("after") {
+		return limit, nil, nil
+	}
+
+	after := parseIndexCursor(r.URL.Query().Get("after"))
+	if after.name == "" || strings.Contains(after.name, "/") {
+		return 0, nil, fmt.Errorf("invalid cursor %q", r.URL.Query().Get("after"))
+	}
+
+	return limit, &after, nil
+}
+
+// entryHeap keeps the last entries in listing order at the top, so the
+// first entries of a list can be found without sorting all of it
+type entryHeap []os.DirEntry
+
+func (h entryHeap) Len() int { return len(h) }
+func (h entryHeap) Less(i, j int) bool {
+	return listedBefore(h[j].IsDir(), h[j].Name(), h[i].IsDir(), h[i].Name())
+}
+func (h entryHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+func (h *entryHeap) Push(x any)   { *h = append(*h, x.(os.DirEntry)) }
+func (h *entryHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// nextEntries returns, in listing order, the entries listed after the
+// cursor, up to the limit if it's above zero, along with whether more
+// entries were left out. Only the entries returned are sorted, so reading
+// a small batch of a huge directory stays cheap.
+func nextEntries(list []os.DirEntry, after *indexCursor, limit int) ([]os.DirEntry, bool) {
+	var kept entryHeap
+	more := false
+
+	for _, f := range list {
+		if after != nil && !listedBefore(after.isDir, after.name, f.IsDir(), f.Name()) {
+			continue
+		}
+
+		if limit <= 0 || len(kept) < limit {
+			heap.Push(&kept, f)
+			continue
+		}
+
+		// The batch is full, so the entry only makes it if it's
+		// listed before the last entry currently in the batch
+		more = true
+		if listedBefore(f.IsDir(), f.Name(), kept[0].IsDir(), kept[0].Name()) {
+			kept[0] = f
+			heap.Fix(&kept, 0)
+		}
+	}
+
+	sort.Sort(foldersFirst(kept))
+	return kept, more
+}
+
+// readDirUnsorted reads the entries of the directory in the order the
+// filesystem returns them, unlike os.ReadDir, which sorts them by name
+func readDirUnsorted(dir string) ([]os.DirEntry, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return f.ReadDir(-1)
+}
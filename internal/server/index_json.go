@@ -0,0 +1,176 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"time"
+)
+
+// indexJSONName is the name of the file generated in every
+// directory with its listing, when enabled
+const indexJSONName = ".index.json"
+
+// indexJSON is the listing of a directory, as served by the
+// generated index file
+type indexJSON struct {
+	Path    string           `json:"path"`
+	Entries []indexJSONEntry `json:"entries"`
+	Next    string           `json:"next,omitempty"`
+}
+
+// indexJSONEntry is a file or directory in the generated index file
+type indexJSONEntry struct {
+	Name    string    `json:"name"`
+	URL     string    `json:"url"`
+	IsDir   bool      `json:"is_dir"`
+	Size    int64     `json:"size,omitempty"`
+	Mode    string    `json:"mode"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// serveIndexJSON renders the listing of the requested directory as JSON,
+// giving programs a stable URL to read it from. It's only called when
+// no real index file exists at that location, so it never shadows one.
+func (s *Server) serveIndexJSON(requestedDir string, w http.ResponseWriter, r *http.Request) {
+	basePath, err := s.servedPath(requestedDir)
+	if err != nil {
+		s.printWarning("unable to generate absolute path for %q: %s", requestedDir, err)
+		s.httpError(http.StatusInternalServerError, w, r, "internal error generating full paths -- see application logs for details")
+		return
+	}
+
+	dirPath := s.resolveOverlay(requestedDir, basePath)
+
+	// The index is just another way to see the directory listing, so
+	// it's not available when listings are disabled
+	info, err := os.Stat(dirPath)
+	if err != nil || !info.IsDir() || s.DisableDirectoryList {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	// Large directories can be read in batches
+	limit, after, err := s.indexPagination(r)
+	if err != nil {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: %s", err)
+		return
+	}
+
+	list, err := readDirUnsorted(dirPath)
+	if err != nil {
+		s.printWarning("unable to read directory %q: %s", dirPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read directory -- see application logs for more information")
+		return
+	}
+
+	// Merge the entries from overlays, which also count
+	// towards the modification time of the listing
+	overlays := s.overlayDirs(requestedDir, dirPath)
+	list, from := s.readOverlays(list, overlays)
+
+	modTime := info.ModTime()
+	for _, dir := range overlays {
+		if dirInfo, err := os.Stat(dir); err == nil && dirInfo.ModTime().After(modTime) {
+			modTime = dirInfo.ModTime()
+		}
+	}
+
+	// Skip filtered files, and dotfiles if they're hidden, before
+	// picking the batch of entries, so batches are always full
+	visible := list[:0]
+	for _, f := range list {
+		if !s.isHidden(f.Name()) {
+			visible = append(visible, f)
+		}
+	}
+
+	list, more := nextEntries(visible, after, limit)
+
+	// Links are generated using the path prefix the client sees,
+	// in case the server is running behind a reverse proxy
+	index := indexJSON{
+		Path:    fileURL(true, s.publicPrefix(r), requestedDir),
+		Entries: make([]indexJSONEntry, 0, len(list)),
+	}
+
+	// Let the client know where the next batch starts
+	if more && len(list) > 0 {
+		last := list[len(list)-1]
+		index.Next = indexCursor{name: last.Name(), isDir: last.IsDir()}.String()
+	}
+
+	checksumsPending := false
+	for _, f := range list {
+		fi, err := f.Info()
+		if err != nil {
+			s.printWarning("unable to stat file %q: %s", f.Name(), err)
+			continue
+		}
+
+		// Keep track of files coming from overlays, to find their checksum
+		if dir, found := from[fi.Name()]; found {
+			fi = layeredFileInfo{FileInfo: fi, dir: dir}
+		}
+
+		entry := indexJSONEntry{
+			Name:    fi.Name(),
+			URL:     fileURL(fi.IsDir(), index.Path, fi.Name()),
+			IsDir:   fi.IsDir(),
+			Mode:    fi.Mode().String(),
+			ModTime: fi.ModTime().UTC(),
+		}
+
+		if !fi.IsDir() {
+			entry.Size = fi.Size()
+		}
+
+		// Checksums are left out until they're computed
+		if s.ShowChecksums && fi.Mode().IsRegular() {
+			entry.SHA256 = s.listingChecksum(dirPath, fi)
+			checksumsPending = checksumsPending || entry.SHA256 == ""
+		}
+
+		index.Entries = append(index.Entries, entry)
+	}
+
+	body, err := json.Marshal(index)
+	if err != nil {
+		s.printWarning("unable to generate index of directory %q: %s", dirPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate directory index -- see application logs for more information")
+		return
+	}
+
+	// The ETag is computed from the listing as it's served, so it
+	// changes along with any file, or with the files being filtered.
+	// It's weak, since the same listing could be serialized differently.
+	if !s.ETagDisabled {
+		hash := sha1.Sum(body)
+		w.Header().Set("Etag", fmt.Sprintf("W/%q", hex.EncodeToString(hash[:])))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	s.setListingCacheControl(w)
+
+	// The index changes once pending checksums are computed, without
+	// the directory changing, so it can't be validated by date until then
+	if checksumsPending {
+		modTime = time.Time{}
+	}
+
+	// Let the standard library handle HEAD requests, as well as
+	// the "If-None-Match" and "If-Modified-Since" headers
+	http.ServeContent(w, r, "", modTime, bytes.NewReader(body))
+}
+
+// isIndexJSON checks if the requested path is the generated index
+// file of a directory
+func (s *Server) isIndexJSON(requested string) bool {
+	return s.IndexJSON && path.Base(requested) == indexJSONName
+}
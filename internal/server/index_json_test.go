@@ -0,0 +1,295 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func Test_indexJSON(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+	writeTestFile(t, root, "with space.txt", "hello")
+	writeTestFile(t, root, "sub/nested.txt", "nested")
+	writeTestFile(t, root, "_redirects", "")
+	writeTestFile(t, root, "custom/.index.json", `{"custom":true}`)
+
+	tests := []struct {
+		name        string
+		server      *Server
+		path        string
+		wantStatus  int
+		wantEntries []string
+		wantBody    string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			path:       "/.index.json",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:        "root directory",
+			server:      &Server{Path: root, IndexJSON: true},
+			path:        "/.index.json",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{"custom/", "sub/", "file.txt", "with%20space.txt"},
+		},
+		{
+			name:        "nested directory",
+			server:      &Server{Path: root, IndexJSON: true},
+			path:        "/sub/.index.json",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{"nested.txt"},
+		},
+		{
+			name:       "real file is served instead",
+			server:     &Server{Path: root, IndexJSON: true},
+			path:       "/custom/.index.json",
+			wantStatus: http.StatusOK,
+			wantBody:   `{"custom":true}`,
+		},
+		{
+			name:       "missing directory",
+			server:     &Server{Path: root, IndexJSON: true},
+			path:       "/missing/.index.json",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "directory listing disabled",
+			server:     &Server{Path: root, IndexJSON: true, DisableDirectoryList: true},
+			path:       "/.index.json",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantBody != "" {
+				if got := rec.Body.String(); got != tt.wantBody {
+					t.Fatalf("expected body %q, got %q", tt.wantBody, got)
+				}
+				return
+			}
+
+			if tt.wantEntries == nil {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Type"); got != "application/json" {
+				t.Errorf("expected content type %q, got %q", "application/json", got)
+			}
+
+			var index indexJSON
+			if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+				t.Fatalf("unable to decode index: %s", err)
+			}
+
+			if len(index.Entries) != len(tt.wantEntries) {
+				t.Fatalf("expected %d entries, got %d: %+v", len(tt.wantEntries), len(index.Entries), index.Entries)
+			}
+
+			for i, want := range tt.wantEntries {
+				if got := index.Entries[i].URL; got != index.Path+want {
+					t.Errorf("expected entry %d to link to %q, got %q", i, index.Path+want, got)
+				}
+			}
+		})
+	}
+}
+
+func Test_indexJSONConditional(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+	writeTestFile(t, root, ".hidden", "hello")
+
+	h := newTestHandler(t, &Server{Path: root, IndexJSON: true})
+
+	first := doRequest(h, http.MethodGet, "/.index.json", nil)
+	etag := first.Header().Get("Etag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("expected a weak ETag, got %q", etag)
+	}
+
+	tests := []struct {
+		name        string
+		ifNoneMatch string
+		wantStatus  int
+	}{
+		{name: "matching ETag", ifNoneMatch: etag, wantStatus: http.StatusNotModified},
+		{name: "matching strong form of the ETag", ifNoneMatch: strings.TrimPrefix(etag, "W/"), wantStatus: http.StatusNotModified},
+		{name: "matching ETag in a list", ifNoneMatch: `"other", ` + etag, wantStatus: http.StatusNotModified},
+		{name: "any ETag", ifNoneMatch: "*", wantStatus: http.StatusNotModified},
+		{name: "different ETag", ifNoneMatch: `W/"other"`, wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, "/.index.json", map[string]string{"If-None-Match": tt.ifNoneMatch})
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantStatus == http.StatusNotModified && rec.Body.Len() != 0 {
+				t.Fatalf("expected no body, got %q", rec.Body.String())
+			}
+		})
+	}
+
+	t.Run("ETag changes with the files", func(t *testing.T) {
+		writeTestFile(t, root, "new.txt", "hello")
+
+		rec := doRequest(h, http.MethodGet, "/.index.json", map[string]string{"If-None-Match": etag})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		if rec.Header().Get("Etag") == etag {
+			t.Fatalf("expected the ETag to change, got %q", etag)
+		}
+	})
+
+	t.Run("ETag changes with the filters", func(t *testing.T) {
+		filtered := newTestHandler(t, &Server{Path: root, IndexJSON: true, HideDotfiles: true})
+		all := doRequest(h, http.MethodGet, "/.index.json", nil)
+
+		rec := doRequest(filtered, http.MethodGet, "/.index.json", map[string]string{"If-None-Match": all.Header().Get("Etag")})
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+	})
+}
+
+func Test_indexJSONPagination(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"b.txt", "A.txt", "a.txt", "c.txt", "zdir/file.txt", "adir/file.txt", "_redirects"} {
+		writeTestFile(t, root, name, "contents")
+	}
+
+	h := newTestHandler(t, &Server{Path: root, IndexJSON: true, IndexJSONPageSize: 2})
+
+	// Follow the cursors until the last batch
+	var names []string
+	var batches int
+	path := "/.index.json"
+	for {
+		rec := doRequest(h, http.MethodGet, path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d for %q, got %d: %s", http.StatusOK, path, rec.Code, rec.Body.String())
+		}
+
+		var index indexJSON
+		if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+			t.Fatalf("unable to decode index: %s", err)
+		}
+
+		if len(index.Entries) > 2 {
+			t.Fatalf("expected at most 2 entries per batch, got %d", len(index.Entries))
+		}
+
+		for _, e := range index.Entries {
+			names = append(names, e.Name)
+		}
+
+		batches++
+		if index.Next == "" {
+			break
+		}
+
+		path = "/.index.json?after=" + url.QueryEscape(index.Next)
+	}
+
+	want := []string{"adir", "zdir", "A.txt", "a.txt", "b.txt", "c.txt"}
+	if strings.Join(names, ",") != strings.Join(want, ",") {
+		t.Fatalf("expected entries %v, got %v", want, names)
+	}
+
+	if batches != 3 {
+		t.Fatalf("expected 3 batches, got %d", batches)
+	}
+
+	tests := []struct {
+		name        string
+		path        string
+		wantStatus  int
+		wantEntries []string
+		wantNext    string
+	}{
+		{
+			name:        "limit from the query string",
+			path:        "/.index.json?limit=5",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{"adir", "zdir", "A.txt", "a.txt", "b.txt"},
+			wantNext:    "b.txt",
+		},
+		{
+			name:        "cursor after a directory",
+			path:        "/.index.json?after=adir/&limit=1",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{"zdir"},
+			wantNext:    "zdir/",
+		},
+		{
+			name:        "cursor for a removed entry",
+			path:        "/.index.json?after=b0.txt",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{"c.txt"},
+		},
+		{
+			name:        "cursor past the end",
+			path:        "/.index.json?after=zzz.txt",
+			wantStatus:  http.StatusOK,
+			wantEntries: []string{},
+		},
+		{
+			name:       "invalid limit",
+			path:       "/.index.json?limit=-1",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "invalid cursor",
+			path:       "/.index.json?after=",
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var index indexJSON
+			if err := json.Unmarshal(rec.Body.Bytes(), &index); err != nil {
+				t.Fatalf("unable to decode index: %s", err)
+			}
+
+			got := make([]string, 0, len(index.Entries))
+			for _, e := range index.Entries {
+				got = append(got, e.Name)
+			}
+
+			if strings.Join(got, ",") != strings.Join(tt.wantEntries, ",") {
+				t.Fatalf("expected entries %v, got %v", tt.wantEntries, got)
+			}
+
+			if index.Next != tt.wantNext {
+				t.Fatalf("expected next cursor %q, got %q", tt.wantNext, index.Next)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"os"
+)
+
+// indexTemplateName is the name index files are parsed under
+// when they're rendered as templates
+const indexTemplateName = "index"
+
+// serveIndexTemplate renders the index file as a template with the data
+// of the directory listing, so it can list the files in the directory or
+// reuse parts of the built-in templates, like "head" or "footer". If the
+// index can't be parsed or rendered, it's served as it is instead.
+func (s *Server) serveIndexTemplate(indexPath string, content map[string]any, w http.ResponseWriter, r *http.Request) {
+	fi, err := os.Stat(indexPath)
+	if err != nil {
+		s.printWarning("unable to stat index file %q: %s", indexPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat index file -- see application logs for more information")
+		return
+	}
+
+	if s.refuseWritable(indexPath, fi, w, r) {
+		return
+	}
+
+	b, err := os.ReadFile(indexPath)
+	if err != nil {
+		s.printWarning("unable to read index file %q: %s", indexPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read index file -- see application logs for more information")
+		return
+	}
+
+	if s.templates == nil {
+		s.serveFile(indexPath, w, r)
+		return
+	}
+
+	tpl, err := s.templates.Clone()
+	if err != nil {
+		s.printWarning("unable to prepare index template %q: %s", indexPath, err)
+		s.serveFile(indexPath, w, r)
+		return
+	}
+
+	if _, err := tpl.New(indexTemplateName).Parse(string(b)); err != nil {
+		s.printWarning("unable to parse index file %q as a template, serving it as is: %s", indexPath, err)
+		s.serveFile(indexPath, w, r)
+		return
+	}
+
+	var body bytes.Buffer
+	if err := tpl.ExecuteTemplate(&body, indexTemplateName, content); err != nil {
+		s.printWarning("unable to render index file %q as a template, serving it as is: %s", indexPath, err)
+		s.serveFile(indexPath, w, r)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Write(body.Bytes())
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_indexTemplating(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "templated/index.html", `<h1>{{ .PageTitle }}</h1><ul>{{ range .Files }}<li>{{ .Name }}</li>{{ end }}</ul>`)
+	writeTestFile(t, root, "templated/report.pdf", "pdf")
+	writeTestFile(t, root, "partials/index.html", `{{ template "head" . }}<p>{{ .CurrentPath }}</p>`)
+	writeTestFile(t, root, "broken/index.html", `<p>{{ .Unclosed </p>`)
+	writeTestFile(t, root, "failing/index.html", `<p>{{ template "missing" . }}</p>`)
+
+	tests := []struct {
+		name        string
+		server      *Server
+		path        string
+		wantStatus  int
+		wantContain string
+	}{
+		{
+			name:        "index rendered with the listing data",
+			server:      &Server{IndexTemplating: true, PageTitle: "Reports"},
+			path:        "/templated/",
+			wantStatus:  http.StatusOK,
+			wantContain: "<h1>Reports</h1><ul><li>index.html</li><li>report.pdf</li></ul>",
+		},
+		{
+			name:        "built-in templates can be reused",
+			server:      &Server{IndexTemplating: true},
+			path:        "/partials/",
+			wantStatus:  http.StatusOK,
+			wantContain: "<p>/partials/</p>",
+		},
+		{
+			name:        "parse errors serve the index as is",
+			server:      &Server{IndexTemplating: true},
+			path:        "/broken/",
+			wantStatus:  http.StatusOK,
+			wantContain: `<p>{{ .Unclosed </p>`,
+		},
+		{
+			name:        "render errors serve the index as is",
+			server:      &Server{IndexTemplating: true},
+			path:        "/failing/",
+			wantStatus:  http.StatusOK,
+			wantContain: `<p>{{ template "missing" . }}</p>`,
+		},
+		{
+			name:        "rendered even if listings are disabled",
+			server:      &Server{IndexTemplating: true, DisableDirectoryList: true},
+			path:        "/templated/",
+			wantStatus:  http.StatusOK,
+			wantContain: "<li>report.pdf</li>",
+		},
+		{
+			name:        "listing requested instead",
+			server:      &Server{IndexTemplating: true},
+			path:        "/templated/?noindex",
+			wantStatus:  http.StatusOK,
+			wantContain: `data-name="report.pdf"`,
+		},
+		{
+			name:        "disabled",
+			server:      &Server{},
+			path:        "/templated/",
+			wantStatus:  http.StatusOK,
+			wantContain: "<h1>{{ .PageTitle }}</h1>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantContain) {
+				t.Errorf("expected body to contain %q, got %q", tt.wantContain, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package server
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// layoutMarker is the file, inside a directory, holding the name of
+// the layout its listing is rendered with
+const layoutMarker = ".layout"
+
+// layoutTemplate is the name each custom layout is parsed under
+const layoutTemplate = "layout"
+
+// loadLayouts parses the custom directory listing layouts, if any. Each
+// one is parsed against a copy of the built-in templates, so layouts can
+// reuse parts like "head.tmpl" or "footer.tmpl", and redefine any of them
+// without affecting the other layouts.
+func (s *Server) loadLayouts() error {
+	if len(s.Layouts) == 0 {
+		if len(s.LayoutPaths) > 0 {
+			return fmt.Errorf("directory patterns were mapped to layouts, but no layouts were configured")
+		}
+
+		return nil
+	}
+
+	// Layouts are built on top of the built-in templates, so
+	// without them, every directory gets the plain listing
+	if s.templates == nil {
+		s.printError("built-in templates aren't available, ignoring the %d configured layouts", len(s.Layouts))
+		return nil
+	}
+
+	s.layouts = make(map[string]*template.Template, len(s.Layouts))
+	for name, file := range s.Layouts {
+		b, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("unable to read layout %q template %q: %w", name, file, err)
+		}
+
+		tpl, err := s.templates.Clone()
+		if err != nil {
+			return fmt.Errorf("unable to prepare layout %q: %w", name, err)
+		}
+
+		if _, err := tpl.New(layoutTemplate).Parse(string(b)); err != nil {
+			return withKind(ErrTemplateParse, fmt.Errorf("unable to parse layout %q template %q: %w", name, file, err))
+		}
+
+		s.layouts[name] = tpl
+	}
+
+	for pattern, name := range s.LayoutPaths {
+		if _, err := path.Match(pattern, "/"); err != nil {
+			return fmt.Errorf("invalid layout directory pattern %q: %w", pattern, err)
+		}
+
+		if _, found := s.layouts[name]; !found {
+			return fmt.Errorf("directory pattern %q uses layout %q, which isn't configured", pattern, name)
+		}
+	}
+
+	return nil
+}
+
+// listingLayout returns the name of the layout the directory listing is
+// rendered with, or an empty string for the default one. A ".layout" file
+// in the directory takes precedence over the configured directory patterns,
+// and among those, the longest matching pattern wins.
+func (s *Server) listingLayout(dirPath string, r *http.Request) string {
+	if len(s.layouts) == 0 {
+		return ""
+	}
+
+	if b, err := os.ReadFile(filepath.Join(dirPath, layoutMarker)); err == nil {
+		name := strings.TrimSpace(string(b))
+		if _, found := s.layouts[name]; found {
+			return name
+		}
+
+		s.printWarning("directory %q asks for layout %q, which isn't configured, using the default one", dirPath, name)
+	}
+
+	// Patterns are matched against the directory path,
+	// relative to the path prefix and without a trailing slash
+	rel := "/" + strings.Trim(strings.TrimPrefix(r.URL.Path, s.requestPrefix(r)), "/")
+
+	patterns := make([]string, 0, len(s.LayoutPaths))
+	for pattern := range s.LayoutPaths {
+		patterns = append(patterns, pattern)
+	}
+
+	sort.Slice(patterns, func(i, j int) bool {
+		if len(patterns[i]) != len(patterns[j]) {
+			return len(patterns[i]) > len(patterns[j])
+		}
+		return patterns[i] < patterns[j]
+	})
+
+	for _, pattern := range patterns {
+		p := pattern
+		if p != "/" {
+			p = strings.TrimSuffix(p, "/")
+		}
+
+		if matched, _ := path.Match(p, rel); matched {
+			return s.LayoutPaths[pattern]
+		}
+	}
+
+	return ""
+}
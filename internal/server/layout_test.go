@@ -0,0 +1,154 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_layouts(t *testing.T) {
+	layouts := t.TempDir()
+	writeTestFile(t, layouts, "gallery.tmpl", `gallery:{{ range .Files }}[{{ .Name }}]{{ end }}`)
+	writeTestFile(t, layouts, "table.tmpl", `table:{{ .CurrentPath }}`)
+
+	root := t.TempDir()
+	writeTestFile(t, root, "photos/2024/beach.jpg", "image")
+	writeTestFile(t, root, "photos/.layout", "gallery\n")
+	writeTestFile(t, root, "docs/guide.txt", "guide")
+	writeTestFile(t, root, "docs/reports/q1.txt", "report")
+	writeTestFile(t, root, "other/file.txt", "file")
+	writeTestFile(t, root, "unknown/.layout", "missing")
+	writeTestFile(t, root, "unknown/file.txt", "file")
+
+	tests := []struct {
+		name     string
+		path     string
+		want     string
+		wantNone []string
+	}{
+		{
+			name:     "marker file",
+			path:     "/photos/",
+			want:     "gallery:[2024]",
+			wantNone: []string{".layout"},
+		},
+		{
+			name: "directory pattern",
+			path: "/docs/",
+			want: "table:/docs/",
+		},
+		{
+			name: "longest pattern wins",
+			path: "/docs/reports/",
+			want: "gallery:[q1.txt]",
+		},
+		{
+			name:     "default layout",
+			path:     "/other/",
+			want:     "file.txt",
+			wantNone: []string{"gallery:", "table:"},
+		},
+		{
+			name:     "unknown layout in marker falls back to the default",
+			path:     "/unknown/",
+			want:     "file.txt",
+			wantNone: []string{"gallery:", "table:"},
+		},
+		{
+			name:     "bare listings ignore layouts",
+			path:     "/photos/?bare",
+			want:     "2024",
+			wantNone: []string{"gallery:"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{
+				Path: root,
+				Layouts: map[string]string{
+					"gallery": filepath.Join(layouts, "gallery.tmpl"),
+					"table":   filepath.Join(layouts, "table.tmpl"),
+				},
+				LayoutPaths: map[string]string{
+					"/docs/":        "table",
+					"/docs/*":       "table",
+					"/docs/reports": "gallery",
+				},
+			})
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rec.Code, rec.Body.String())
+			}
+
+			body := rec.Body.String()
+			if !strings.Contains(body, tt.want) {
+				t.Fatalf("expected body to contain %q, got %q", tt.want, body)
+			}
+
+			for _, s := range tt.wantNone {
+				if strings.Contains(body, s) {
+					t.Fatalf("expected body not to contain %q, got %q", s, body)
+				}
+			}
+		})
+	}
+}
+
+func Test_loadLayouts(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "valid.tmpl", `{{ template "head.tmpl" . }}`)
+	writeTestFile(t, dir, "invalid.tmpl", `{{ if }}`)
+
+	tests := []struct {
+		name        string
+		layouts     map[string]string
+		layoutPaths map[string]string
+		wantErr     bool
+	}{
+		{
+			name:    "built-in templates can be reused",
+			layouts: map[string]string{"valid": filepath.Join(dir, "valid.tmpl")},
+		},
+		{
+			name:    "invalid template",
+			layouts: map[string]string{"invalid": filepath.Join(dir, "invalid.tmpl")},
+			wantErr: true,
+		},
+		{
+			name:        "pattern for an unknown layout",
+			layouts:     map[string]string{"valid": filepath.Join(dir, "valid.tmpl")},
+			layoutPaths: map[string]string{"/photos/": "missing"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid pattern",
+			layouts:     map[string]string{"valid": filepath.Join(dir, "valid.tmpl")},
+			layoutPaths: map[string]string{"/photos/[": "valid"},
+			wantErr:     true,
+		},
+		{
+			name:        "patterns without layouts",
+			layoutPaths: map[string]string{"/photos/": "valid"},
+			wantErr:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{Layouts: tt.layouts, LayoutPaths: tt.layoutPaths}
+
+			templates, err := s.generateTemplates()
+			if err != nil {
+				t.Fatalf("unable to generate templates: %s", err)
+			}
+			s.templates = templates
+
+			if err := s.loadLayouts(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package server
+
+import "slices"
+
+// listColumns are the columns the directory listing can show
+var listColumns = []string{"name", "size", "modtime", "mode"}
+
+// defaultListColumns are the columns shown in the directory
+// listing when none are configured
+var defaultListColumns = []string{"name", "size", "modtime"}
+
+// isListColumn checks if the column is one the directory listing can show
+func isListColumn(column string) bool {
+	for _, c := range listColumns {
+		if c == column {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listColumns returns the columns to show in the directory listing,
+// in the order they should be shown. When file modes are requested,
+// the mode column is added if needed, followed by the file owner on
+// platforms that support it, and the checksum column goes last when
+// checksums are shown.
+func (s *Server) listColumns() []string {
+	columns := s.ListColumns
+	if len(columns) == 0 {
+		columns = defaultListColumns
+	}
+
+	if !s.ShowMode && !s.ShowChecksums {
+		return columns
+	}
+
+	columns = slices.Clone(columns)
+
+	if s.ShowMode {
+		if !slices.Contains(columns, "mode") {
+			columns = append(columns, "mode")
+		}
+
+		if fileOwnerSupported {
+			columns = append(columns, "owner")
+		}
+	}
+
+	if s.ShowChecksums {
+		columns = append(columns, "checksum")
+	}
+
+	return columns
+}
@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -10,55 +11,174 @@ import (
 	"time"
 
 	"github.com/patrickdappollonio/http-server/internal/utils"
+	"golang.org/x/net/netutil"
 )
 
+// limitedIdleTimeout is how long idle keep-alive connections are kept
+// open when the amount of concurrent connections is limited
+const limitedIdleTimeout = 15 * time.Second
+
+// ListenAndServe starts the server and blocks until it receives an
+// interrupt or termination signal, at which point it shuts down gracefully.
+// It uses the Listener if one is set, otherwise it binds Addr or, if empty,
+// all interfaces on the configured Port.
 func (s *Server) ListenAndServe() error {
-	// Generate the appropriate templates for the entire server
+	// Create a context that's cancelled on an OS signal
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	return s.serve(ctx)
+}
+
+// listenAddr returns the address the server binds to when
+// no listener has been provided
+func (s *Server) listenAddr() string {
+	if s.Addr != "" {
+		return s.Addr
+	}
+
+	return fmt.Sprintf(":%d", s.Port)
+}
+
+// listeners returns the listeners to accept connections from: the provided
+// Listener if set, the sockets passed by systemd when socket activation is
+// enabled and available, or a newly bound address otherwise
+func (s *Server) listeners() ([]net.Listener, error) {
+	if s.Listener != nil {
+		return []net.Listener{s.Listener}, nil
+	}
+
+	if s.SocketActivation {
+		listeners, err := activationListeners()
+		if err != nil {
+			return nil, err
+		}
+
+		if len(listeners) > 0 {
+			for _, l := range listeners {
+				fmt.Fprintln(s.LogOutput, "Using socket-activated listener on:", l.Addr())
+			}
+
+			return listeners, nil
+		}
+	}
+
+	// Bind the address before starting the server, so binding
+	// errors are reported right away
+	listener, err := net.Listen("tcp", s.listenAddr())
+	if err != nil {
+		return nil, fmt.Errorf("unable to listen on %q: %w", s.listenAddr(), err)
+	}
+
+	return []net.Listener{listener}, nil
+}
+
+// serve runs the server until the context is cancelled
+func (s *Server) serve(ctx context.Context) error {
+	// Generate the appropriate templates for the entire server, falling
+	// back to plain listings if they can't be parsed, so the files are
+	// still served instead of the server failing to start, unless
+	// templates are required to parse
 	dltemplates, err := s.generateTemplates()
 	if err != nil {
-		return err
+		if s.StrictTemplates {
+			return err
+		}
+
+		s.printError("%s: directory listings will be rendered as plain HTML", err)
 	}
 	s.templates = dltemplates
 
+	// Load the custom error template, if any
+	if err := s.loadErrorTemplate(); err != nil {
+		return err
+	}
+
+	// Keep track of when the server started, for health checks
+	s.startedAt = time.Now()
+
 	// Configure a cache buster if the option is enabled
 	if !s.DisableCacheBuster {
 		s.cacheBuster = utils.Random(8)
 	}
 
-	// Create a OS Signal handler
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
+	// Buffer the log output if requested, writing out whatever
+	// is left once the server stops
+	if s.LogFlushInterval > 0 {
+		logs := newBufferedLog(s.LogOutput, s.LogFlushInterval)
+		s.LogOutput = logs
+		defer logs.Close()
+	}
 
-	// Create a channel to hold closure
-	close := make(chan error, 1)
+	// Generate the router with all the handlers
+	router, err := s.router()
+	if err != nil {
+		return err
+	}
 
 	// Set up an initial server
 	srv := &http.Server{
-		Addr:    fmt.Sprintf(":%d", s.Port),
-		Handler: s.router(),
+		Handler: router,
+
+		// Bound how long clients can take to send the request headers,
+		// so slow clients can't hold connections open indefinitely
+		ReadHeaderTimeout: s.ReadHeaderTimeout,
+
+		// Let "OPTIONS *" requests reach the router when they're
+		// answered by it, rather than by the standard library
+		DisableGeneralOptionsHandler: s.AllowOptions,
 	}
 
-	// Start the server asynchronously
-	go func() {
-		fmt.Fprintln(s.LogOutput, "Starting server...")
-		if err := srv.ListenAndServe(); err != nil {
-			if err != http.ErrServerClosed {
+	// Grab the listeners the server will accept connections from
+	listeners, err := s.listeners()
+	if err != nil {
+		return err
+	}
+
+	// Create a channel to hold closure, with room for every
+	// listener failing plus the shutdown result
+	close := make(chan error, len(listeners)+1)
+
+	// Idle keep-alive connections hold a slot when connections are
+	// limited, so they're closed sooner to let waiting clients in
+	if s.MaxConnections > 0 {
+		srv.IdleTimeout = limitedIdleTimeout
+	}
+
+	// Start the server asynchronously on every listener, limiting the
+	// amount of concurrent connections on each if requested, so further
+	// connections wait until a slot is available
+	fmt.Fprintln(s.LogOutput, "Starting server...")
+	for _, listener := range listeners {
+		if s.MaxConnections > 0 {
+			listener = netutil.LimitListener(listener, s.MaxConnections)
+		}
+
+		go func(listener net.Listener) {
+			if err := srv.Serve(listener); err != nil && err != http.ErrServerClosed {
 				close <- err
-			} else {
-				fmt.Fprintln(s.LogOutput, "Server closed. Bye!")
 			}
-		}
-	}()
+		}(listener)
+	}
 
-	// Wait for a closing signal
+	// Let systemd know the server is ready, if running as a service
+	s.notifySystemd("READY=1")
+
+	// Wait for the context to be cancelled
 	go func() {
-		<-sigs
+		<-ctx.Done()
 
 		fmt.Fprintln(s.LogOutput, "Requesting server to stop. Please wait...")
+		s.notifySystemd("STOPPING=1")
 
 		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
 		defer cancel()
-		close <- srv.Shutdown(ctx)
+
+		err := srv.Shutdown(ctx)
+		if err == nil {
+			fmt.Fprintln(s.LogOutput, "Server closed. Bye!")
+		}
+		close <- err
 	}()
 
 	// Hold here until close happens
@@ -0,0 +1,141 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func Test_serveWithListener(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to create listener: %s", err)
+	}
+
+	root := t.TempDir()
+	writeTestFile(t, root, "hello.txt", "hello world")
+
+	s := &Server{
+		Path:       root,
+		PathPrefix: "/",
+		LogOutput:  io.Discard,
+		Listener:   listener,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- s.serve(ctx) }()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/hello.txt")
+	if err != nil {
+		cancel()
+		t.Fatalf("unable to perform request: %s", err)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || string(body) != "hello world" {
+		t.Errorf("expected 200 with file contents, got %d: %q", resp.StatusCode, string(body))
+	}
+
+	cancel()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("expected a clean shutdown, got: %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not shut down after the context was cancelled")
+	}
+}
+
+func Test_listenAddr(t *testing.T) {
+	tests := []struct {
+		name   string
+		server *Server
+		want   string
+	}{
+		{
+			name:   "port only",
+			server: &Server{Port: 5000},
+			want:   ":5000",
+		},
+		{
+			name:   "address takes precedence over port",
+			server: &Server{Port: 5000, Addr: "127.0.0.1:8080"},
+			want:   "127.0.0.1:8080",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.server.listenAddr(); got != tt.want {
+				t.Errorf("expected address %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_readHeaderTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to create listener: %s", err)
+	}
+
+	s := &Server{
+		Path:              t.TempDir(),
+		PathPrefix:        "/",
+		LogOutput:         io.Discard,
+		Listener:          listener,
+		ReadHeaderTimeout: 100 * time.Millisecond,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go s.serve(ctx)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to connect: %s", err)
+	}
+	defer conn.Close()
+
+	// Send the start of a request, without ever finishing its headers
+	if _, err := io.WriteString(conn, "GET / HTTP/1.1\r\nHost: example.com\r\n"); err != nil {
+		t.Fatalf("unable to write request: %s", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	start := time.Now()
+
+	// The server closes the connection once the timeout passes
+	io.Copy(io.Discard, conn)
+
+	if elapsed := time.Since(start); elapsed >= 5*time.Second {
+		t.Fatalf("expected the connection to be closed after the timeout, still open after %s", elapsed)
+	}
+}
+
+func Test_serveStrictTemplates(t *testing.T) {
+	original := listingTemplatesFS
+	listingTemplatesFS = fstest.MapFS{"templates/broken.tmpl": {Data: []byte(`{{ define "broken" }}{{ .Unclosed `)}}
+	t.Cleanup(func() { listingTemplatesFS = original })
+
+	s := &Server{
+		Path:            t.TempDir(),
+		PathPrefix:      "/",
+		LogOutput:       io.Discard,
+		StrictTemplates: true,
+	}
+
+	if err := s.serve(context.Background()); !errors.Is(err, ErrTemplateParse) {
+		t.Fatalf("expected a template parse error, got: %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"container/list"
+	"encoding/binary"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+)
+
+// maxListingCacheBytes is the most memory, in bytes, rendered listings
+// can take in the cache
+const maxListingCacheBytes = 64 << 20
+
+// listingCacheEntry is a rendered directory listing, along with the
+// information needed to know if it's still valid
+type listingCacheEntry struct {
+	key        string
+	hash       uint64
+	dirModTime time.Time
+	expiresAt  time.Time
+	body       []byte
+}
+
+// listingCache keeps rendered directory listings in memory, so directories
+// that rarely change aren't rendered again on every request. If maxBytes or
+// maxEntries are set, the oldest entries are evicted to keep the cache within
+// them. Each cache stores its entries for the same amount of time, so the
+// oldest entries are also the ones closest to expiring, which is what allows
+// expired entries to be removed without going through all of them.
+type listingCache struct {
+	mu         sync.Mutex
+	entries    map[string]*list.Element
+	order      *list.List
+	size       int
+	maxBytes   int
+	maxEntries int
+}
+
+// get returns the cached listing for the key if it hasn't expired, and
+// both the directory modification time and the file set hash still match
+func (c *listingCache) get(key string, hash uint64, dirModTime time.Time) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+
+	entry := elem.Value.(listingCacheEntry)
+	if time.Now().After(entry.expiresAt) || entry.hash != hash || !entry.dirModTime.Equal(dirModTime) {
+		c.remove(key)
+		return nil, false
+	}
+
+	return entry.body, true
+}
+
+// set stores a rendered listing for the given amount of time, also
+// removing the expired entries at the front of the cache so it doesn't
+// grow with entries that are never requested again
+func (c *listingCache) set(key string, hash uint64, dirModTime time.Time, ttl time.Duration, body []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+
+	if c.entries == nil {
+		c.entries = make(map[string]*list.Element)
+		c.order = list.New()
+	}
+
+	for front := c.order.Front(); front != nil && now.After(front.Value.(listingCacheEntry).expiresAt); front = c.order.Front() {
+		c.remove(front.Value.(listingCacheEntry).key)
+	}
+
+	c.remove(key)
+
+	if c.maxBytes > 0 && len(body) > c.maxBytes {
+		return
+	}
+
+	for c.order.Len() > 0 && ((c.maxBytes > 0 && c.size+len(body) > c.maxBytes) || (c.maxEntries > 0 && c.order.Len() >= c.maxEntries)) {
+		c.remove(c.order.Front().Value.(listingCacheEntry).key)
+	}
+
+	c.size += len(body)
+	c.entries[key] = c.order.PushBack(listingCacheEntry{
+		key:        key,
+		hash:       hash,
+		dirModTime: dirModTime,
+		expiresAt:  now.Add(ttl),
+		body:       body,
+	})
+}
+
+// remove deletes the entry for the key, if any
+func (c *listingCache) remove(key string) {
+	if elem, found := c.entries[key]; found {
+		c.size -= len(elem.Value.(listingCacheEntry).body)
+		c.order.Remove(elem)
+		delete(c.entries, key)
+	}
+}
+
+// len returns the amount of listings currently cached
+func (c *listingCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.entries)
+}
+
+// hashListing generates a hash of the files in a listing and the paths
+// used to generate its links, so any change to them invalidates the cache
+func hashListing(files []os.FileInfo, paths ...string) uint64 {
+	h := fnv.New64a()
+
+	for _, p := range paths {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+
+	var buf [8]byte
+	for _, f := range files {
+		h.Write([]byte(f.Name()))
+		h.Write([]byte{0})
+
+		binary.LittleEndian.PutUint64(buf[:], uint64(f.Size()))
+		h.Write(buf[:])
+
+		binary.LittleEndian.PutUint64(buf[:], uint64(f.ModTime().UnixNano()))
+		h.Write(buf[:])
+
+		binary.LittleEndian.PutUint64(buf[:], uint64(f.Mode()))
+		h.Write(buf[:])
+	}
+
+	return h.Sum64()
+}
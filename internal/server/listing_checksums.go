@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// listingChecksumWorkers is how many files are hashed at the same
+	// time for the checksums shown in directory listings
+	listingChecksumWorkers = 2
+
+	// maxPendingListingChecksums is how many files can be waiting to be
+	// hashed at once. Files over the limit are scheduled again the next
+	// time a listing shows them.
+	maxPendingListingChecksums = 1000
+)
+
+// checksumWorkers hashes files in the background, so listings showing
+// checksums never wait for them. Its zero value is ready to use.
+type checksumWorkers struct {
+	mu      sync.Mutex
+	pending map[string]bool
+	sem     chan struct{}
+}
+
+// schedule hashes the file in the background, unless it's pending
+// already or too many files are
+func (c *checksumWorkers) schedule(fp string, hashFile func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.pending == nil {
+		c.pending = make(map[string]bool)
+		c.sem = make(chan struct{}, listingChecksumWorkers)
+	}
+
+	if c.pending[fp] || len(c.pending) >= maxPendingListingChecksums {
+		return
+	}
+
+	c.pending[fp] = true
+
+	go func() {
+		c.sem <- struct{}{}
+		hashFile()
+		<-c.sem
+
+		c.mu.Lock()
+		delete(c.pending, fp)
+		c.mu.Unlock()
+	}()
+}
+
+// listingChecksum returns the SHA-256 checksum of the file in the given
+// directory, as shown in the listing. Checksums not computed yet are
+// scheduled to be, and an empty string is returned in the meantime.
+func (s *Server) listingChecksum(dir string, fi os.FileInfo) string {
+	if !s.ShowChecksums || !fi.Mode().IsRegular() {
+		return ""
+	}
+
+	fp := filepath.Join(fileDir(dir, fi), fi.Name())
+	if sum, found := s.cachedChecksum(fp, fi, "sha256"); found {
+		return sum
+	}
+
+	s.listingChecksums.schedule(fp, func() {
+		if _, err := s.fileChecksumContext(context.Background(), fp, fi, "sha256"); err != nil {
+			s.printWarning("unable to compute the checksum of file %q for the directory listing: %s", fp, err)
+		}
+	})
+
+	return ""
+}
+
+// checksumsPending checks if any of the files in the directory listing
+// is still waiting for its checksum to be computed
+func (s *Server) checksumsPending(dir string, files []os.FileInfo) bool {
+	if !s.ShowChecksums {
+		return false
+	}
+
+	for _, fi := range files {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+
+		if _, found := s.cachedChecksum(filepath.Join(fileDir(dir, fi), fi.Name()), fi, "sha256"); !found {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,122 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForBody requests the path until the body contains the
+// given string, failing the test if it never does
+func waitForBody(t *testing.T, h http.Handler, path, want string) string {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rec := doRequest(h, http.MethodGet, path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+		}
+
+		body := rec.Body.String()
+		if strings.Contains(body, want) {
+			return body
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %q to eventually contain %q, got: %s", path, want, body)
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func Test_listingChecksums(t *testing.T) {
+	sum := sha256.Sum256([]byte("release"))
+	want := hex.EncodeToString(sum[:])
+
+	tests := []struct {
+		name   string
+		server *Server
+	}{
+		{
+			name:   "rendered straight to the client",
+			server: &Server{ShowChecksums: true},
+		},
+		{
+			name:   "cached listings",
+			server: &Server{ShowChecksums: true, ListingCacheTTL: time.Minute},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "release.tar.gz", "release")
+			writeTestFile(t, root, "nested/file.txt", "file")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			body := doRequest(h, http.MethodGet, "/", nil).Body.String()
+			if !strings.Contains(body, "<strong>SHA-256</strong>") {
+				t.Fatalf("expected the listing to have a checksum column")
+			}
+
+			if !strings.Contains(body, want[:12]) && !strings.Contains(body, "computing&hellip;") {
+				t.Fatalf("expected the listing to show the checksum or that it's being computed")
+			}
+
+			body = waitForBody(t, h, "/", `title="`+want+`"`)
+			if !strings.Contains(body, `<span class="checksum">-</span>`) {
+				t.Errorf("expected directories to have no checksum")
+			}
+		})
+	}
+}
+
+func Test_listingChecksumsDisabled(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "release.tar.gz", "release")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	body := doRequest(h, http.MethodGet, "/", nil).Body.String()
+	if strings.Contains(body, "SHA-256") || strings.Contains(body, `class="checksum`) {
+		t.Fatalf("expected no checksum column when checksums aren't shown")
+	}
+}
+
+func Test_indexJSONChecksums(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "release.tar.gz", "release")
+	writeTestFile(t, root, "nested/file.txt", "file")
+
+	sum := sha256.Sum256([]byte("release"))
+	want := hex.EncodeToString(sum[:])
+
+	h := newTestHandler(t, &Server{Path: root, IndexJSON: true, ShowChecksums: true})
+	body := waitForBody(t, h, "/"+indexJSONName, want)
+
+	var index indexJSON
+	if err := json.Unmarshal([]byte(body), &index); err != nil {
+		t.Fatalf("unable to decode index: %s", err)
+	}
+
+	for _, entry := range index.Entries {
+		switch entry.Name {
+		case "release.tar.gz":
+			if entry.SHA256 != want {
+				t.Errorf("expected checksum %q, got %q", want, entry.SHA256)
+			}
+		case "nested":
+			if entry.SHA256 != "" {
+				t.Errorf("expected directories to have no checksum, got %q", entry.SHA256)
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// listingFilter returns the pattern given in the "filter" query string
+// parameter, which directory listings only show matching files for, or
+// an empty string if there's none or it isn't a valid pattern
+func (s *Server) listingFilter(r *http.Request) string {
+	pattern := r.URL.Query().Get("filter")
+	if pattern == "" {
+		return ""
+	}
+
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		s.printDebug("ignoring invalid listing filter %q: %s", pattern, err)
+		return ""
+	}
+
+	return pattern
+}
+
+// matchesListingFilter reports whether the entry is shown in a listing
+// filtered with the pattern. Directories are always shown, so it's still
+// possible to navigate into them.
+func matchesListingFilter(pattern string, fi os.FileInfo) bool {
+	if pattern == "" || fi.IsDir() {
+		return true
+	}
+
+	matched, _ := filepath.Match(pattern, fi.Name())
+	return matched
+}
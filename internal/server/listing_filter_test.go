@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_listingFilter(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "report.pdf", "pdf")
+	writeTestFile(t, root, "notes.txt", "txt")
+	writeTestFile(t, root, "docs/guide.txt", "guide")
+
+	tests := []struct {
+		name        string
+		path        string
+		wantShown   []string
+		wantHidden  []string
+		wantFilter  string
+		wantMessage string
+	}{
+		{
+			name:      "no filter",
+			path:      "/",
+			wantShown: []string{"report.pdf", "notes.txt", "docs"},
+		},
+		{
+			name:       "matching files and every directory",
+			path:       "/?filter=*.pdf",
+			wantShown:  []string{"report.pdf", "docs"},
+			wantHidden: []string{"notes.txt"},
+			wantFilter: "*.pdf",
+		},
+		{
+			name:        "nothing matches",
+			path:        "/docs/?filter=*.pdf",
+			wantHidden:  []string{"guide.txt"},
+			wantFilter:  "*.pdf",
+			wantMessage: "No files match the filter",
+		},
+		{
+			name:      "invalid patterns are ignored",
+			path:      "/?filter=%5B",
+			wantShown: []string{"report.pdf", "notes.txt", "docs"},
+		},
+	}
+
+	servers := []*Server{
+		{Path: root},
+		{Path: root, ListingCacheTTL: time.Minute},
+		{Path: root, StreamListing: true},
+	}
+
+	for _, s := range servers {
+		h := newTestHandler(t, s)
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rec := doRequest(h, http.MethodGet, tt.path, nil)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+				}
+
+				body := rec.Body.String()
+				for _, name := range tt.wantShown {
+					if !strings.Contains(body, `data-name="`+name+`"`) {
+						t.Errorf("expected %q to be listed", name)
+					}
+				}
+
+				for _, name := range tt.wantHidden {
+					if strings.Contains(body, `data-name="`+name+`"`) {
+						t.Errorf("expected %q to not be listed", name)
+					}
+				}
+
+				if want := `name="filter" value="` + tt.wantFilter + `"`; !strings.Contains(body, want) {
+					t.Errorf("expected the filter box to contain %q", tt.wantFilter)
+				}
+
+				if tt.wantMessage != "" && !strings.Contains(body, tt.wantMessage) {
+					t.Errorf("expected body to contain %q", tt.wantMessage)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"time"
+)
+
+// errListingLimit is returned when rendering a directory listing goes
+// over the configured size or time limits
+var errListingLimit = errors.New("directory listing render limit exceeded")
+
+// limitedRender is the buffer directory listings are rendered into when
+// render limits are configured. Once the listing grows past the maximum
+// size, or rendering takes longer than allowed, writes fail, which stops
+// the template execution right away.
+type limitedRender struct {
+	bytes.Buffer
+	maxBytes int64
+	deadline time.Time
+}
+
+// Write implements io.Writer
+func (l *limitedRender) Write(p []byte) (int, error) {
+	if l.maxBytes > 0 && int64(l.Len()+len(p)) > l.maxBytes {
+		return 0, errListingLimit
+	}
+
+	if !l.deadline.IsZero() && time.Now().After(l.deadline) {
+		return 0, errListingLimit
+	}
+
+	return l.Buffer.Write(p)
+}
+
+// hasListingLimits reports whether listings are rendered with size or
+// time limits, which requires buffering them before they're sent
+func (s *Server) hasListingLimits() bool {
+	return s.MaxListingBytes > 0 || s.ListingRenderTimeout > 0
+}
+
+// newListingRender returns the buffer a listing is rendered into,
+// enforcing the configured render limits
+func (s *Server) newListingRender() *limitedRender {
+	l := &limitedRender{maxBytes: s.MaxListingBytes}
+	if s.ListingRenderTimeout > 0 {
+		l.deadline = time.Now().Add(s.ListingRenderTimeout)
+	}
+
+	return l
+}
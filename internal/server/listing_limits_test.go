@@ -0,0 +1,76 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_listingLimits(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		writeTestFile(t, root, fmt.Sprintf("file-%02d.txt", i), "contents")
+	}
+
+	tests := []struct {
+		name       string
+		server     *Server
+		wantStatus int
+	}{
+		{
+			name:       "within the limits",
+			server:     &Server{MaxListingBytes: 1 << 20, ListingRenderTimeout: time.Minute},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "within the limits with caching",
+			server:     &Server{MaxListingBytes: 1 << 20, ListingCacheTTL: time.Minute},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "too large",
+			server:     &Server{MaxListingBytes: 1024},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "too slow",
+			server:     &Server{ListingRenderTimeout: time.Nanosecond},
+			wantStatus: http.StatusInternalServerError,
+		},
+		{
+			name:       "no limits",
+			server:     &Server{},
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			body := rec.Body.String()
+			if tt.wantStatus == http.StatusOK {
+				if !strings.Contains(body, `data-name="file-19.txt"`) {
+					t.Errorf("expected the whole listing to be rendered")
+				}
+				return
+			}
+
+			if strings.Contains(body, "file-00.txt") {
+				t.Errorf("expected the partial listing to not be sent, got %q", body)
+			}
+
+			if !strings.Contains(body, "too large to render") {
+				t.Errorf("expected an error about the listing size, got %q", body)
+			}
+		})
+	}
+}
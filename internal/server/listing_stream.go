@@ -0,0 +1,173 @@
+package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"os"
+)
+
+// streamBatchSize is the amount of directory entries read from disk
+// and written to the client before flushing the response
+const streamBatchSize = 500
+
+// streamListing renders the directory listing incrementally: entries are
+// read from disk in batches and written to the client as they come, which
+// keeps memory bounded for very large directories. Since entries are never
+// held all at once, they're shown in the order the filesystem returns them
+// and no markdown file is rendered.
+func (s *Server) streamListing(requestedPath string, overlays []string, w http.ResponseWriter, r *http.Request) {
+	// Open the directory path, but don't read it all at once
+	dir, err := os.Open(requestedPath)
+	if err != nil {
+		// If the directory doesn't exist, render an appropriate message
+		if os.IsNotExist(err) {
+			s.printWarning("attempted to access non-existent path: %s", requestedPath)
+			s.httpError(http.StatusNotFound, w, r, "404 not found")
+			return
+		}
+
+		// Otherwise handle it generically speaking
+		s.printWarning("unable to open directory %q: %s", requestedPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open directory -- see application logs for more information")
+		return
+	}
+	defer dir.Close()
+
+	// Grab a flusher if the response writer supports it, so rows
+	// are sent to the client as soon as a batch is rendered
+	flusher, _ := w.(http.Flusher)
+
+	// Links are generated using the path prefix the client sees,
+	// in case the server is running behind a reverse proxy
+	prefix := s.publicPrefix(r)
+	currentPath := s.publicPath(r, r.URL.Path)
+	filter := s.listingFilter(r)
+
+	content := map[string]any{
+		"DirectoryRootPath": prefix,
+		"PageTitle":         s.pageTitle(r),
+		"CurrentPath":       currentPath,
+		"CacheBuster":       s.cacheBuster,
+		"RequestedPath":     requestedPath,
+		"IsRoot":            s.requestPrefix(r) == r.URL.Path,
+		"UpDirectory":       getParentURL(prefix, currentPath),
+		"HideLinks":         s.HideLinks,
+		"Columns":           s.listColumns(),
+		"AllowUpload":       s.AllowUpload,
+		"Filter":            filter,
+	}
+
+	// Bare listings are streamed with their own templates
+	startTpl, rowTpl, endTpl := "stream-start", "file-row", "stream-end"
+	if s.isBareListing(r) {
+		startTpl, rowTpl, endTpl = "bare-start", "bare-row", "bare-end"
+	}
+
+	// Render the page up to the start of the file list
+	s.setListingCacheControl(w)
+	if err := s.templates.ExecuteTemplate(w, startTpl, content); err != nil {
+		s.printWarning("unable to render directory listing: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to render directory listing -- see application logs for more information")
+		return
+	}
+
+	// From this point onwards the headers have been sent, so errors
+	// can only be logged and the listing cut short
+	isEmpty := true
+
+	// With overlays, names already listed by a directory with a higher
+	// precedence are skipped, which requires keeping track of them
+	var seen map[string]bool
+	if len(overlays) > 0 {
+		seen = make(map[string]bool)
+	}
+
+	// streamDir writes a row for every entry in the directory, in batches,
+	// and reports whether the listing can continue
+	streamDir := func(dir *os.File, dirPath string, isOverlay bool) bool {
+		for {
+			// Stop reading the directory if the client is gone
+			if err := r.Context().Err(); err != nil {
+				s.printCanceled(r, "stopped streaming directory %q: %s", dirPath, err)
+				return false
+			}
+
+			list, err := dir.ReadDir(streamBatchSize)
+
+			for _, f := range list {
+				fi, err := f.Info()
+				if err != nil {
+					s.printWarning("unable to stat file %q: %s", f.Name(), err)
+					continue
+				}
+
+				// Skip filtered files, dotfiles if they're hidden, and
+				// files not matching the filter given in the request
+				if s.isHidden(fi.Name()) || !matchesListingFilter(filter, fi) {
+					continue
+				}
+
+				if seen != nil {
+					if seen[fi.Name()] {
+						continue
+					}
+					seen[fi.Name()] = true
+				}
+
+				if isOverlay {
+					fi = layeredFileInfo{FileInfo: fi, dir: dirPath}
+				}
+
+				row := map[string]any{
+					"CurrentPath":   currentPath,
+					"RequestedPath": requestedPath,
+					"Columns":       content["Columns"],
+					"File":          fi,
+				}
+
+				if err := s.templates.ExecuteTemplate(w, rowTpl, row); err != nil {
+					s.printWarning("unable to render directory listing entry %q: %s", fi.Name(), err)
+					return false
+				}
+
+				isEmpty = false
+			}
+
+			if flusher != nil {
+				flusher.Flush()
+			}
+
+			if errors.Is(err, io.EOF) {
+				return true
+			}
+
+			if err != nil {
+				s.printWarning("unable to read directory %q: %s", dirPath, err)
+				return false
+			}
+		}
+	}
+
+	for i, dirPath := range append([]string{requestedPath}, overlays...) {
+		// The requested directory is open already, overlays are
+		// opened as they're reached
+		if i > 0 {
+			dir, err = os.Open(dirPath)
+			if err != nil {
+				s.printWarning("unable to open overlay directory %q: %s", dirPath, err)
+				continue
+			}
+			defer dir.Close()
+		}
+
+		if !streamDir(dir, dirPath, i > 0) {
+			return
+		}
+	}
+
+	content["IsEmpty"] = isEmpty
+	if err := s.templates.ExecuteTemplate(w, endTpl, content); err != nil {
+		s.printWarning("unable to render directory listing: %s", err)
+	}
+}
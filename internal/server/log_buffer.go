@@ -0,0 +1,73 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+// logBufferSize is how much log output is gathered in memory, at most,
+// before being written out when log buffering is enabled
+const logBufferSize = 64 << 10
+
+// bufferedLog gathers log lines in memory and writes them out when the
+// buffer fills up or, at the latest, once every flush interval, so busy
+// servers make far fewer write calls. It's safe for concurrent use.
+type bufferedLog struct {
+	mu   sync.Mutex
+	bw   *bufio.Writer
+	stop chan struct{}
+	done chan struct{}
+}
+
+// newBufferedLog starts buffering the log output written to w, flushing
+// it periodically until it's closed
+func newBufferedLog(w io.Writer, interval time.Duration) *bufferedLog {
+	b := &bufferedLog{
+		bw:   bufio.NewWriterSize(w, logBufferSize),
+		stop: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(b.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-b.stop:
+				return
+			case <-ticker.C:
+				b.Flush()
+			}
+		}
+	}()
+
+	return b
+}
+
+// Write implements io.Writer
+func (b *bufferedLog) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bw.Write(p)
+}
+
+// Flush writes out any buffered log output
+func (b *bufferedLog) Flush() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.bw.Flush()
+}
+
+// Close stops the periodic flushing and writes out any log output
+// still buffered. Lines written afterwards are still buffered, but
+// only written out once the buffer fills up or is flushed again.
+func (b *bufferedLog) Close() error {
+	close(b.stop)
+	<-b.done
+	return b.Flush()
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// countingWriter keeps what's written to it, counting the write calls
+type countingWriter struct {
+	mu     sync.Mutex
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.writes++
+	return c.buf.Write(p)
+}
+
+func (c *countingWriter) String() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.buf.String()
+}
+
+func Test_bufferedLog(t *testing.T) {
+	t.Run("flushed on close", func(t *testing.T) {
+		out := &countingWriter{}
+		logs := newBufferedLog(out, time.Hour)
+
+		fmt.Fprintln(logs, "first line")
+		fmt.Fprintln(logs, "second line")
+
+		if got := out.String(); got != "" {
+			t.Fatalf("expected no output before flushing, got %q", got)
+		}
+
+		if err := logs.Close(); err != nil {
+			t.Fatalf("unable to close log buffer: %s", err)
+		}
+
+		if got := out.String(); got != "first line\nsecond line\n" {
+			t.Fatalf("unexpected output after closing: %q", got)
+		}
+
+		if out.writes != 1 {
+			t.Fatalf("expected a single write call, got %d", out.writes)
+		}
+	})
+
+	t.Run("flushed periodically", func(t *testing.T) {
+		out := &countingWriter{}
+		logs := newBufferedLog(out, 10*time.Millisecond)
+		defer logs.Close()
+
+		fmt.Fprintln(logs, "periodic line")
+
+		deadline := time.Now().Add(5 * time.Second)
+		for out.String() == "" {
+			if time.Now().After(deadline) {
+				t.Fatal("expected the buffer to be flushed periodically")
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+
+		if got := out.String(); got != "periodic line\n" {
+			t.Fatalf("unexpected output: %q", got)
+		}
+	})
+}
+
+func Benchmark_logFlushInterval(b *testing.B) {
+	for _, interval := range []time.Duration{0, time.Second} {
+		b.Run(fmt.Sprintf("interval=%s", interval), func(b *testing.B) {
+			out := &countingWriter{}
+
+			var logs *bufferedLog
+			var w io.Writer = out
+			if interval > 0 {
+				logs = newBufferedLog(out, interval)
+				w = logs
+			}
+
+			h := mw.LogRequest(w, logFormat, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				h.ServeHTTP(httptest.NewRecorder(), req)
+			}
+			b.StopTimer()
+
+			if logs != nil {
+				logs.Close()
+			}
+
+			b.ReportMetric(float64(out.writes)/float64(b.N), "writes/op")
+		})
+	}
+}
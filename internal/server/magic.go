@@ -0,0 +1,63 @@
+package server
+
+import "bytes"
+
+// magicTypes are the content types detected from the first bytes of a
+// file, for formats http.DetectContentType doesn't know about. They're
+// only checked for files whose extension didn't match a known content
+// type. To support a new format, add its magic number here: the bytes
+// the file has at the given offset, and optionally, a string that must
+// also be found in the first bytes of the file, for formats sharing
+// the same magic number.
+var magicTypes = []struct {
+	Offset      int
+	Magic       string
+	Contains    string
+	ContentType string
+}{
+	// WebAssembly
+	{0, "\x00asm", "", "application/wasm"},
+
+	// Audio and video
+	{0, "fLaC", "", "audio/flac"},
+	{0, "\x1a\x45\xdf\xa3", "webm", "video/webm"},
+	{0, "\x1a\x45\xdf\xa3", "matroska", "video/x-matroska"},
+
+	// Images stored in ISO base media files
+	{4, "ftypavif", "", "image/avif"},
+	{4, "ftypheic", "", "image/heic"},
+	{4, "ftypheix", "", "image/heic"},
+	{4, "ftypmif1", "", "image/heif"},
+
+	// Archives and compressed files
+	{0, "7z\xbc\xaf\x27\x1c", "", "application/x-7z-compressed"},
+	{0, "\xfd7zXZ\x00", "", "application/x-xz"},
+	{0, "\x28\xb5\x2f\xfd", "", "application/zstd"},
+	{0, "BZh", "", "application/x-bzip2"},
+
+	// Databases and data files
+	{0, "SQLite format 3\x00", "", "application/vnd.sqlite3"},
+	{0, "PAR1", "", "application/vnd.apache.parquet"},
+
+	// Executables
+	{0, "\x7fELF", "", "application/x-executable"},
+}
+
+// getContentTypeForMagic returns the content type of the file based on
+// its first bytes, or an empty string if they don't match a known format
+func getContentTypeForMagic(data []byte) string {
+	for _, mt := range magicTypes {
+		end := mt.Offset + len(mt.Magic)
+		if len(data) < end || string(data[mt.Offset:end]) != mt.Magic {
+			continue
+		}
+
+		if mt.Contains != "" && !bytes.Contains(data, []byte(mt.Contains)) {
+			continue
+		}
+
+		return mt.ContentType
+	}
+
+	return ""
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_getContentTypeForMagic(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{
+			name: "webassembly",
+			data: "\x00asm\x01\x00\x00\x00",
+			want: "application/wasm",
+		},
+		{
+			name: "flac",
+			data: "fLaC\x00\x00\x00\x22",
+			want: "audio/flac",
+		},
+		{
+			name: "matroska",
+			data: "\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x88matroska",
+			want: "video/x-matroska",
+		},
+		{
+			name: "webm",
+			data: "\x1a\x45\xdf\xa3\x9f\x42\x86\x81\x01\x42\x82\x84webm",
+			want: "video/webm",
+		},
+		{
+			name: "magic at an offset",
+			data: "\x00\x00\x00\x1cftypavif\x00\x00\x00\x00",
+			want: "image/avif",
+		},
+		{
+			name: "data shorter than the magic number",
+			data: "\x00as",
+			want: "",
+		},
+		{
+			name: "unknown format",
+			data: "just some text",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := getContentTypeForMagic([]byte(tt.data)); got != tt.want {
+				t.Errorf("getContentTypeForMagic() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_serveFileMagicTypes(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "module", "\x00asm\x01\x00\x00\x00")
+	writeTestFile(t, root, "module.txt", "\x00asm\x01\x00\x00\x00")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		// Detected by the magic number, since there's no extension
+		{path: "/module", want: "application/wasm"},
+
+		// The extension takes precedence over the magic number
+		{path: "/module.txt", want: "text/plain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("expected content type %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
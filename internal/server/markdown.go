@@ -19,8 +19,8 @@ import (
 var allowedIndexFiles = []string{"README.md", "README.markdown", "readme.md", "readme.markdown", "index.md", "index.markdown"}
 
 // generateMarkdown generates the markdown needed to render the content
-// in the directory listing page
-func (s *Server) generateMarkdown(pathLocation string, files []os.FileInfo, placeholder *bytes.Buffer) error {
+// in the directory listing page, with assets linked from the given prefix
+func (s *Server) generateMarkdown(pathLocation, prefix string, files []os.FileInfo, placeholder *bytes.Buffer) error {
 	// Check if markdown is enabled or not, if not, don't bother running
 	// the rest of the code
 	if s.DisableMarkdown {
@@ -28,11 +28,11 @@ func (s *Server) generateMarkdown(pathLocation string, files []os.FileInfo, plac
 	}
 
 	// Find a file name among the available options that can be rendered
-	var foundFilename string
+	var foundFilename, foundDir string
 	for _, f := range files {
 		for _, allowed := range allowedIndexFiles {
 			if f.Name() == allowed {
-				foundFilename = allowed
+				foundFilename, foundDir = allowed, fileDir(pathLocation, f)
 				break
 			}
 		}
@@ -44,7 +44,7 @@ func (s *Server) generateMarkdown(pathLocation string, files []os.FileInfo, plac
 	}
 
 	// Generate a full path then open the file
-	fullpath := path.Join(pathLocation, foundFilename)
+	fullpath := path.Join(foundDir, foundFilename)
 	f, err := os.Open(fullpath)
 	if err != nil {
 		return fmt.Errorf("unable to open markdown file %q: %w", fullpath, err)
@@ -65,7 +65,7 @@ func (s *Server) generateMarkdown(pathLocation string, files []os.FileInfo, plac
 			extension.GFM,
 			&mermaid.Extender{
 				RenderMode: mermaid.RenderModeClient,
-				MermaidURL: s.assetpath("mermaid-9.2.0.js"),
+				MermaidURL: s.assetpath(prefix, "mermaid-9.2.0.js"),
 			},
 		),
 		goldmark.WithParserOptions(
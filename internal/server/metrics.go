@@ -0,0 +1,21 @@
+package server
+
+import "github.com/patrickdappollonio/http-server/internal/metrics"
+
+// newMetrics creates the metrics registry for the server, including
+// gauges describing the filesystem backing the served directory
+func (s *Server) newMetrics() *metrics.Registry {
+	registry := metrics.New()
+
+	registry.AddGauge("served_root_disk_total_bytes", "Total size of the filesystem backing the served directory.", func() (float64, bool) {
+		usage, err := s.diskUsage.get(s.Path)
+		return float64(usage.TotalBytes), err == nil
+	})
+
+	registry.AddGauge("served_root_disk_free_bytes", "Free space in the filesystem backing the served directory.", func() (float64, bool) {
+		usage, err := s.diskUsage.get(s.Path)
+		return float64(usage.FreeBytes), err == nil
+	})
+
+	return registry
+}
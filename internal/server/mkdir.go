@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// mkdirName returns the name of the directory to create if the request
+// asks for one, either through the "mkdir" query string parameter or the
+// "mkdir" field of a submitted form
+func mkdirName(r *http.Request) (string, bool) {
+	if query := r.URL.Query(); query.Has("mkdir") {
+		return query.Get("mkdir"), true
+	}
+
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "application/x-www-form-urlencoded" {
+		return r.PostFormValue("mkdir"), true
+	}
+
+	return "", false
+}
+
+// mkdir creates a directory with the given name inside the directory
+// requested, answering with a "201 Created" status code, or redirecting
+// browsers to the new directory listing
+func (s *Server) mkdir(parent, name string, w http.ResponseWriter, r *http.Request) {
+	name = strings.TrimSpace(name)
+
+	if _, ok := plainFileName(name); !ok || s.isFiltered(name) {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: invalid directory name %q", name)
+		return
+	}
+
+	if !s.createDirectory(filepath.Join(parent, name), w, r) {
+		return
+	}
+
+	location := fileURL(true, s.publicPath(r, r.URL.Path), name)
+
+	// Browsers submitting the form from the directory listing are sent
+	// to the new directory, other clients get the status code instead
+	if strings.Contains(r.Header.Get("Accept"), "text/html") {
+		http.Redirect(w, r, location, http.StatusSeeOther)
+		return
+	}
+
+	writeCreated(w, location)
+}
+
+// makeCollection handles WebDAV "MKCOL" requests, which create a
+// directory at the requested path
+func (s *Server) makeCollection(target string, w http.ResponseWriter, r *http.Request) {
+	name := filepath.Base(target)
+	if _, ok := plainFileName(name); !ok || s.isFiltered(name) {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: invalid directory name %q", name)
+		return
+	}
+
+	// The parent directory must already exist
+	if info, err := os.Stat(filepath.Dir(target)); err != nil || !info.IsDir() {
+		s.httpError(http.StatusConflict, w, r, "409 conflict: parent directory does not exist")
+		return
+	}
+
+	if !s.createDirectory(target, w, r) {
+		return
+	}
+
+	writeCreated(w, fileURL(true, s.publicPath(r, r.URL.Path)))
+}
+
+// writeCreated answers with a "201 Created" status code, pointing to
+// what was created in the "Location" header
+func writeCreated(w http.ResponseWriter, location string) {
+	w.Header().Set("Location", location)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.WriteHeader(http.StatusCreated)
+	fmt.Fprint(w, "201 created")
+}
+
+// createDirectory creates the directory after validating it's within the
+// served directory and it doesn't exist yet, writing an error response
+// and returning false if it couldn't be created
+func (s *Server) createDirectory(target string, w http.ResponseWriter, r *http.Request) bool {
+	if !s.isSameOrigin(r) {
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden: requests from other sites are not allowed")
+		return false
+	}
+
+	if _, ok := s.servedDir(target); !ok {
+		s.printWarning("attempted to create a directory outside the served directory: %s", target)
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden")
+		return false
+	}
+
+	if err := os.Mkdir(target, 0o755); err != nil {
+		if os.IsExist(err) {
+			// WebDAV rejects "MKCOL" requests for existing paths as
+			// not allowed, since the method only applies to new ones
+			if r.Method == methodMkcol {
+				allowed := slices.DeleteFunc(s.allowedMethods(), func(method string) bool { return method == methodMkcol })
+				w.Header().Set("Allow", strings.Join(allowed, ", "))
+				s.httpError(http.StatusMethodNotAllowed, w, r, "405 method not allowed: %q already exists", filepath.Base(target))
+				return false
+			}
+
+			s.httpError(http.StatusConflict, w, r, "409 conflict: %q already exists", filepath.Base(target))
+			return false
+		}
+
+		s.printWarning("unable to create directory %q: %s", target, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to create directory -- see application logs for more information")
+		return false
+	}
+
+	fmt.Fprintf(s.LogOutput, "Directory created at %q\n", target)
+	return true
+}
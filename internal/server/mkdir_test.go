@@ -0,0 +1,161 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_mkdir(t *testing.T) {
+	tests := []struct {
+		name         string
+		server       *Server
+		method       string
+		target       string
+		form         string
+		accept       string
+		origin       string
+		wantStatus   int
+		wantLocation string
+		wantDir      string
+	}{
+		{
+			name:       "creating directories disabled",
+			server:     &Server{},
+			method:     http.MethodPost,
+			target:     "/?mkdir=photos",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "created from the query string",
+			server:       &Server{AllowUpload: true},
+			method:       http.MethodPost,
+			target:       "/docs/?mkdir=photos",
+			wantStatus:   http.StatusCreated,
+			wantLocation: "/docs/photos/",
+			wantDir:      "docs/photos",
+		},
+		{
+			name:         "created from the listing form",
+			server:       &Server{AllowUpload: true},
+			method:       http.MethodPost,
+			target:       "/docs/",
+			form:         "photos",
+			accept:       "text/html",
+			wantStatus:   http.StatusSeeOther,
+			wantLocation: "/docs/photos/",
+			wantDir:      "docs/photos",
+		},
+		{
+			name:       "existing directories are a conflict",
+			server:     &Server{AllowUpload: true},
+			method:     http.MethodPost,
+			target:     "/?mkdir=docs",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "names with separators are rejected",
+			server:     &Server{AllowUpload: true},
+			method:     http.MethodPost,
+			target:     "/docs/?mkdir=" + url.QueryEscape("../outside"),
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "traversal names are rejected",
+			server:     &Server{AllowUpload: true},
+			method:     http.MethodPost,
+			target:     "/docs/?mkdir=..",
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "requests from other sites are rejected",
+			server:     &Server{AllowUpload: true},
+			method:     http.MethodPost,
+			target:     "/?mkdir=photos",
+			origin:     "https://evil.example.com",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:         "created with MKCOL",
+			server:       &Server{AllowUpload: true},
+			method:       methodMkcol,
+			target:       "/docs/photos/",
+			wantStatus:   http.StatusCreated,
+			wantLocation: "/docs/photos/",
+			wantDir:      "docs/photos",
+		},
+		{
+			name:       "MKCOL with a missing parent is a conflict",
+			server:     &Server{AllowUpload: true},
+			method:     methodMkcol,
+			target:     "/missing/photos",
+			wantStatus: http.StatusConflict,
+		},
+		{
+			name:       "MKCOL on an existing directory is not allowed",
+			server:     &Server{AllowUpload: true},
+			method:     methodMkcol,
+			target:     "/docs",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "MKCOL on an existing file is not allowed",
+			server:     &Server{AllowUpload: true},
+			method:     methodMkcol,
+			target:     "/docs/existing.txt",
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "docs/existing.txt", "contents")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			var req *http.Request
+			if tt.form != "" {
+				req = httptest.NewRequest(tt.method, tt.target, strings.NewReader(url.Values{"mkdir": {tt.form}}.Encode()))
+				req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				req = httptest.NewRequest(tt.method, tt.target, nil)
+			}
+
+			if tt.accept != "" {
+				req.Header.Set("Accept", tt.accept)
+			}
+
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Errorf("expected location %q, got %q", tt.wantLocation, got)
+			}
+
+			if tt.wantDir != "" {
+				info, err := os.Stat(filepath.Join(root, tt.wantDir))
+				if err != nil || !info.IsDir() {
+					t.Errorf("expected directory %q to exist", tt.wantDir)
+				}
+			}
+
+			if _, err := os.Stat(filepath.Join(filepath.Dir(root), "outside")); err == nil {
+				t.Errorf("expected no directory to be created outside the served directory")
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// move handles WebDAV "MOVE" requests, which rename a file or directory
+// to the path given in the "Destination" header. Existing destinations
+// are only replaced when the request sets the "Overwrite: T" header.
+func (s *Server) move(source string, w http.ResponseWriter, r *http.Request) {
+	if !s.isSameOrigin(r) {
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden: requests from other sites are not allowed")
+		return
+	}
+
+	destination, status, err := s.moveDestination(r)
+	if err != nil {
+		s.httpError(status, w, r, "%d %s: %s", status, strings.ToLower(http.StatusText(status)), err)
+		return
+	}
+
+	// Neither the source nor the destination can be outside of the
+	// served directories, or be one of the served directories itself
+	for _, p := range []string{source, destination} {
+		if root, ok := s.servedDir(p); !ok || p == root {
+			s.printWarning("attempted to move a path outside the served directory: %s", p)
+			s.httpError(http.StatusForbidden, w, r, "403 forbidden")
+			return
+		}
+	}
+
+	if s.isFiltered(filepath.Base(source)) {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	if _, ok := plainFileName(filepath.Base(destination)); !ok || s.isFiltered(filepath.Base(destination)) {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: invalid destination name %q", filepath.Base(destination))
+		return
+	}
+
+	if source == destination {
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden: source and destination are the same")
+		return
+	}
+
+	if strings.HasPrefix(destination, source+string(filepath.Separator)) {
+		s.httpError(http.StatusConflict, w, r, "409 conflict: unable to move a directory into itself")
+		return
+	}
+
+	sourceInfo, err := os.Lstat(source)
+	if err != nil {
+		if os.IsNotExist(err) {
+			s.httpError(http.StatusNotFound, w, r, "404 not found")
+			return
+		}
+
+		s.printWarning("unable to stat path %q: %s", source, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to move path -- see application logs for more information")
+		return
+	}
+
+	// Only move the file if it's still the version the client saw
+	if !s.writePreconditionsMet(source, sourceInfo, w, r) {
+		return
+	}
+
+	// The parent directory of the destination must already exist
+	if info, err := os.Stat(filepath.Dir(destination)); err != nil || !info.IsDir() {
+		s.httpError(http.StatusConflict, w, r, "409 conflict: destination parent directory does not exist")
+		return
+	}
+
+	overwritten := false
+	if info, err := os.Lstat(destination); err == nil {
+		if r.Header.Get("Overwrite") != "T" {
+			s.httpError(http.StatusPreconditionFailed, w, r, "412 precondition failed: %q already exists", filepath.Base(destination))
+			return
+		}
+
+		// Replacing a directory would mean deleting everything in it,
+		// which is never done on behalf of a client
+		if info.IsDir() {
+			s.httpError(http.StatusConflict, w, r, "409 conflict: unable to overwrite directory %q", filepath.Base(destination))
+			return
+		}
+
+		overwritten = true
+	}
+
+	if err := os.Rename(source, destination); err != nil {
+		s.printWarning("unable to move %q to %q: %s", source, destination, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to move path -- see application logs for more information")
+		return
+	}
+
+	fmt.Fprintf(s.LogOutput, "Moved %q to %q\n", source, destination)
+
+	if overwritten {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	// The destination was already validated, so it's known to parse
+	location, _ := url.Parse(r.Header.Get("Destination"))
+	writeCreated(w, location.EscapedPath())
+}
+
+// moveDestination resolves the "Destination" header of a move request
+// to a path on disk, returning the status code to reply with when the
+// destination is invalid. Destinations can be absolute URLs, as WebDAV
+// clients send them, or just a path, but they must point to this server.
+func (s *Server) moveDestination(r *http.Request) (string, int, error) {
+	header := r.Header.Get("Destination")
+	if header == "" {
+		return "", http.StatusBadRequest, fmt.Errorf("missing destination header")
+	}
+
+	u, err := url.Parse(header)
+	if err != nil {
+		return "", http.StatusBadRequest, fmt.Errorf("invalid destination %q", header)
+	}
+
+	var ok bool
+	if u.Path, ok = s.normalizeBackslashes(u.Path); !ok {
+		return "", http.StatusBadRequest, fmt.Errorf("destination %q can't contain backslashes", header)
+	}
+
+	if u.Host != "" && u.Host != r.Host && (!s.TrustProxy || u.Host != r.Header.Get("X-Forwarded-Host")) {
+		return "", http.StatusBadGateway, fmt.Errorf("destination %q is on a different server", header)
+	}
+
+	// Destinations are given as the client sees them, so
+	// they start with the public path prefix, which is also
+	// matched when sent without its trailing slash
+	prefix := s.publicPrefix(r)
+	if u.Path+"/" == prefix {
+		u.Path = prefix
+	}
+
+	if !strings.HasPrefix(u.Path, prefix) {
+		return "", http.StatusForbidden, fmt.Errorf("destination %q is outside the served directory", header)
+	}
+
+	requested := strings.TrimPrefix(u.Path, prefix)
+	if strings.Trim(requested, "/") == "" {
+		return "", http.StatusForbidden, fmt.Errorf("destination %q is the served directory", header)
+	}
+
+	if s.MaxPathDepth > 0 && pathDepth(requested) > s.MaxPathDepth {
+		return "", http.StatusBadRequest, fmt.Errorf("destination %q is nested too deep", header)
+	}
+
+	destination, err := s.servedPath("/" + requested)
+	if err != nil {
+		return "", http.StatusInternalServerError, fmt.Errorf("unable to resolve destination %q", header)
+	}
+
+	return destination, 0, nil
+}
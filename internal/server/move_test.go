@@ -0,0 +1,190 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_move(t *testing.T) {
+	tests := []struct {
+		name        string
+		server      *Server
+		target      string
+		destination string
+		overwrite   string
+		wantStatus  int
+		wantFiles   map[string]string
+		wantMissing []string
+	}{
+		{
+			name:        "moving disabled",
+			server:      &Server{},
+			target:      "/docs/a.txt",
+			destination: "/docs/c.txt",
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "file renamed",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "/docs/c.txt",
+			wantStatus:  http.StatusCreated,
+			wantFiles:   map[string]string{"docs/c.txt": "a"},
+			wantMissing: []string{"docs/a.txt"},
+		},
+		{
+			name:        "absolute destination URL",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "http://example.com/c.txt",
+			wantStatus:  http.StatusCreated,
+			wantFiles:   map[string]string{"c.txt": "a"},
+			wantMissing: []string{"docs/a.txt"},
+		},
+		{
+			name:        "directory moved",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/",
+			destination: "/archive/",
+			wantStatus:  http.StatusCreated,
+			wantFiles:   map[string]string{"archive/a.txt": "a", "archive/b.txt": "b"},
+			wantMissing: []string{"docs"},
+		},
+		{
+			name:        "existing destination without overwrite",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "/docs/b.txt",
+			wantStatus:  http.StatusPreconditionFailed,
+			wantFiles:   map[string]string{"docs/a.txt": "a", "docs/b.txt": "b"},
+		},
+		{
+			name:        "existing destination with overwrite",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "/docs/b.txt",
+			overwrite:   "T",
+			wantStatus:  http.StatusNoContent,
+			wantFiles:   map[string]string{"docs/b.txt": "a"},
+			wantMissing: []string{"docs/a.txt"},
+		},
+		{
+			name:        "missing source",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/missing.txt",
+			destination: "/docs/c.txt",
+			wantStatus:  http.StatusNotFound,
+		},
+		{
+			name:        "destination outside the served directory",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "/../a.txt",
+			wantStatus:  http.StatusForbidden,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "destination on another server",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "http://other.example.com/c.txt",
+			wantStatus:  http.StatusBadGateway,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "destination parent missing",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/a.txt",
+			destination: "/missing/c.txt",
+			wantStatus:  http.StatusConflict,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "directory moved into itself",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/",
+			destination: "/docs/inner/",
+			wantStatus:  http.StatusConflict,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "filtered destination name",
+			server:      &Server{AllowUpload: true, ConfigFilePrefix: ".http-server"},
+			target:      "/docs/a.txt",
+			destination: "/.http-server.yaml",
+			wantStatus:  http.StatusBadRequest,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "served directory can't be moved",
+			server:      &Server{AllowUpload: true},
+			target:      "/",
+			destination: "/archive/",
+			wantStatus:  http.StatusForbidden,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "served directory can't be the destination",
+			server:      &Server{AllowUpload: true, PathPrefix: "/files/"},
+			target:      "/files/docs/a.txt",
+			destination: "/files/",
+			wantStatus:  http.StatusForbidden,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+		},
+		{
+			name:        "prefix without trailing slash is the served directory",
+			server:      &Server{AllowUpload: true, PathPrefix: "/files/"},
+			target:      "/files/docs/a.txt",
+			destination: "http://example.com/files",
+			wantStatus:  http.StatusForbidden,
+			wantFiles:   map[string]string{"docs/a.txt": "a"},
+			wantMissing: []string{"files"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "docs/a.txt", "a")
+			writeTestFile(t, root, "docs/b.txt", "b")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			req := httptest.NewRequest(methodMove, tt.target, nil)
+			req.Header.Set("Destination", tt.destination)
+			if tt.overwrite != "" {
+				req.Header.Set("Overwrite", tt.overwrite)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			for name, contents := range tt.wantFiles {
+				b, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil {
+					t.Errorf("expected file %q to exist: %s", name, err)
+					continue
+				}
+
+				if string(b) != contents {
+					t.Errorf("expected file %q to contain %q, got %q", name, contents, string(b))
+				}
+			}
+
+			for _, name := range tt.wantMissing {
+				if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+					t.Errorf("expected %q to not exist", name)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+)
+
+// optionsHeaders returns the headers OPTIONS requests are answered with,
+// describing the features enabled in the server besides the allowed methods
+func (s *Server) optionsHeaders() http.Header {
+	headers := http.Header{}
+
+	// Files are always served with support for range requests
+	headers.Set("Accept-Ranges", "bytes")
+
+	if s.AllowUpload {
+		headers.Set("Accept-Post", "multipart/form-data")
+	}
+
+	// OPTIONS requests are answered before the CORS headers are added,
+	// so they're included here for preflight requests to succeed
+	if s.CorsEnabled {
+		headers.Set("Access-Control-Allow-Origin", "*")
+		headers.Set("Access-Control-Allow-Methods", strings.Join(s.allowedMethods(), ", "))
+		headers.Set("Access-Control-Allow-Headers", "Content-Type")
+	}
+
+	return headers
+}
@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_optionsRequests(t *testing.T) {
+	tests := []struct {
+		name           string
+		server         *Server
+		target         string
+		wantStatus     int
+		wantAllow      string
+		wantAcceptPost string
+	}{
+		{
+			name:       "disabled",
+			server:     &Server{},
+			target:     "/file.txt",
+			wantStatus: http.StatusMethodNotAllowed,
+			wantAllow:  "GET, HEAD",
+		},
+		{
+			name:       "enabled for a path",
+			server:     &Server{AllowOptions: true},
+			target:     "/file.txt",
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "GET, HEAD, OPTIONS",
+		},
+		{
+			name:       "enabled for the server",
+			server:     &Server{AllowOptions: true},
+			target:     "*",
+			wantStatus: http.StatusNoContent,
+			wantAllow:  "GET, HEAD, OPTIONS",
+		},
+		{
+			name:           "enabled with uploads",
+			server:         &Server{AllowOptions: true, AllowUpload: true},
+			target:         "/",
+			wantStatus:     http.StatusNoContent,
+			wantAllow:      "GET, HEAD, POST, MKCOL, MOVE, OPTIONS",
+			wantAcceptPost: "multipart/form-data",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", "hello")
+			tt.server.Path = root
+
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodOptions, tt.target, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("expected Allow header %q, got %q", tt.wantAllow, got)
+			}
+
+			if got := rec.Header().Get("Accept-Post"); got != tt.wantAcceptPost {
+				t.Errorf("expected Accept-Post header %q, got %q", tt.wantAcceptPost, got)
+			}
+
+			if tt.wantStatus == http.StatusNoContent && rec.Header().Get("Accept-Ranges") != "bytes" {
+				t.Errorf("expected Accept-Ranges header %q, got %q", "bytes", rec.Header().Get("Accept-Ranges"))
+			}
+
+			// Methods rejected with a 405 must advertise the same methods
+			rec = doRequest(h, http.MethodPut, "/file.txt", nil)
+			if rec.Code != http.StatusMethodNotAllowed {
+				t.Fatalf("expected status %d for PUT, got %d", http.StatusMethodNotAllowed, rec.Code)
+			}
+
+			if got := rec.Header().Get("Allow"); got != tt.wantAllow {
+				t.Errorf("expected Allow header %q on 405 responses, got %q", tt.wantAllow, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// layeredFileInfo is a file listed from one of the overlay directories,
+// which keeps track of the directory it was read from, since it could
+// differ from the one being listed
+type layeredFileInfo struct {
+	os.FileInfo
+	dir string
+}
+
+// fileDir returns the directory the file was read from, which is the
+// given directory unless the file comes from an overlay
+func fileDir(dir string, fi os.FileInfo) string {
+	if lf, ok := fi.(layeredFileInfo); ok {
+		return lf.dir
+	}
+
+	return dir
+}
+
+// roots returns the served directories, from the highest precedence to
+// the lowest: overlays configured later take precedence over the ones
+// configured earlier, and all of them over the served path
+func (s *Server) roots() []string {
+	roots := make([]string, 0, len(s.Roots)+1)
+	for i := len(s.Roots) - 1; i >= 0; i-- {
+		roots = append(roots, s.Roots[i])
+	}
+
+	return append(roots, s.Path)
+}
+
+// layerPaths returns the absolute location of the requested path
+// within each of the served directories, by precedence. Aliased
+// directories aren't layered, so paths under an alias only have
+// the location within it.
+func (s *Server) layerPaths(requested string) ([]string, error) {
+	if _, _, found := s.resolveAlias(requested); found {
+		p, err := s.servedPath(requested)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{p}, nil
+	}
+
+	roots := s.roots()
+	paths := make([]string, 0, len(roots))
+
+	for _, root := range roots {
+		p, err := filepath.Abs(filepath.Join(root, requested))
+		if err != nil {
+			return nil, err
+		}
+
+		paths = append(paths, p)
+	}
+
+	return paths, nil
+}
+
+// resolveOverlay returns the location of the requested path in the
+// directory with the highest precedence where it exists, whether it's
+// a file or a directory. Without overlays, or if the path doesn't exist
+// anywhere, the location within the served path is returned.
+func (s *Server) resolveOverlay(requested, fallback string) string {
+	if len(s.Roots) == 0 {
+		return fallback
+	}
+
+	paths, err := s.layerPaths(requested)
+	if err != nil {
+		return fallback
+	}
+
+	for _, p := range paths {
+		if _, err := os.Stat(p); err == nil {
+			return p
+		}
+	}
+
+	return fallback
+}
+
+// overlayDirs returns the directories, other than the given one, that
+// have to be merged into its listing: the same path within every other
+// served directory where it's a directory, by precedence. Paths that
+// are files in a layer are ignored, since the type of the entry is
+// decided by the layer with the highest precedence.
+func (s *Server) overlayDirs(requested, current string) []string {
+	if len(s.Roots) == 0 {
+		return nil
+	}
+
+	paths, err := s.layerPaths(requested)
+	if err != nil {
+		return nil
+	}
+
+	var dirs []string
+	for _, p := range paths {
+		if p == current {
+			continue
+		}
+
+		if info, err := os.Stat(p); err == nil && info.IsDir() {
+			dirs = append(dirs, p)
+		}
+	}
+
+	return dirs
+}
+
+// readOverlays adds to the listing the entries found in the overlay
+// directories, in order, skipping the names already listed so the
+// directory with the highest precedence wins. It also returns the
+// directory each added entry was read from.
+func (s *Server) readOverlays(list []os.DirEntry, overlays []string) ([]os.DirEntry, map[string]string) {
+	if len(overlays) == 0 {
+		return list, nil
+	}
+
+	seen := make(map[string]bool, len(list))
+	for _, f := range list {
+		seen[f.Name()] = true
+	}
+
+	from := make(map[string]string)
+	for _, dir := range overlays {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			s.printWarning("unable to read overlay directory %q: %s", dir, err)
+			continue
+		}
+
+		for _, f := range entries {
+			if seen[f.Name()] {
+				continue
+			}
+
+			seen[f.Name()] = true
+			from[f.Name()] = dir
+			list = append(list, f)
+		}
+	}
+
+	return list, from
+}
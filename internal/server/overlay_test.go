@@ -0,0 +1,96 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_overlays(t *testing.T) {
+	base := t.TempDir()
+	writeTestFile(t, base, "base.txt", "from base")
+	writeTestFile(t, base, "shared.txt", "shared from base")
+	writeTestFile(t, base, "sub/base.txt", "nested from base")
+	writeTestFile(t, base, "conflict", "a file in base")
+
+	first := t.TempDir()
+	writeTestFile(t, first, "shared.txt", "shared from first")
+	writeTestFile(t, first, "first.txt", "from first")
+
+	second := t.TempDir()
+	writeTestFile(t, second, "shared.txt", "shared from second")
+	writeTestFile(t, second, "sub/second.txt", "nested from second")
+	writeTestFile(t, second, "conflict/inside.txt", "a directory in second")
+
+	t.Run("files", func(t *testing.T) {
+		h := newTestHandler(t, &Server{Path: base, Roots: []string{first, second}})
+
+		tests := []struct {
+			path string
+			want string
+		}{
+			{path: "/base.txt", want: "from base"},
+			{path: "/first.txt", want: "from first"},
+			{path: "/shared.txt", want: "shared from second"},
+			{path: "/sub/base.txt", want: "nested from base"},
+			{path: "/sub/second.txt", want: "nested from second"},
+			{path: "/conflict/inside.txt", want: "a directory in second"},
+		}
+
+		for _, tt := range tests {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != http.StatusOK {
+				t.Errorf("%s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+				continue
+			}
+
+			if got := rec.Body.String(); got != tt.want {
+				t.Errorf("%s: expected body %q, got %q", tt.path, tt.want, got)
+			}
+		}
+
+		// The overlay with the highest precedence decides the type
+		if rec := doRequest(h, http.MethodGet, "/conflict", nil); rec.Code != http.StatusMovedPermanently {
+			t.Errorf("expected path that is a directory in an overlay to redirect, got status %d", rec.Code)
+		}
+	})
+
+	for _, stream := range []bool{false, true} {
+		name := "listing"
+		if stream {
+			name = "streamed listing"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			h := newTestHandler(t, &Server{Path: base, Roots: []string{first, second}, StreamListing: stream})
+
+			tests := []struct {
+				path string
+				want []string
+			}{
+				{path: "/", want: []string{"base.txt", "first.txt", "shared.txt", "sub", "conflict"}},
+				{path: "/sub/", want: []string{"base.txt", "second.txt"}},
+			}
+
+			for _, tt := range tests {
+				rec := doRequest(h, http.MethodGet, tt.path, nil)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: expected status %d, got %d", tt.path, http.StatusOK, rec.Code)
+				}
+
+				body := rec.Body.String()
+				for _, name := range tt.want {
+					if n := strings.Count(body, `data-name="`+name+`"`); n != 1 {
+						t.Errorf("%s: expected %q to be listed once, found %d times", tt.path, name, n)
+					}
+				}
+			}
+
+			// Directories shadowing a file are listed as directories
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if got := findFileURL(t, rec.Body.String(), "conflict"); got != "/conflict/" {
+				t.Errorf("expected conflicting entry to link to a directory, got %q", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"unicode"
+)
+
+// maxPageTitleLength is the maximum length, in characters, of the page
+// title, both configured and given in the "title" query string parameter
+const maxPageTitleLength = 100
+
+// pageTitle returns the title of the page rendered for the request: the
+// one given in the "title" query string parameter, if any, so embedded
+// listings can be given a title matching their context, or the configured
+// one otherwise. Like every other value, it's escaped by the templates.
+func (s *Server) pageTitle(r *http.Request) string {
+	if title := requestedPageTitle(r); title != "" {
+		return title
+	}
+
+	return s.PageTitle
+}
+
+// requestedPageTitle returns the title given in the "title" query string
+// parameter, without control characters and cut to the same length the
+// configured title is limited to, or an empty string if there's none
+func requestedPageTitle(r *http.Request) string {
+	title := strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, r.URL.Query().Get("title"))
+
+	title = strings.TrimSpace(title)
+	if runes := []rune(title); len(runes) > maxPageTitleLength {
+		title = strings.TrimSpace(string(runes[:maxPageTitleLength]))
+	}
+
+	return title
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_pageTitle(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+
+	tests := []struct {
+		name   string
+		server *Server
+		title  string
+		want   string
+	}{
+		{
+			name:   "default title",
+			server: &Server{},
+			want:   "<title>HTTP File Server</title>",
+		},
+		{
+			name:   "configured title",
+			server: &Server{PageTitle: "Builds"},
+			want:   "<title>Builds</title>",
+		},
+		{
+			name:   "requested title",
+			server: &Server{PageTitle: "Builds"},
+			title:  "Nightly builds",
+			want:   "<title>Nightly builds</title>",
+		},
+		{
+			name:   "empty requested title",
+			server: &Server{PageTitle: "Builds"},
+			title:  "   ",
+			want:   "<title>Builds</title>",
+		},
+		{
+			name:   "requested title is escaped",
+			server: &Server{},
+			title:  "</title><script>alert(1)</script>",
+			want:   "<title>&lt;/title&gt;&lt;script&gt;alert(1)&lt;/script&gt;</title>",
+		},
+		{
+			name:   "control characters are removed",
+			server: &Server{},
+			title:  "Nightly\r\nbuilds\x00",
+			want:   "<title>Nightlybuilds</title>",
+		},
+		{
+			name:   "long titles are cut",
+			server: &Server{},
+			title:  strings.Repeat("é", maxPageTitleLength+50),
+			want:   "<title>" + strings.Repeat("é", maxPageTitleLength) + "</title>",
+		},
+		{
+			name:   "cached listings keep their title",
+			server: &Server{ListingCacheTTL: time.Minute},
+			title:  "Cached",
+			want:   "<title>Cached</title>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			// Render the listing without a title first, so cached
+			// listings are already there for the titled request
+			doRequest(h, http.MethodGet, "/", nil)
+
+			target := "/"
+			if tt.title != "" {
+				target += "?title=" + url.QueryEscape(tt.title)
+			}
+
+			rec := doRequest(h, http.MethodGet, target, nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.want) {
+				t.Errorf("expected body to contain %q", tt.want)
+			}
+		})
+	}
+}
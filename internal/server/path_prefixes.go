@@ -0,0 +1,53 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// pathPrefixes returns every path prefix the served directory is available
+// under, starting with the main one, making sure none of them falls under
+// the paths used by the server itself, like the health check, of another
+func (s *Server) pathPrefixes() ([]string, error) {
+	prefixes := append([]string{s.PathPrefix}, s.ExtraPathPrefixes...)
+
+	seen := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		if seen[prefix] {
+			return nil, fmt.Errorf("path prefix %q is configured more than once", prefix)
+		}
+		seen[prefix] = true
+
+		for _, other := range prefixes {
+			if special := path.Join(other, specialPath) + "/"; strings.HasPrefix(prefix, special) {
+				return nil, fmt.Errorf("path prefix %q collides with the paths the server uses under %q", prefix, special)
+			}
+		}
+	}
+
+	return prefixes, nil
+}
+
+// requestPrefix returns the path prefix the request was received under,
+// which is the longest of the configured prefixes the path starts with,
+// or the main one if none matches
+func (s *Server) requestPrefix(r *http.Request) string {
+	matched := ""
+	for _, prefix := range append([]string{s.PathPrefix}, s.ExtraPathPrefixes...) {
+		if len(prefix) <= len(matched) {
+			continue
+		}
+
+		if strings.HasPrefix(r.URL.Path, prefix) || r.URL.Path == strings.TrimSuffix(prefix, "/") {
+			matched = prefix
+		}
+	}
+
+	if matched == "" {
+		return s.PathPrefix
+	}
+
+	return matched
+}
@@ -0,0 +1,148 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+	"testing"
+)
+
+func Test_extraPathPrefixes(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "shared file")
+	writeTestFile(t, root, "docs/guide.txt", "guide")
+
+	s := &Server{Path: root, PathPrefix: "/files/", ExtraPathPrefixes: []string{"/download/"}}
+	h := newTestHandler(t, s)
+
+	tests := []struct {
+		name         string
+		path         string
+		wantStatus   int
+		wantBody     string
+		wantLocation string
+	}{
+		{
+			name:       "file under the main prefix",
+			path:       "/files/file.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "shared file",
+		},
+		{
+			name:       "file under the extra prefix",
+			path:       "/download/file.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "shared file",
+		},
+		{
+			name:       "listing under the main prefix links to it",
+			path:       "/files/docs/",
+			wantStatus: http.StatusOK,
+			wantBody:   `href="/files/docs/guide.txt"`,
+		},
+		{
+			name:       "listing under the extra prefix links to it",
+			path:       "/download/docs/",
+			wantStatus: http.StatusOK,
+			wantBody:   `href="/download/docs/guide.txt"`,
+		},
+		{
+			name:       "parent directory uses the matched prefix",
+			path:       "/download/docs/",
+			wantStatus: http.StatusOK,
+			wantBody:   `href="/download/"`,
+		},
+		{
+			name:         "directory redirect keeps the extra prefix",
+			path:         "/download/docs",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/download/docs/",
+		},
+		{
+			name:         "extra prefix without trailing slash",
+			path:         "/download",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/download/",
+		},
+		{
+			name:         "root redirects to the main prefix",
+			path:         "/",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/files/",
+		},
+		{
+			name:       "assets under the extra prefix",
+			path:       path.Join("/download/", specialPath, s.cacheBuster, "assets", "style.css"),
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "health check under the main prefix",
+			path:       "/files/_/health",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "unconfigured prefix",
+			path:       "/other/file.txt",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantBody != "" && !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+
+			if tt.wantLocation != "" && rec.Header().Get("Location") != tt.wantLocation {
+				t.Fatalf("expected location %q, got %q", tt.wantLocation, rec.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func Test_pathPrefixes(t *testing.T) {
+	tests := []struct {
+		name    string
+		main    string
+		extra   []string
+		wantErr bool
+	}{
+		{
+			name:  "distinct prefixes",
+			main:  "/files/",
+			extra: []string{"/download/", "/"},
+		},
+		{
+			name:    "duplicated prefix",
+			main:    "/files/",
+			extra:   []string{"/files/"},
+			wantErr: true,
+		},
+		{
+			name:    "prefix under the server paths",
+			main:    "/",
+			extra:   []string{"/_/files/"},
+			wantErr: true,
+		},
+		{
+			name:    "prefix under the server paths of an extra prefix",
+			main:    "/download/_/files/",
+			extra:   []string{"/download/"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Server{PathPrefix: tt.main, ExtraPathPrefixes: tt.extra}
+			if _, err := s.pathPrefixes(); (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// playlistTitle replaces the line breaks in the titles of playlist entries
+var playlistTitle = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// isPlaylistRequest checks if the directory listing was requested
+// as a playlist
+func (s *Server) isPlaylistRequest(r *http.Request) bool {
+	return s.DirectoryPlaylists && r.URL.Query().Get("format") == "m3u"
+}
+
+// isMediaFile checks if the file is audio or video, based on the
+// content type of its extension
+func (s *Server) isMediaFile(name string) bool {
+	ctype := s.contentTypeByName(name)
+	return strings.HasPrefix(ctype, "audio/") || strings.HasPrefix(ctype, "video/")
+}
+
+// servePlaylist renders the audio and video files in the directory as an
+// M3U playlist, so media players can open the directory directly. Files
+// are listed in the same order as in the directory listing, subdirectories
+// aren't included, and links are absolute, as media players require.
+func (s *Server) servePlaylist(requestedPath string, overlays []string, w http.ResponseWriter, r *http.Request) {
+	if s.DisableDirectoryList {
+		s.httpError(http.StatusNotFound, w, r, "404 not found")
+		return
+	}
+
+	list, err := os.ReadDir(requestedPath)
+	if err != nil {
+		s.printWarning("unable to read directory %q: %s", requestedPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to read directory -- see application logs for more information")
+		return
+	}
+
+	list, _ = s.readOverlays(list, overlays)
+	sort.Sort(foldersFirst(list))
+
+	files := make([]os.FileInfo, 0, len(list))
+	for _, f := range list {
+		if f.IsDir() || s.isHidden(f.Name()) || !s.isMediaFile(f.Name()) {
+			continue
+		}
+
+		fi, err := f.Info()
+		if err != nil {
+			s.printWarning("unable to stat file %q: %s", filepath.Join(requestedPath, f.Name()), err)
+			continue
+		}
+
+		files = append(files, fi)
+	}
+
+	if by, desc := s.listingOrder(r); by != "name" || desc {
+		sortListing(files, by, desc)
+	}
+
+	dirURL := s.baseURL(r) + fileURL(true, s.publicPath(r, r.URL.Path))
+
+	var b bytes.Buffer
+	b.WriteString("#EXTM3U\n")
+	for _, fi := range files {
+		// Line breaks in a title would start a new entry, so they're
+		// replaced, while the URL has them escaped already
+		title := playlistTitle.Replace(strings.TrimSuffix(fi.Name(), filepath.Ext(fi.Name())))
+		fmt.Fprintf(&b, "#EXTINF:-1,%s\n%s\n", title, dirURL+fileURL(false, fi.Name()))
+	}
+
+	// Name the playlist after the directory, or the site for the root
+	name := path.Base(strings.TrimSuffix(s.publicPath(r, r.URL.Path), "/"))
+	if name == "." || name == "/" || name == "" {
+		name = "playlist"
+	}
+
+	w.Header().Set("Content-Type", "audio/x-mpegurl; charset=utf-8")
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": name + ".m3u"}))
+	s.setListingCacheControl(w)
+	w.Write(b.Bytes())
+}
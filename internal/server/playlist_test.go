@@ -0,0 +1,135 @@
+package server
+
+import (
+	"net/http"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_directoryPlaylists(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "music/b-second song.mp3", strings.Repeat("b", 10))
+	writeTestFile(t, root, "music/a-first.flac", strings.Repeat("a", 20))
+	writeTestFile(t, root, "music/c-clip.mp4", "c")
+	writeTestFile(t, root, "music/cover.jpg", "cover")
+	writeTestFile(t, root, "music/notes.txt", "notes")
+	writeTestFile(t, root, "music/live/d-encore.mp3", "d")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		wantStatus int
+		wantType   string
+		wantURLs   []string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			path:       "/music/?format=m3u",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "media files in listing order",
+			server:     &Server{Path: root, DirectoryPlaylists: true},
+			path:       "/music/?format=m3u",
+			wantStatus: http.StatusOK,
+			wantType:   "audio/x-mpegurl",
+			wantURLs:   []string{"http://example.com/music/a-first.flac", "http://example.com/music/b-second%20song.mp3", "http://example.com/music/c-clip.mp4"},
+		},
+		{
+			name:       "requested order",
+			server:     &Server{Path: root, DirectoryPlaylists: true},
+			path:       "/music/?format=m3u&sort=size&order=desc",
+			wantStatus: http.StatusOK,
+			wantType:   "audio/x-mpegurl",
+			wantURLs:   []string{"http://example.com/music/a-first.flac", "http://example.com/music/b-second%20song.mp3", "http://example.com/music/c-clip.mp4"},
+		},
+		{
+			name:       "configured base URL",
+			server:     &Server{Path: root, DirectoryPlaylists: true, BaseURL: "https://media.example.org/"},
+			path:       "/music/?format=m3u&order=desc",
+			wantStatus: http.StatusOK,
+			wantType:   "audio/x-mpegurl",
+			wantURLs:   []string{"https://media.example.org/music/c-clip.mp4", "https://media.example.org/music/b-second%20song.mp3", "https://media.example.org/music/a-first.flac"},
+		},
+		{
+			name:       "directory listing disabled",
+			server:     &Server{Path: root, DirectoryPlaylists: true, DisableDirectoryList: true},
+			path:       "/music/?format=m3u",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantType == "" {
+				if strings.HasPrefix(rec.Body.String(), "#EXTM3U") {
+					t.Fatalf("expected no playlist to be served")
+				}
+				return
+			}
+
+			if ctype := rec.Header().Get("Content-Type"); !strings.HasPrefix(ctype, tt.wantType) {
+				t.Fatalf("expected content type %q, got %q", tt.wantType, ctype)
+			}
+
+			if tt.wantURLs == nil {
+				return
+			}
+
+			body := rec.Body.String()
+			if !strings.HasPrefix(body, "#EXTM3U\n") {
+				t.Fatalf("expected an M3U playlist, got:\n%s", body)
+			}
+
+			var urls []string
+			for _, line := range strings.Split(body, "\n") {
+				if line != "" && !strings.HasPrefix(line, "#") {
+					urls = append(urls, line)
+				}
+			}
+
+			if strings.Join(urls, "\n") != strings.Join(tt.wantURLs, "\n") {
+				t.Errorf("expected playlist entries %v, got %v", tt.wantURLs, urls)
+			}
+
+			if got := rec.Header().Get("Content-Disposition"); got != `attachment; filename=music.m3u` {
+				t.Errorf("expected playlist to be named after the directory, got %q", got)
+			}
+		})
+	}
+}
+
+func Test_playlistTitles(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file names can't contain line breaks on this platform")
+	}
+
+	root := t.TempDir()
+	writeTestFile(t, root, "music/first\nline.mp3", "a")
+	writeTestFile(t, root, "music/second\r\nline.mp3", "b")
+
+	h := newTestHandler(t, &Server{Path: root, DirectoryPlaylists: true})
+
+	rec := doRequest(h, http.MethodGet, "/music/?format=m3u", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	want := "#EXTM3U\n" +
+		"#EXTINF:-1,first line\nhttp://example.com/music/first%0Aline.mp3\n" +
+		"#EXTINF:-1,second line\nhttp://example.com/music/second%0D%0Aline.mp3\n"
+
+	if got := rec.Body.String(); got != want {
+		t.Fatalf("expected playlist:\n%q\ngot:\n%q", want, got)
+	}
+}
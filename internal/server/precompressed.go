@@ -0,0 +1,148 @@
+package server
+
+import (
+	"bufio"
+	"compress/gzip"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// gzipExtension is the extension of files stored compressed with gzip
+const gzipExtension = ".gz"
+
+// findGzipped returns the path to a gzip-compressed copy of the file,
+// stored next to it with the ".gz" extension, if one exists
+func (s *Server) findGzipped(fp string) string {
+	if s.isFiltered(filepath.Base(fp) + gzipExtension) {
+		return ""
+	}
+
+	if info, err := os.Stat(fp + gzipExtension); err == nil && !info.IsDir() {
+		return fp + gzipExtension
+	}
+
+	return ""
+}
+
+// serveGzipped serves a file stored compressed with gzip in place of the
+// missing uncompressed one. Clients accepting gzip get the file as it's
+// stored, with a "Content-Encoding: gzip" header, while the rest get it
+// decompressed on the fly. Either way, the content type is the one of
+// the uncompressed file.
+func (s *Server) serveGzipped(gzPath string, w http.ResponseWriter, r *http.Request) {
+	mw.SetServedPath(r, gzPath)
+
+	f, err := os.Open(gzPath)
+	if err != nil {
+		s.printWarning("unable to open file %q: %s", gzPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to open file -- see application logs for more information")
+		return
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		s.printWarning("unable to stat file %q: %s", gzPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to stat file -- see application logs for more information")
+		return
+	}
+
+	if s.refuseWritable(gzPath, fi, w, r) {
+		return
+	}
+
+	// The response depends on whether the client accepts gzip
+	w.Header().Add("Vary", "Accept-Encoding")
+
+	// The uncompressed size isn't known without reading the whole
+	// file, so the size on disk is used to decide the disposition
+	name := strings.TrimSuffix(fi.Name(), gzipExtension)
+	s.setContentDisposition(name, fi.Size(), w, r)
+
+	if acceptsGzip(r) {
+		if ctype := s.contentTypeByName(name); ctype != "" {
+			if s.NoCharset {
+				ctype = withoutCharset(ctype)
+			}
+
+			w.Header().Set("Content-Type", ctype)
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		http.ServeContent(w, r, "", fi.ModTime(), &contextReader{ctx: r.Context(), ReadSeeker: f})
+		return
+	}
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		s.printWarning("unable to decompress file %q: %s", gzPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to decompress file -- see application logs for more information")
+		return
+	}
+	defer gz.Close()
+
+	// Without a known extension, sniff the decompressed content
+	body := bufio.NewReaderSize(gz, sniffBytes)
+	ctype := s.contentTypeByName(name)
+	if ctype == "" {
+		sample, _ := body.Peek(sniffBytes)
+		ctype = http.DetectContentType(sample)
+	}
+
+	if s.NoCharset {
+		ctype = withoutCharset(ctype)
+	}
+
+	w.Header().Set("Content-Type", ctype)
+
+	// Ranges are only known to be valid once the decompressed size
+	// is, so they need the file to be decompressed at least once
+	if r.Header.Get("Range") != "" {
+		s.serveGzippedRange(gzPath, f, fi, w, r)
+		return
+	}
+
+	// Otherwise, the decompressed size isn't known upfront,
+	// so the file is decompressed while it's sent
+	w.Header().Set("Last-Modified", fi.ModTime().UTC().Format(http.TimeFormat))
+
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil && !fi.ModTime().Truncate(time.Second).After(since) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	if _, err := body.WriteTo(w); err != nil {
+		if err := r.Context().Err(); err != nil {
+			s.printCanceled(r, "stopped serving file %q: %s", gzPath, err)
+			return
+		}
+
+		s.printWarning("unable to decompress file %q: %s", gzPath, err)
+	}
+}
+
+// contentTypeByName returns the content type for the file name,
+// or an empty string if its extension isn't known
+func (s *Server) contentTypeByName(name string) string {
+	if ctype := s.contentTypeForFilename(name); ctype != "" {
+		return ctype
+	}
+
+	return mime.TypeByExtension(filepath.Ext(name))
+}
+
+// acceptsGzip checks if the client listed gzip, or any encoding,
+// among the ones it accepts, without explicitly refusing it
+func acceptsGzip(r *http.Request) bool {
+	return mw.AcceptsEncoding(r.Header.Get("Accept-Encoding"), "gzip")
+}
@@ -0,0 +1,133 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func Test_serveGzipped(t *testing.T) {
+	const contents = "body { color: red; }"
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte(contents))
+	zw.Close()
+
+	root := t.TempDir()
+	writeTestFile(t, root, "style.css.gz", compressed.String())
+	writeTestFile(t, root, "plain.txt", "plain")
+	writeTestFile(t, root, "plain.txt.gz", "not used")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		headers      map[string]string
+		wantStatus   int
+		wantEncoding string
+		wantBody     string
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			path:       "/style.css",
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "decompressed for clients without gzip",
+			server:     &Server{Path: root, ServeGzipped: true},
+			path:       "/style.css",
+			wantStatus: http.StatusOK,
+			wantBody:   contents,
+		},
+		{
+			name:       "decompressed for clients refusing gzip",
+			server:     &Server{Path: root, ServeGzipped: true},
+			path:       "/style.css",
+			headers:    map[string]string{"Accept-Encoding": "gzip;q=0, br"},
+			wantStatus: http.StatusOK,
+			wantBody:   contents,
+		},
+		{
+			name:         "served as is for clients accepting gzip",
+			server:       &Server{Path: root, ServeGzipped: true},
+			path:         "/style.css",
+			headers:      map[string]string{"Accept-Encoding": "gzip, deflate"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "gzip",
+			wantBody:     contents,
+		},
+		{
+			name:         "not compressed twice",
+			server:       &Server{Path: root, ServeGzipped: true, GzipEnabled: true},
+			path:         "/style.css",
+			headers:      map[string]string{"Accept-Encoding": "gzip"},
+			wantStatus:   http.StatusOK,
+			wantEncoding: "gzip",
+			wantBody:     contents,
+		},
+		{
+			name:       "ranges of decompressed files",
+			server:     &Server{Path: root, ServeGzipped: true},
+			path:       "/style.css",
+			headers:    map[string]string{"Range": "bytes=7-11"},
+			wantStatus: http.StatusPartialContent,
+			wantBody:   "color",
+		},
+		{
+			name:       "unsatisfiable ranges of decompressed files",
+			server:     &Server{Path: root, ServeGzipped: true},
+			path:       "/style.css",
+			headers:    map[string]string{"Range": "bytes=100-200"},
+			wantStatus: http.StatusRequestedRangeNotSatisfiable,
+		},
+		{
+			name:       "uncompressed file takes precedence",
+			server:     &Server{Path: root, ServeGzipped: true},
+			path:       "/plain.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "plain",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, tt.headers)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if tt.wantBody == "" {
+				return
+			}
+
+			if got := rec.Header().Get("Content-Encoding"); got != tt.wantEncoding {
+				t.Fatalf("expected content encoding %q, got %q", tt.wantEncoding, got)
+			}
+
+			if tt.path == "/style.css" && !strings.HasPrefix(rec.Header().Get("Content-Type"), "text/css") {
+				t.Errorf("expected content type of the uncompressed file, got %q", rec.Header().Get("Content-Type"))
+			}
+
+			body := rec.Body.Bytes()
+			if tt.wantEncoding == "gzip" {
+				zr, err := gzip.NewReader(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("unable to decompress response: %s", err)
+				}
+
+				body, _ = io.ReadAll(zr)
+			}
+
+			if string(body) != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, body)
+			}
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// fileEtag returns the ETag the file is served with, the SHA-1 checksum
+// of its contents, going through the checksum cache so files aren't hashed
+// again until they change
+func (s *Server) fileEtag(fp string, fi os.FileInfo, r *http.Request) (string, error) {
+	sum, err := s.fileChecksum(fp, fi, "sha1", r)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%q", sum), nil
+}
+
+// etagMatches checks if the ETag is in the list of an "If-Match" header,
+// using the strong comparison "If-Match" requires, so weak ETags never match
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// writePreconditionsMet checks the "If-Match" and "If-Unmodified-Since"
+// headers of a request that would replace or move an existing file, so
+// clients only change it if it's still the version they last saw. When not
+// met, the request is answered and false is returned. If "If-Match" is
+// sent, "If-Unmodified-Since" is ignored, as with any other precondition.
+func (s *Server) writePreconditionsMet(fp string, fi os.FileInfo, w http.ResponseWriter, r *http.Request) bool {
+	ifMatch := r.Header.Get("If-Match")
+	ifUnmodifiedSince := r.Header.Get("If-Unmodified-Since")
+
+	if ifMatch == "" && ifUnmodifiedSince == "" {
+		if s.RequireWritePreconditions {
+			s.httpError(http.StatusPreconditionRequired, w, r, "428 precondition required: send the ETag of %q in an \"If-Match\" header to change it", fi.Name())
+			return false
+		}
+
+		return true
+	}
+
+	if ifMatch != "" {
+		// Directories don't have an ETag of their own, since the one
+		// of their listing changes with how it's rendered
+		if fi.IsDir() {
+			if strings.TrimSpace(ifMatch) == "*" {
+				return true
+			}
+
+			s.httpError(http.StatusPreconditionFailed, w, r, "412 precondition failed: %q didn't match the given ETag", fi.Name())
+			return false
+		}
+
+		etag, err := s.fileEtag(fp, fi, r)
+		if err != nil {
+			if err := r.Context().Err(); err != nil {
+				s.printCanceled(r, "stopped computing the ETag of file %q: %s", fp, err)
+				return false
+			}
+
+			s.printWarning("unable to compute the ETag of file %q: %s", fp, err)
+			s.httpError(http.StatusInternalServerError, w, r, "unable to read file -- see application logs for more information")
+			return false
+		}
+
+		if !etagMatches(ifMatch, etag) {
+			s.httpError(http.StatusPreconditionFailed, w, r, "412 precondition failed: %q was changed, its current ETag is %s", fi.Name(), etag)
+			return false
+		}
+
+		return true
+	}
+
+	// Dates that can't be parsed are ignored, as HTTP requires
+	since, err := http.ParseTime(ifUnmodifiedSince)
+	if err != nil {
+		return true
+	}
+
+	if fi.ModTime().Truncate(time.Second).After(since) {
+		s.httpError(http.StatusPreconditionFailed, w, r, "412 precondition failed: %q was modified since %s", fi.Name(), since.UTC().Format(http.TimeFormat))
+		return false
+	}
+
+	return true
+}
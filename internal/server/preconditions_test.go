@@ -0,0 +1,183 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_writePreconditions(t *testing.T) {
+	modified := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		require    bool
+		compress   bool
+		method     string
+		headers    map[string]string
+		wantStatus int
+		wantWrite  bool
+	}{
+		{
+			name:       "upload without preconditions",
+			method:     http.MethodPost,
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload with a matching etag",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": "current"},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload with the etag of a compressed response",
+			compress:   true,
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": "current"},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload with one of many etags matching",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": `"0000", current`},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload with a mismatching etag",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": `"0000"`},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "upload with a weak etag",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": "W/current"},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "upload with any etag",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": "*"},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload unmodified since",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Unmodified-Since": modified.Format(http.TimeFormat)},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload modified since",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "if-match takes precedence over if-unmodified-since",
+			method:     http.MethodPost,
+			headers:    map[string]string{"If-Match": "current", "If-Unmodified-Since": modified.Add(-time.Hour).Format(http.TimeFormat)},
+			wantStatus: http.StatusSeeOther,
+			wantWrite:  true,
+		},
+		{
+			name:       "upload without preconditions when required",
+			require:    true,
+			method:     http.MethodPost,
+			wantStatus: http.StatusPreconditionRequired,
+		},
+		{
+			name:       "move with a matching etag",
+			method:     methodMove,
+			headers:    map[string]string{"If-Match": "current"},
+			wantStatus: http.StatusCreated,
+			wantWrite:  true,
+		},
+		{
+			name:       "move with a mismatching etag",
+			method:     methodMove,
+			headers:    map[string]string{"If-Match": `"0000"`},
+			wantStatus: http.StatusPreconditionFailed,
+		},
+		{
+			name:       "move without preconditions when required",
+			require:    true,
+			method:     methodMove,
+			wantStatus: http.StatusPreconditionRequired,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			// Large enough for the file to be served compressed
+			original := strings.Repeat("original\n", 512)
+			writeTestFile(t, root, "docs/file.txt", original)
+
+			fp := filepath.Join(root, "docs", "file.txt")
+			if err := os.Chtimes(fp, modified, modified); err != nil {
+				t.Fatalf("unable to change file times: %s", err)
+			}
+
+			h := newTestHandler(t, &Server{Path: root, AllowUpload: true, RequireWritePreconditions: tt.require, GzipEnabled: tt.compress})
+
+			var headers map[string]string
+			if tt.compress {
+				headers = map[string]string{"Accept-Encoding": "gzip"}
+			}
+
+			// The ETag sent back is the one the file is served with
+			served := doRequest(h, http.MethodGet, "/docs/file.txt", headers)
+			etag := served.Header().Get("Etag")
+			if etag == "" {
+				t.Fatalf("expected the file to be served with an ETag")
+			}
+
+			if compressed := served.Header().Get("Content-Encoding") != ""; compressed != tt.compress {
+				t.Fatalf("expected the file to be served compressed to be %v, got %v", tt.compress, compressed)
+			}
+
+			var req *http.Request
+			if tt.method == methodMove {
+				req = httptest.NewRequest(methodMove, "/docs/file.txt", nil)
+				req.Header.Set("Destination", "/docs/moved.txt")
+			} else {
+				req = newUploadRequest(t, "/docs/", map[string]string{"file.txt": "replaced"})
+			}
+
+			for k, v := range tt.headers {
+				switch v {
+				case "current":
+					v = etag
+				case "W/current":
+					v = "W/" + etag
+				case `"0000", current`:
+					v = `"0000", ` + etag
+				}
+
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			contents, err := os.ReadFile(fp)
+			written := err != nil || string(contents) != original
+			if written != tt.wantWrite {
+				t.Fatalf("expected the file to be changed to be %v, got %v", tt.wantWrite, written)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+	"path"
+	"strings"
+)
+
+// publicPrefix returns the path prefix clients see in front of the server,
+// which might differ from the path prefix the server receives when running
+// behind a reverse proxy that adds or strips part of the path. The
+// "X-Forwarded-Prefix" header is only honored when the proxy is trusted.
+func (s *Server) publicPrefix(r *http.Request) string {
+	if s.TrustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-Prefix"); strings.HasPrefix(forwarded, "/") {
+			return cleanPrefix(forwarded)
+		}
+	}
+
+	// The external prefix stands for the main path prefix, while
+	// the extra ones are seen by clients as they are
+	prefix := s.requestPrefix(r)
+	if s.ExternalPrefix != "" && prefix == s.PathPrefix {
+		return s.ExternalPrefix
+	}
+
+	return prefix
+}
+
+// publicPath converts a path received by the server into the path the
+// client sees by swapping the internal path prefix for the public one
+func (s *Server) publicPath(r *http.Request, p string) string {
+	prefix, internal := s.publicPrefix(r), s.requestPrefix(r)
+	if prefix == internal {
+		return p
+	}
+
+	return prefix + strings.TrimPrefix(p, internal)
+}
+
+// cleanPrefix normalizes a path prefix so it always starts and ends with
+// a slash, collapsing any duplicated slashes or dot segments in between
+func cleanPrefix(p string) string {
+	p = path.Clean("/" + p)
+
+	if p == "/" {
+		return p
+	}
+
+	return p + "/"
+}
+
+// baseURL returns the scheme and host clients use to reach the server,
+// used for links that need to be absolute. The configured base URL is
+// used if set, otherwise it's built from the request, honoring the
+// "X-Forwarded-Proto" and "X-Forwarded-Host" headers from trusted proxies.
+func (s *Server) baseURL(r *http.Request) string {
+	if s.BaseURL != "" {
+		return strings.TrimSuffix(s.BaseURL, "/")
+	}
+
+	scheme, host := "http", r.Host
+	if r.TLS != nil {
+		scheme = "https"
+	}
+
+	if s.TrustProxy {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			scheme = proto
+		}
+
+		if forwarded := r.Header.Get("X-Forwarded-Host"); forwarded != "" {
+			host = forwarded
+		}
+	}
+
+	return scheme + "://" + host
+}
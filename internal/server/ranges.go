@@ -0,0 +1,112 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// normalizeRange rewrites the Range header of a request before it's handed
+// to http.ServeContent, which otherwise rejects malformed headers with a 416
+// and answers zero-length suffix ranges like "bytes=-0" with an empty 206.
+// Syntactically invalid headers are dropped, so the whole file is served,
+// and zero-length suffix ranges are removed. When no range is left, the
+// header is replaced by one past the end of the file, so the standard
+// library replies with a 416 and a "Content-Range: bytes */size" header
+// after evaluating any conditional headers as usual.
+func normalizeRange(r *http.Request, size int64) {
+	header := r.Header.Get("Range")
+	if header == "" {
+		return
+	}
+
+	specs, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		r.Header.Del("Range")
+		return
+	}
+
+	var kept []string
+	for _, spec := range strings.Split(specs, ",") {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		start, end, ok := strings.Cut(spec, "-")
+		if !ok {
+			r.Header.Del("Range")
+			return
+		}
+
+		start, end = strings.TrimSpace(start), strings.TrimSpace(end)
+
+		switch {
+		// Suffix ranges, like "-500", need a length
+		case start == "":
+			if !isDigits(end) {
+				r.Header.Del("Range")
+				return
+			}
+
+			// A suffix of zero bytes can never be satisfied
+			if strings.Trim(end, "0") == "" {
+				continue
+			}
+
+		// Open ranges, like "500-"
+		case end == "":
+			if !isDigits(start) {
+				r.Header.Del("Range")
+				return
+			}
+
+		// Closed ranges, like "0-499", can't end before they start
+		default:
+			if !isDigits(start) || !isDigits(end) || compareDigits(start, end) > 0 {
+				r.Header.Del("Range")
+				return
+			}
+		}
+
+		kept = append(kept, start+"-"+end)
+	}
+
+	if len(kept) == 0 {
+		r.Header.Set("Range", fmt.Sprintf("bytes=%d-", size))
+		return
+	}
+
+	r.Header.Set("Range", "bytes="+strings.Join(kept, ","))
+}
+
+// isDigits reports whether s is a non-empty string of ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// compareDigits compares two strings of digits numerically, without
+// parsing them, so positions too large for an int64 are still compared
+func compareDigits(a, b string) int {
+	a = strings.TrimLeft(a, "0")
+	b = strings.TrimLeft(b, "0")
+
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+
+	return strings.Compare(a, b)
+}
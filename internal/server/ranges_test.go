@@ -0,0 +1,91 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_rangeRequests(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello world")
+
+	tests := []struct {
+		name             string
+		rangeHeader      string
+		wantStatus       int
+		wantContentRange string
+		wantBody         string
+	}{
+		{
+			name:             "satisfiable range",
+			rangeHeader:      "bytes=0-4",
+			wantStatus:       http.StatusPartialContent,
+			wantContentRange: "bytes 0-4/11",
+			wantBody:         "hello",
+		},
+		{
+			name:             "suffix range",
+			rangeHeader:      "bytes=-5",
+			wantStatus:       http.StatusPartialContent,
+			wantContentRange: "bytes 6-10/11",
+			wantBody:         "world",
+		},
+		{
+			name:             "start past the end of the file",
+			rangeHeader:      "bytes=9999999-",
+			wantStatus:       http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */11",
+		},
+		{
+			name:             "zero-length suffix",
+			rangeHeader:      "bytes=-0",
+			wantStatus:       http.StatusRequestedRangeNotSatisfiable,
+			wantContentRange: "bytes */11",
+		},
+		{
+			name:             "zero-length suffix alongside a valid range",
+			rangeHeader:      "bytes=-0, 0-4",
+			wantStatus:       http.StatusPartialContent,
+			wantContentRange: "bytes 0-4/11",
+			wantBody:         "hello",
+		},
+		{
+			name:        "malformed range",
+			rangeHeader: "bytes=abc",
+			wantStatus:  http.StatusOK,
+			wantBody:    "hello world",
+		},
+		{
+			name:        "range ending before it starts",
+			rangeHeader: "bytes=5-2",
+			wantStatus:  http.StatusOK,
+			wantBody:    "hello world",
+		},
+		{
+			name:        "unknown unit",
+			rangeHeader: "items=0-4",
+			wantStatus:  http.StatusOK,
+			wantBody:    "hello world",
+		},
+	}
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, "/file.txt", map[string]string{"Range": tt.rangeHeader})
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Content-Range"); got != tt.wantContentRange {
+				t.Errorf("expected Content-Range %q, got %q", tt.wantContentRange, got)
+			}
+
+			if tt.wantBody != "" && rec.Body.String() != tt.wantBody {
+				t.Errorf("expected body %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"bufio"
+	"io"
+	"os"
+)
+
+// bufferedFile reads a file through a buffer, so the file is read from
+// disk in chunks of the configured size regardless of the size of the
+// reads made by the caller. Seeking discards whatever was buffered.
+type bufferedFile struct {
+	f  *os.File
+	br *bufio.Reader
+}
+
+// Read implements io.Reader
+func (b *bufferedFile) Read(p []byte) (int, error) {
+	return b.br.Read(p)
+}
+
+// Seek implements io.Seeker, accounting for the data already
+// read from the file into the buffer but not yet consumed
+func (b *bufferedFile) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekCurrent {
+		offset -= int64(b.br.Buffered())
+	}
+
+	pos, err := b.f.Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+
+	b.br.Reset(b.f)
+	return pos, nil
+}
+
+// fileReader returns the reader used to send the file to the client,
+// which is buffered if a read buffer size is configured. The returned
+// function must be called once the file has been sent, to release the
+// buffer so other requests can reuse it.
+func (s *Server) fileReader(f *os.File) (io.ReadSeeker, func()) {
+	if s.ReadBufferSize <= 0 {
+		return f, func() {}
+	}
+
+	br, _ := s.readBuffers.Get().(*bufio.Reader)
+	if br == nil || br.Size() != s.ReadBufferSize {
+		br = bufio.NewReaderSize(f, s.ReadBufferSize)
+	} else {
+		br.Reset(f)
+	}
+
+	return &bufferedFile{f: f, br: br}, func() {
+		br.Reset(nil)
+		s.readBuffers.Put(br)
+	}
+}
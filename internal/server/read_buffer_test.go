@@ -0,0 +1,95 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_readBufferSize(t *testing.T) {
+	contents := strings.Repeat("0123456789", 1000)
+
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", contents)
+
+	tests := []struct {
+		name        string
+		rangeHeader string
+		wantStatus  int
+		wantBody    string
+	}{
+		{
+			name:       "whole file",
+			wantStatus: http.StatusOK,
+			wantBody:   contents,
+		},
+		{
+			name:        "range within the first buffer",
+			rangeHeader: "bytes=5-14",
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    contents[5:15],
+		},
+		{
+			name:        "range past several buffers",
+			rangeHeader: "bytes=5000-",
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    contents[5000:],
+		},
+		{
+			name:        "suffix range",
+			rangeHeader: "bytes=-25",
+			wantStatus:  http.StatusPartialContent,
+			wantBody:    contents[len(contents)-25:],
+		},
+	}
+
+	for _, size := range []int{0, 16, 4096} {
+		h := newTestHandler(t, &Server{Path: root, ReadBufferSize: size})
+
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s with a %d bytes buffer", tt.name, size), func(t *testing.T) {
+				var headers map[string]string
+				if tt.rangeHeader != "" {
+					headers = map[string]string{"Range": tt.rangeHeader}
+				}
+
+				// Request twice, so pooled buffers are reused
+				for i := 0; i < 2; i++ {
+					rec := doRequest(h, http.MethodGet, "/file.txt", headers)
+					if rec.Code != tt.wantStatus {
+						t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+					}
+
+					if rec.Body.String() != tt.wantBody {
+						t.Fatalf("expected %d bytes of the file, got %d different bytes", len(tt.wantBody), rec.Body.Len())
+					}
+				}
+			})
+		}
+	}
+}
+
+func Benchmark_readBufferSize(b *testing.B) {
+	root := b.TempDir()
+	contents := strings.Repeat("a", 8<<20)
+	writeTestFile(b, root, "large.bin", contents)
+	fp := filepath.Join(root, "large.bin")
+
+	for _, size := range []int{0, 32 << 10, 256 << 10, 1 << 20} {
+		b.Run(fmt.Sprintf("buffer=%d", size), func(b *testing.B) {
+			s := &Server{Path: root, ReadBufferSize: size, LogOutput: io.Discard}
+
+			b.SetBytes(int64(len(contents)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				rec := httptest.NewRecorder()
+				s.serveFile(fp, rec, httptest.NewRequest(http.MethodGet, "/large.bin", nil))
+			}
+		})
+	}
+}
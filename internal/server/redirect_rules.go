@@ -0,0 +1,123 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// debugPrefix is the prefix of log lines only shown at the debug level
+const debugPrefix = "[DEBUG] >>> "
+
+// redirectRule is a redirection configured with the "redirect" setting.
+// Rules whose source ends with an asterisk match every path starting with
+// the rest of it, and the remainder of the path is appended to the target.
+type redirectRule struct {
+	from   string
+	to     string
+	prefix bool
+	status int
+}
+
+// parseRedirectRules parses the redirections, written as the source path,
+// the target and optionally the status code, separated by spaces, such as
+// "/old/* /new/ 302". Without a status code, redirections are permanent.
+func parseRedirectRules(rules []string) ([]redirectRule, error) {
+	parsed := make([]redirectRule, 0, len(rules))
+
+	for _, rule := range rules {
+		fields := strings.Fields(rule)
+		if len(fields) < 2 || len(fields) > 3 {
+			return nil, fmt.Errorf("invalid redirection %q: expected a source, a target and optionally a status code", rule)
+		}
+
+		rr := redirectRule{from: fields[0], to: fields[1], status: http.StatusMovedPermanently}
+
+		if !strings.HasPrefix(rr.from, "/") {
+			return nil, fmt.Errorf("invalid redirection %q: source must start with a forward slash", rule)
+		}
+
+		if rr.from, rr.prefix = strings.CutSuffix(rr.from, "*"); strings.Contains(rr.from, "*") {
+			return nil, fmt.Errorf("invalid redirection %q: an asterisk is only allowed at the end of the source", rule)
+		}
+
+		if len(fields) == 3 {
+			status, err := strconv.Atoi(fields[2])
+			if err != nil || (status != http.StatusMovedPermanently && status != http.StatusFound &&
+				status != http.StatusTemporaryRedirect && status != http.StatusPermanentRedirect) {
+				return nil, fmt.Errorf("invalid redirection %q: status code must be one of 301, 302, 307 or 308", rule)
+			}
+
+			rr.status = status
+		}
+
+		parsed = append(parsed, rr)
+	}
+
+	return parsed, nil
+}
+
+// applyRedirectRules redirects the request if its path, relative to the
+// path prefix, matches one of the configured redirections, the first one
+// matching taking precedence. Prefix redirections keep the rest of the
+// path and the query string. It reports whether the request was handled.
+func (s *Server) applyRedirectRules(requested string, w http.ResponseWriter, r *http.Request) bool {
+	// The requested path is cleaned, which drops the trailing slash
+	// directories are requested with, so it's added back to match
+	if requested != "/" && strings.HasSuffix(r.URL.Path, "/") {
+		requested += "/"
+	}
+
+	for _, rule := range s.redirectRules {
+		target := rule.to
+
+		switch {
+		case rule.prefix && strings.HasPrefix(requested, rule.from):
+			// Avoid doubling the slash between the target and the rest
+			// of the path, which would make it a protocol-relative URL
+			rest := strings.TrimPrefix(requested, rule.from)
+			if strings.HasSuffix(target, "/") {
+				rest = strings.TrimPrefix(rest, "/")
+			}
+
+			// The rest of the path is decoded, so characters like "?" or
+			// "#" in file names are escaped again to stay part of it
+			segments := strings.Split(rest, "/")
+			for i, segment := range segments {
+				segments[i] = url.PathEscape(segment)
+			}
+
+			target += strings.Join(segments, "/")
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+
+		case !rule.prefix && requested == rule.from:
+
+		default:
+			continue
+		}
+
+		// Targets within the server are relative to the path prefix
+		// clients see, while full URLs are used as they are
+		if strings.HasPrefix(target, "/") && !strings.HasPrefix(target, "//") {
+			target = strings.TrimSuffix(s.publicPrefix(r), "/") + target
+		}
+
+		s.printDebug("redirecting %q to %q with status %d", r.URL.Path, target, rule.status)
+		http.Redirect(w, r, target, rule.status)
+		return true
+	}
+
+	return false
+}
+
+func (s *Server) printDebug(format string, args ...interface{}) {
+	if s.LogOutput != nil && s.logLevels.Enabled(mw.LogDebug) {
+		fmt.Fprintf(s.LogOutput, debugPrefix+format+"\n", args...)
+	}
+}
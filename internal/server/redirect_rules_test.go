@@ -0,0 +1,137 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+)
+
+func Test_redirectRules(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "old/page.html", "still on disk")
+
+	tests := []struct {
+		name         string
+		server       *Server
+		path         string
+		wantStatus   int
+		wantLocation string
+	}{
+		{
+			name:         "exact match",
+			server:       &Server{Redirects: []string{"/old/page.html /new/page.html"}},
+			path:         "/old/page.html",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/new/page.html",
+		},
+		{
+			name:         "exact match drops the query",
+			server:       &Server{Redirects: []string{"/old/page.html /new/page.html 302"}},
+			path:         "/old/page.html?ref=home",
+			wantStatus:   http.StatusFound,
+			wantLocation: "/new/page.html",
+		},
+		{
+			name:       "exact match doesn't match nested paths",
+			server:     &Server{Redirects: []string{"/old /new"}},
+			path:       "/old/page.html",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:         "prefix match keeps the rest of the path and the query",
+			server:       &Server{Redirects: []string{"/old/* /new/ 308"}},
+			path:         "/old/nested/page.html?ref=home",
+			wantStatus:   http.StatusPermanentRedirect,
+			wantLocation: "/new/nested/page.html?ref=home",
+		},
+		{
+			name:         "prefix match keeps the trailing slash",
+			server:       &Server{Redirects: []string{"/old/* /new/"}},
+			path:         "/old/nested/",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/new/nested/",
+		},
+		{
+			name:         "prefix match without a slash doesn't produce protocol-relative URLs",
+			server:       &Server{Redirects: []string{"/old* /"}},
+			path:         "/old/example.com",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/example.com",
+		},
+		{
+			name:         "prefix match escapes the rest of the path",
+			server:       &Server{Redirects: []string{"/old/* /new/"}},
+			path:         "/old/what%3Fis%23this%20page.html?ref=home",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/new/what%3Fis%23this%20page.html?ref=home",
+		},
+		{
+			name:         "first match wins",
+			server:       &Server{Redirects: []string{"/old/page.html /first", "/old/* /second/"}},
+			path:         "/old/page.html",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/first",
+		},
+		{
+			name:         "absolute target",
+			server:       &Server{Redirects: []string{"/old/* https://example.com/archive/"}},
+			path:         "/old/page.html",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "https://example.com/archive/page.html",
+		},
+		{
+			name:         "relative to the path prefix",
+			server:       &Server{PathPrefix: "/docs/", Redirects: []string{"/old/* /new/"}},
+			path:         "/docs/old/page.html",
+			wantStatus:   http.StatusMovedPermanently,
+			wantLocation: "/docs/new/page.html",
+		},
+		{
+			name:       "no match",
+			server:     &Server{Redirects: []string{"/other/* /new/"}},
+			path:       "/old/page.html",
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("Location"); got != tt.wantLocation {
+				t.Fatalf("expected location %q, got %q", tt.wantLocation, got)
+			}
+		})
+	}
+}
+
+func Test_parseRedirectRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    string
+		wantErr bool
+	}{
+		{name: "exact", rule: "/old /new"},
+		{name: "prefix with status", rule: "/old/* /new/ 307"},
+		{name: "missing target", rule: "/old", wantErr: true},
+		{name: "too many fields", rule: "/old /new 301 extra", wantErr: true},
+		{name: "relative source", rule: "old /new", wantErr: true},
+		{name: "asterisk in the middle", rule: "/old/*/page /new", wantErr: true},
+		{name: "unsupported status", rule: "/old /new 200", wantErr: true},
+		{name: "status not a number", rule: "/old /new permanent", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseRedirectRules([]string{tt.rule})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error: %v, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
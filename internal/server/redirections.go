@@ -33,7 +33,7 @@ func (s *Server) LoadRedirectionsIfEnabled() error {
 	// Parse the redirections file
 	engine, err := redirects.New(string(b))
 	if err != nil {
-		return fmt.Errorf("redirection error on file %q: %w", s.getPathToRedirectionsFile(), err)
+		return withKind(ErrInvalidRedirections, fmt.Errorf("redirection error on file %q: %w", s.getPathToRedirectionsFile(), err))
 	}
 
 	// Set the redirections engine
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"os"
+)
+
+// resolvedFileKey is the context key holding the file or directory
+// a request was resolved to
+type resolvedFileKey struct{}
+
+// ResolvedFile is the file or directory on disk a request was resolved to,
+// after applying aliases, overlays and redirections
+type ResolvedFile struct {
+	// Path is the absolute path of the file or directory
+	Path string
+
+	// Info describes the file or directory, as returned by os.Stat
+	Info os.FileInfo
+}
+
+// ResolvedFileFromContext returns the file or directory the request was
+// resolved to, which is only available to the middlewares configured in
+// the server's Middlewares field, and to the handlers they wrap
+func ResolvedFileFromContext(ctx context.Context) (ResolvedFile, bool) {
+	rf, ok := ctx.Value(resolvedFileKey{}).(ResolvedFile)
+	return rf, ok
+}
+
+// serveResolved stores the file or directory the request was resolved to
+// in the request context, then serves it through the configured
+// middlewares, so they can act on it without having to stat it again
+func (s *Server) serveResolved(fp string, fi os.FileInfo, w http.ResponseWriter, r *http.Request, serve http.HandlerFunc) {
+	r = r.WithContext(context.WithValue(r.Context(), resolvedFileKey{}, ResolvedFile{Path: fp, Info: fi}))
+
+	var h http.Handler = serve
+	for i := len(s.Middlewares) - 1; i >= 0; i-- {
+		h = s.Middlewares[i](h)
+	}
+
+	h.ServeHTTP(w, r)
+}
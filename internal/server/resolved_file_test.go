@@ -0,0 +1,110 @@
+package server
+
+import (
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func Test_resolvedFileMiddlewares(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+	writeTestFile(t, root, "private/secret.txt", "secret")
+	writeTestFile(t, root, "public/readme.txt", "readme")
+
+	var order []string
+	s := &Server{
+		Path: root,
+		Middlewares: []func(http.Handler) http.Handler{
+			func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					order = append(order, "first")
+
+					rf, ok := ResolvedFileFromContext(r.Context())
+					if !ok {
+						t.Errorf("expected the resolved file in the context of %q", r.URL.Path)
+						next.ServeHTTP(w, r)
+						return
+					}
+
+					w.Header().Set("X-Resolved-Path", rf.Path)
+					w.Header().Set("X-Resolved-Dir", strconv.FormatBool(rf.Info.IsDir()))
+					next.ServeHTTP(w, r)
+				})
+			},
+			func(next http.Handler) http.Handler {
+				return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					order = append(order, "second")
+
+					if rf, _ := ResolvedFileFromContext(r.Context()); rf.Info.IsDir() && rf.Info.Name() == "private" {
+						http.Error(w, "forbidden", http.StatusForbidden)
+						return
+					}
+
+					next.ServeHTTP(w, r)
+				})
+			},
+		},
+	}
+
+	h := newTestHandler(t, s)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantPath   string
+		wantDir    string
+	}{
+		{
+			name:       "file",
+			path:       "/file.txt",
+			wantStatus: http.StatusOK,
+			wantPath:   filepath.Join(root, "file.txt"),
+			wantDir:    "false",
+		},
+		{
+			name:       "directory",
+			path:       "/public/",
+			wantStatus: http.StatusOK,
+			wantPath:   filepath.Join(root, "public"),
+			wantDir:    "true",
+		},
+		{
+			name:       "directory denied by a middleware",
+			path:       "/private/",
+			wantStatus: http.StatusForbidden,
+			wantPath:   filepath.Join(root, "private"),
+			wantDir:    "true",
+		},
+		{
+			name:       "missing files skip the middlewares",
+			path:       "/missing.txt",
+			wantStatus: http.StatusNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			order = nil
+
+			rec := doRequest(h, http.MethodGet, tt.path, nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if got := rec.Header().Get("X-Resolved-Path"); got != tt.wantPath {
+				t.Errorf("expected resolved path %q, got %q", tt.wantPath, got)
+			}
+
+			if got := rec.Header().Get("X-Resolved-Dir"); got != tt.wantDir {
+				t.Errorf("expected resolved directory %q, got %q", tt.wantDir, got)
+			}
+
+			if tt.wantPath != "" && (len(order) != 2 || order[0] != "first" || order[1] != "second") {
+				t.Errorf("expected the middlewares to run in order, got %v", order)
+			}
+		})
+	}
+}
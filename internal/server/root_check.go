@@ -0,0 +1,37 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"os"
+)
+
+// isRootAvailable checks whether the served directory still exists
+// and is a directory. Since the directory could be removed or unmounted
+// while the server runs and then come back, it logs every time the
+// availability changes rather than on every request.
+func (s *Server) isRootAvailable() bool {
+	info, err := os.Stat(s.Path)
+	if err == nil && !info.IsDir() {
+		err = errors.New("path is not a directory")
+	}
+	available := err == nil
+
+	// Swap returns the previous state, so only log on changes
+	if wasMissing := s.rootMissing.Swap(!available); wasMissing == available {
+		if available {
+			s.printWarning("served directory %q is available again, resuming normal operation", s.Path)
+		} else {
+			s.printWarning("served directory %q is no longer available, responding with 503 until it's back: %s", s.Path, err)
+		}
+	}
+
+	return available
+}
+
+// rootUnavailableError renders the error page shown while the served
+// directory is missing
+func (s *Server) rootUnavailableError(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Retry-After", "30")
+	s.httpError(http.StatusServiceUnavailable, w, r, "503 service unavailable: the served directory is currently unavailable")
+}
@@ -2,28 +2,114 @@ package server
 
 import (
 	"fmt"
-	"log"
 	"net/http"
 	"path"
+	"slices"
 	"strings"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
-	"github.com/klauspost/compress/gzhttp"
+	"github.com/patrickdappollonio/http-server/internal/metrics"
 	"github.com/patrickdappollonio/http-server/internal/mw"
 )
 
-func (s *Server) router() http.Handler {
+func (s *Server) router() (http.Handler, error) {
 	r := chi.NewRouter()
 
-	// Allow logging all request to our custom logger
-	r.Use(mw.LogRequest(s.LogOutput, logFormat, "token"))
+	// Identify the server on every response, errors included,
+	// unless the header was configured to be suppressed
+	if s.ServerHeader != "" {
+		r.Use(mw.ServerHeader(s.ServerHeader))
+	}
+
+	// Allow logging all request to our custom logger, optionally
+	// including the filesystem path served for the request
+	format := logFormat
+	if s.LogServedPath {
+		format += logFormatServedPath
+	}
+	levels, err := mw.NewLogLevels(s.LogLevel, s.LogStatusLevels)
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure log levels: %w", err)
+	}
+	s.logLevels = levels
+	r.Use(mw.LogRequest(s.LogOutput, format, s.LogServedPathOnErrors, levels, "token", mw.ShareParam))
+
+	// Keep track of request metrics if enabled
+	var registry *metrics.Registry
+	if s.MetricsEnabled {
+		registry = s.newMetrics()
+		r.Use(registry.Middleware)
+	}
+
+	// Bound how many expensive operations run at once
+	s.expensiveOps = newOpLimiter(s.MaxExpensiveOps, s.ExpensiveOpsWait)
+
+	// Bound the memory cached listings, images and file details can take
+	s.listingCache.maxBytes = maxListingCacheBytes
+	s.imageCache.maxBytes = maxImageCacheBytes
+	s.checksumCache.maxEntries = maxChecksumCacheEntries
+	s.gzipSizeCache.maxEntries = maxGzipSizeCacheEntries
 
 	// Recover the request in case of a panic
 	r.Use(middleware.Recoverer)
 
-	// Only allow specific methods in all our requests
-	r.Use(mw.VerbsAllowed("GET", "HEAD"))
+	// Slow down every response, for testing clients only
+	if s.ResponseDelay > 0 {
+		r.Use(mw.Delay(s.ResponseDelay))
+	}
+
+	// Restrict access to specific network ranges if needed
+	if len(s.AllowCIDRs) > 0 || len(s.DenyCIDRs) > 0 {
+		allowed, err := mw.ParseCIDRs(s.AllowCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse allowed network ranges: %w", err)
+		}
+
+		denied, err := mw.ParseCIDRs(s.DenyCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse denied network ranges: %w", err)
+		}
+
+		r.Use(mw.IPFilter(allowed, denied, s.TrustProxy))
+	}
+
+	// Parse the custom directory listing layouts, if any
+	if err := s.loadLayouts(); err != nil {
+		return nil, err
+	}
+
+	// Hide the real content behind the splash page if configured,
+	// except for clients previewing the site and the server's own
+	// endpoints, like the health check
+	if err := s.loadSplashTemplate(); err != nil {
+		return nil, err
+	}
+
+	if s.splashTemplate != nil {
+		allowed, err := mw.ParseCIDRs(s.SplashAllowCIDRs)
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse splash page network ranges: %w", err)
+		}
+
+		r.Use(mw.Splash(allowed, s.TrustProxy, func(r *http.Request) bool {
+			return s.isSpecialPath(r) || s.isACMEChallenge(r)
+		}, http.HandlerFunc(s.serveSplash)))
+	}
+
+	// Only allow specific methods in all our requests, except for the
+	// endpoint warming caches, which takes the paths to warm with POST
+	r.Use(mw.VerbsAllowed(s.isWarmRequest, s.allowedMethods()...))
+
+	// Answer OPTIONS requests with what the server supports, if enabled
+	if s.AllowOptions {
+		r.Use(mw.Options(s.allowedMethods(), s.optionsHeaders()))
+	}
+
+	// Limit the size of request bodies, regardless of the method
+	if s.MaxRequestBodyBytes > 0 {
+		r.Use(mw.MaxBodySize(s.MaxRequestBodyBytes))
+	}
 
 	// Disable access to specific files
 	r.Use(mw.DisableAccessToFile(s.isFiltered, http.StatusNotFound))
@@ -47,17 +133,28 @@ func (s *Server) router() http.Handler {
 		)
 	}
 
-	// Enable etag support
-	r.Use(mw.Etag(!s.ETagDisabled))
+	// Combine the authentication methods, letting share links
+	// skip them for the shared path if enabled
+	auth := func(next http.Handler) http.Handler { return basicAuth(jwtAuth(next)) }
+	if s.ShareSecret != "" {
+		auth = mw.ShareAuth(s.printWarning, s.ShareSecret, auth)
+	}
 
-	// Check if gzip is enabled
+	// Check if compression is enabled
 	if s.GzipEnabled {
-		r.Use(func(h http.Handler) http.Handler {
-			log.Printf("Gzip enabled for all requests")
-			return gzhttp.GzipHandler(h)
-		})
+		compress, err := mw.Compress(s.compressionAlgorithms(), s.NoCompressUserAgents)
+		if err != nil {
+			return nil, fmt.Errorf("unable to configure compression: %w", err)
+		}
+
+		r.Use(compress)
 	}
 
+	// Enable etag support. It runs before compression, so the ETag is
+	// computed from the uncompressed content and matches the one the
+	// upload preconditions compare against, whatever the encoding.
+	r.Use(mw.Etag(!s.ETagDisabled))
+
 	// Enable CORS if needed
 	if s.CorsEnabled {
 		r.Use(mw.EnableCORS)
@@ -69,6 +166,21 @@ func (s *Server) router() http.Handler {
 		r.Use(s.redirects.Middleware(s.LogOutput))
 	}
 
+	// Parse the redirections configured as settings, which are
+	// applied by the handler before looking for files on disk
+	s.redirectRules, err = parseRedirectRules(s.Redirects)
+	if err != nil {
+		return nil, withKind(ErrInvalidRedirections, fmt.Errorf("unable to configure redirections: %w", err))
+	}
+
+	// Redirect paths with consecutive slashes to their canonical
+	// form, so every file and directory has a single URL
+	if s.RedirectDuplicateSlashes {
+		r.Use(mw.RedirectDuplicateSlashes(http.StatusMovedPermanently, func(r *http.Request) bool {
+			return s.isSpecialPath(r) || s.isACMEChallenge(r)
+		}))
+	}
+
 	// Check if the request is against a URL ending on a known
 	// index file, and if so, redirect to the directory
 	r.Use(mw.RedirectIndexes(http.StatusMovedPermanently))
@@ -78,35 +190,68 @@ func (s *Server) router() http.Handler {
 		s.PathPrefix = "/"
 	}
 
-	// Create a route based on a path prefix, prevalidated that
-	// the prefix is a valid prefix, and including any potential
-	// authentication method
-	routePrefix := path.Join(s.PathPrefix, "*")
-	r.With(basicAuth, jwtAuth).HandleFunc(routePrefix, s.showOrRender)
+	// The served directory is available under the main path
+	// prefix and, if configured, under each extra one
+	prefixes, err := s.pathPrefixes()
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure path prefixes: %w", err)
+	}
+
+	for _, prefix := range prefixes {
+		// Create a route based on a path prefix, prevalidated that
+		// the prefix is a valid prefix, and including any potential
+		// authentication method
+		routePrefix := path.Join(prefix, "*")
+		r.With(auth).HandleFunc(routePrefix, s.showOrRender)
+
+		// Create a route for static assets, including
+		// the cache buster randomized string so we can
+		// force reload the assets on each execution
+		assetsPrefix := path.Join(prefix, specialPath, s.cacheBuster)
+		r.HandleFunc(path.Join(assetsPrefix, "assets", "*"), s.serveAssets(assetsPrefix))
+	}
 
-	// Create a route for static assets, including
-	// the cache buster randomized string so we can
-	// force reload the assets on each execution
-	assetsPrefix := path.Join(s.PathPrefix, specialPath, s.cacheBuster)
-	r.HandleFunc(path.Join(assetsPrefix, "assets", "*"), s.serveAssets(assetsPrefix))
+	// Serve ACME challenges from their own directory if configured,
+	// without authentication, so certificates can be renewed
+	if s.ACMEChallengeDir != "" {
+		r.HandleFunc(acmeChallengePath+"*", s.serveACMEChallenge)
+	}
 
 	// Create a health check endpoint
 	r.HandleFunc(path.Join(s.PathPrefix, specialPath, "health"), s.healthCheck)
 
+	// Create a metrics endpoint if enabled
+	if registry != nil {
+		r.Handle(path.Join(s.PathPrefix, specialPath, "metrics"), registry)
+	}
+
+	// Create a debug endpoint if enabled, protected by the
+	// same authentication as the served files
+	if s.DebugEndpoints {
+		r.With(auth).HandleFunc(path.Join(s.PathPrefix, specialPath, "debug"), s.debugStats)
+		r.With(auth).HandleFunc(s.warmEndpoint(), s.warmCaches)
+	}
+
 	// Handle special path prefix cases
-	if s.PathPrefix != "/" {
+	if !slices.Contains(prefixes, "/") {
 		// If the path prefix is not the root of the server, then we
 		// can preemptively redirect users to the appropriate destination
 		// so they don't see a not found error
 		r.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
-			http.Redirect(w, r, s.PathPrefix, http.StatusFound)
+			http.Redirect(w, r, s.publicPrefix(r), http.StatusFound)
 		})
+	}
+
+	for _, prefix := range prefixes {
+		if prefix == "/" {
+			continue
+		}
 
 		// Redirect path prefix without trailing slash to a canonical location
-		r.HandleFunc(strings.TrimSuffix(s.PathPrefix, "/"), func(w http.ResponseWriter, r *http.Request) {
-			http.Redirect(w, r, s.PathPrefix, http.StatusMovedPermanently)
+		r.HandleFunc(strings.TrimSuffix(prefix, "/"), func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, s.publicPrefix(r), http.StatusMovedPermanently)
 		})
 	}
 
-	return r
+	return r, nil
 }
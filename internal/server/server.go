@@ -3,7 +3,13 @@ package server
 import (
 	"html/template"
 	"io"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/patrickdappollonio/http-server/internal/mw"
 	"github.com/patrickdappollonio/http-server/internal/redirects"
 )
 
@@ -12,14 +18,89 @@ const repositoryURL = "https://github.com/patrickdappollonio/http-server/"
 // Server is an HTTP server with optional directory listing enabled
 type Server struct {
 	// Core settings
-	Port                 int    `flagName:"port" validate:"required,min=1,max=65535"`
-	Path                 string `flagName:"path" validate:"required,dir"`
-	PathPrefix           string `flagName:"pathprefix" validate:"omitempty,ispathprefix"`
-	PageTitle            string `flagName:"title" validate:"omitempty,max=100"`
-	BannerMarkdown       string `flagName:"banner" validate:"omitempty,max=1000"`
-	cachedBannerMarkdown string
-	LogOutput            io.Writer
-	DisableDirectoryList bool
+	Port                     int    `flagName:"port" validate:"required_without=Addr,omitempty,min=1,max=65535"`
+	Addr                     string `flagName:"addr" validate:"omitempty,hostname_port"`
+	Listener                 net.Listener
+	SocketActivation         bool
+	MaxConnections           int               `flagName:"max-connections" validate:"min=0"`
+	ReadHeaderTimeout        time.Duration     `flagName:"read-header-timeout" validate:"min=0"`
+	MaxPathDepth             int               `flagName:"max-path-depth" validate:"min=0"`
+	BackslashPaths           string            `flagName:"backslash-paths" validate:"omitempty,oneof=reject slash literal"`
+	MaxRequestBodyBytes      int64             `flagName:"max-request-body-bytes" validate:"min=0"`
+	ReadBufferSize           int               `flagName:"read-buffer-size" validate:"min=0"`
+	Path                     string            `flagName:"path" validate:"required,dir"`
+	Roots                    []string          `flagName:"overlay" validate:"dive,dir"`
+	Aliases                  map[string]string `flagName:"alias" validate:"dive,keys,ispathprefix,endkeys,dir"`
+	ACMEChallengeDir         string            `flagName:"acme-challenge-dir" validate:"omitempty,dir"`
+	PathPrefix               string            `flagName:"pathprefix" validate:"omitempty,ispathprefix"`
+	ExtraPathPrefixes        []string          `flagName:"extra-pathprefix" validate:"dive,ispathprefix"`
+	PageTitle                string            `flagName:"title" validate:"omitempty,max=100"`
+	BannerMarkdown           string            `flagName:"banner" validate:"omitempty,max=1000"`
+	cachedBannerMarkdown     string
+	LogOutput                io.Writer
+	DisableDirectoryList     bool
+	IndexTemplating          bool
+	RootDocument             string
+	StreamListing            bool
+	ListingCacheTTL          time.Duration `flagName:"listing-cache-ttl" validate:"min=0"`
+	MaxListingBytes          int64         `flagName:"max-listing-bytes" validate:"min=0"`
+	ListingRenderTimeout     time.Duration `flagName:"listing-render-timeout" validate:"min=0"`
+	ListingCacheControl      string
+	BareListing              bool
+	CharsetSniffBytes        int               `flagName:"charset-sniff-bytes" validate:"min=0"`
+	CharsetConfidence        int               `flagName:"charset-confidence" validate:"min=0,max=100"`
+	ContentTypes             map[string]string `flagName:"content-type" validate:"dive,keys,required,endkeys,required"`
+	NoCharset                bool
+	StripBOM                 bool
+	RefuseWorldWritable      bool
+	TryExtensions            []string `flagName:"try-extensions" validate:"dive,startswith=."`
+	ForceDownloadExtensions  []string `flagName:"force-download-extensions" validate:"dive,startswith=."`
+	ForceInlineExtensions    []string `flagName:"force-inline-extensions" validate:"dive,startswith=."`
+	MaxInlineBytes           int64    `flagName:"max-inline-bytes" validate:"min=0"`
+	DefaultSort              string   `flagName:"default-sort" validate:"omitempty,oneof=name size modtime"`
+	DefaultOrder             string   `flagName:"default-order" validate:"omitempty,oneof=asc desc"`
+	CaseInsensitive          bool
+	RedirectDuplicateSlashes bool
+	ServeGzipped             bool
+	GzipPreviews             bool
+	CodeViewer               bool
+	ErrorTemplate            string            `flagName:"error-template" validate:"omitempty,file"`
+	Layouts                  map[string]string `flagName:"layout" validate:"dive,keys,required,endkeys,file"`
+	LayoutPaths              map[string]string `flagName:"layout-path" validate:"dive,keys,required,endkeys,required"`
+	StrictTemplates          bool              `flagName:"strict-templates"`
+	ListColumns              []string          `flagName:"list-columns" validate:"omitempty,listcolumns"`
+	ShowMode                 bool
+	ShowChecksums            bool
+	ShowSymlinkTargets       bool
+	HideDotfiles             bool
+	GroupDotfiles            bool
+	IndexJSON                bool
+	IndexJSONPageSize        int `flagName:"index-json-page-size" validate:"min=0"`
+	ImageConversion          bool
+	ImageCacheTTL            time.Duration `flagName:"image-cache-ttl" validate:"min=0"`
+	DirectoryFeeds           bool
+	FeedMaxItems             int `flagName:"feed-max-items" validate:"min=0"`
+	DirectoryPlaylists       bool
+	MaxExpensiveOps          int           `flagName:"max-expensive-ops" validate:"min=0"`
+	ExpensiveOpsWait         time.Duration `flagName:"expensive-ops-wait" validate:"min=0"`
+	Checksums                bool
+	ChecksumHeaders          bool
+	ChecksumTrailers         bool
+	Watch                    bool
+	MaxWatchers              int `flagName:"max-watchers" validate:"min=0"`
+
+	// Access log settings
+	LogServedPath         bool
+	LogServedPathOnErrors bool
+	LogLevel              string        `flagName:"log-level" validate:"omitempty,oneof=debug info warn error"`
+	LogStatusLevels       []string      `flagName:"log-status-levels"`
+	LogFlushInterval      time.Duration `flagName:"log-flush-interval" validate:"min=0"`
+
+	// Upload settings
+	AllowUpload               bool
+	MaxUploadSize             int64 `flagName:"max-upload-size" validate:"min=0"`
+	MaxUploadFiles            int   `flagName:"max-upload-files" validate:"min=0"`
+	RequireWritePreconditions bool
 
 	// Basic auth settings
 	Username string `flagName:"username" validate:"omitempty,alphanum,excluded_with=JWTSigningKey"`
@@ -27,6 +108,7 @@ type Server struct {
 
 	// Boolean specific settings
 	CorsEnabled        bool
+	AllowOptions       bool
 	HideLinks          bool
 	ETagDisabled       bool
 	GzipEnabled        bool
@@ -34,26 +116,105 @@ type Server struct {
 	DisableMarkdown    bool
 	MarkdownBeforeDir  bool
 
+	// Compression settings
+	NoCompressUserAgents  []string
+	CompressionAlgorithms []string `flagName:"compression-algorithms" validate:"dive,oneof=zstd gzip"`
+
+	// ServerHeader is the value of the "Server" header sent with every
+	// response, or empty to not send the header at all
+	ServerHeader string
+
+	// Health check settings
+	ExtendedHealthCheck bool
+
+	// HealthBody and HealthContentType are the body and content type the
+	// health check responds with when it isn't extended
+	HealthBody        string `flagName:"health-body"`
+	HealthContentType string `flagName:"health-content-type"`
+
+	// HealthResponse, when set, is called on every extended health check
+	// request, and the fields it returns are added to the JSON response
+	HealthResponse func() map[string]any
+
+	// Middlewares, when set, wrap the handler serving each file or
+	// directory listing, in order, once the request was resolved to
+	// it, which they can find with ResolvedFileFromContext
+	Middlewares []func(http.Handler) http.Handler
+
+	// Reverse proxy settings
+	ExternalPrefix      string `flagName:"external-prefix" validate:"omitempty,ispathprefix"`
+	BaseURL             string `flagName:"base-url" validate:"omitempty,http_url"`
+	AccelRedirect       string `flagName:"accel-redirect" validate:"omitempty,oneof=nginx sendfile"`
+	AccelRedirectPrefix string `flagName:"accel-redirect-prefix" validate:"omitempty,ispathprefix"`
+	TrustProxy          bool
+
+	// Network access settings
+	AllowCIDRs []string `flagName:"allow-cidr" validate:"dive,cidr"`
+	DenyCIDRs  []string `flagName:"deny-cidr" validate:"dive,cidr"`
+
+	// Splash page settings
+	SplashTemplate   string   `flagName:"splash-template" validate:"omitempty,file"`
+	SplashAllowCIDRs []string `flagName:"splash-allow-cidr" validate:"dive,cidr"`
+
+	// Metrics settings
+	MetricsEnabled bool
+
+	// Debug settings
+	DebugEndpoints bool
+	ResponseDelay  time.Duration `flagName:"chaos-response-delay" validate:"min=0"`
+
 	// Redirection handling
 	DisableRedirects bool
+	Redirects        []string `flagName:"redirect"`
 	redirects        *redirects.Engine
+	redirectRules    []redirectRule
 
 	// JWT Specific settings
 	JWTSigningKey    string `flagName:"jwt-key" validate:"omitempty,excluded_with=Username,excluded_with=Password"`
 	ValidateTimedJWT bool
 
+	// Share link settings
+	ShareSecret string
+	ShareExpiry time.Duration `flagName:"share-expiry" validate:"min=0"`
+
 	// Viper config settings
 	ConfigFilePrefix string
 
 	// Internal fields
 	cacheBuster       string
 	templates         *template.Template
+	errorTemplate     *template.Template
+	splashTemplate    *template.Template
+	layouts           map[string]*template.Template
 	version           string
+	startedAt         time.Time
+	diskUsage         diskUsageCache
+	healthChanges     healthChanges
+	logLevels         *mw.LogLevels
+	listingCache      listingCache
+	readBuffers       sync.Pool
+	imageCache        listingCache
+	checksumCache     listingCache
+	gzipSizeCache     listingCache
+	listingChecksums  checksumWorkers
+	rootMissing       atomic.Bool
+	activeWatchers    atomic.Int64
+	expensiveOps      *opLimiter
 	forbiddenPrefixes []string
 	forbiddenSuffixes []string
 	forbiddenMatches  []string
 }
 
+// compressionAlgorithms returns the algorithms responses are compressed
+// with, in order of preference, which is gzip alone unless configured
+func (s *Server) compressionAlgorithms() []string {
+	if len(s.CompressionAlgorithms) == 0 {
+		return []string{"gzip"}
+	}
+
+	return s.CompressionAlgorithms
+}
+
 // IsBasicAuthEnabled returns true if the server has been configured with
 // a username and password
 func (s *Server) IsBasicAuthEnabled() bool {
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/patrickdappollonio/http-server/internal/mw"
+)
+
+// ShareLink returns a link granting access to the given path, relative to
+// the served directory, until the share expiry elapses, without the
+// configured authentication. Paths ending with a slash share the whole
+// directory. The link is absolute when a base URL is configured.
+func (s *Server) ShareLink(sharedPath string) (string, error) {
+	if s.ShareSecret == "" {
+		return "", fmt.Errorf("a share secret is required to generate share links")
+	}
+
+	if s.ShareExpiry <= 0 {
+		return "", fmt.Errorf("share expiry must be a positive duration, got %s", s.ShareExpiry)
+	}
+
+	prefix := s.PathPrefix
+	if prefix == "" {
+		prefix = "/"
+	}
+
+	// Keep the trailing slash, since it's what tells
+	// directories apart from files
+	rel := strings.TrimPrefix(path.Clean("/"+sharedPath), "/")
+	if rel != "" && strings.HasSuffix(sharedPath, "/") {
+		rel += "/"
+	}
+
+	// The token is signed for the path the server receives, while
+	// the link uses the path clients see
+	token := mw.SignShare(s.ShareSecret, prefix+rel, time.Now().Add(s.ShareExpiry))
+
+	public := prefix
+	if s.ExternalPrefix != "" {
+		public = s.ExternalPrefix
+	}
+
+	link := (&url.URL{Path: public + rel}).EscapedPath() + "?" + url.Values{mw.ShareParam: {token}}.Encode()
+	return strings.TrimSuffix(s.BaseURL, "/") + link, nil
+}
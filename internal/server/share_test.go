@@ -0,0 +1,150 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_shareLink(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "shared/report.txt", "shared report")
+	writeTestFile(t, root, "private/secret.txt", "secret")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		shared     string
+		wantLink   string
+		wantErr    bool
+		requested  string
+		wantStatus int
+	}{
+		{
+			name:       "shared file",
+			server:     &Server{ShareExpiry: time.Hour},
+			shared:     "shared/report.txt",
+			wantLink:   "/shared/report.txt",
+			requested:  "/shared/report.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "shared directory",
+			server:     &Server{ShareExpiry: time.Hour},
+			shared:     "shared/",
+			wantLink:   "/shared/",
+			requested:  "/shared/",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "link for another path",
+			server:     &Server{ShareExpiry: time.Hour},
+			shared:     "shared/report.txt",
+			wantLink:   "/shared/report.txt",
+			requested:  "/private/secret.txt",
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "absolute link with a path prefix",
+			server:     &Server{ShareExpiry: time.Hour, PathPrefix: "/files/", BaseURL: "https://files.example.com"},
+			shared:     "shared/report.txt",
+			wantLink:   "https://files.example.com/files/shared/report.txt",
+			requested:  "/files/shared/report.txt",
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:    "without expiry",
+			server:  &Server{},
+			shared:  "shared/report.txt",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			tt.server.Username = "admin"
+			tt.server.Password = "password"
+			tt.server.ShareSecret = "secret-used-to-sign-share-tokens"
+
+			link, err := tt.server.ShareLink(tt.shared)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got link %q", link)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			path, query, _ := strings.Cut(link, "?")
+			if path != tt.wantLink {
+				t.Fatalf("expected link to %q, got %q", tt.wantLink, link)
+			}
+
+			h := newTestHandler(t, tt.server)
+
+			if rec := doRequest(h, http.MethodGet, tt.requested+"?"+query, nil); rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			// Without the token, the configured authentication applies
+			if rec := doRequest(h, http.MethodGet, tt.requested, nil); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected status %d without the token, got %d", http.StatusUnauthorized, rec.Code)
+			}
+		})
+	}
+}
+
+func Test_shareLinkIsReadOnly(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "shared/report.txt", "shared report")
+
+	s := &Server{
+		Path:        root,
+		Username:    "admin",
+		Password:    "password",
+		AllowUpload: true,
+		ShareSecret: "secret-used-to-sign-share-tokens",
+		ShareExpiry: time.Hour,
+	}
+
+	link, err := s.ShareLink("shared/")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	h := newTestHandler(t, s)
+
+	// Opening the link stores the token in a cookie
+	rec := doRequest(h, http.MethodGet, link, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	cookies := rec.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatalf("expected the share cookie to be set")
+	}
+
+	req := httptest.NewRequest(methodMove, "/shared/report.txt", nil)
+	req.Header.Set("Destination", "/moved.txt")
+	req.AddCookie(cookies[0])
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d", http.StatusUnauthorized, rec.Code)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "shared", "report.txt")); err != nil {
+		t.Fatalf("expected the shared file to stay in place: %s", err)
+	}
+}
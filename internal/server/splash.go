@@ -0,0 +1,61 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// loadSplashTemplate parses the splash page template, if one is configured
+func (s *Server) loadSplashTemplate() error {
+	if s.SplashTemplate == "" {
+		return nil
+	}
+
+	b, err := os.ReadFile(s.SplashTemplate)
+	if err != nil {
+		return fmt.Errorf("unable to read splash template %q: %w", s.SplashTemplate, err)
+	}
+
+	tpl, err := template.New("splash").Parse(string(b))
+	if err != nil {
+		return withKind(ErrTemplateParse, fmt.Errorf("unable to parse splash template %q: %w", s.SplashTemplate, err))
+	}
+
+	s.splashTemplate = tpl
+	return nil
+}
+
+// serveSplash renders the splash page, with a 200 status code since the
+// site is up, only hidden, for every path. It's never cached, so visitors
+// see the real site as soon as the splash page is taken down.
+func (s *Server) serveSplash(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	err := s.splashTemplate.Execute(&buf, map[string]any{
+		"PageTitle": s.PageTitle,
+		"Path":      r.URL.Path,
+	})
+	if err != nil {
+		s.printWarning("unable to render splash template: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to render page -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	if r.Method != http.MethodHead {
+		w.Write(buf.Bytes())
+	}
+}
+
+// isSpecialPath checks if the request is for one of the paths
+// used by the server itself, like the health check or assets
+func (s *Server) isSpecialPath(r *http.Request) bool {
+	return strings.HasPrefix(r.URL.Path, path.Join(s.requestPrefix(r), specialPath)+"/")
+}
@@ -0,0 +1,116 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_splashPage(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "index.html", "real site")
+	writeTestFile(t, root, "docs/guide.txt", "real guide")
+
+	tplDir := t.TempDir()
+	writeTestFile(t, tplDir, "splash.html", "<h1>{{ .PageTitle }} is coming soon</h1>")
+	splash := filepath.Join(tplDir, "splash.html")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		path       string
+		remoteAddr string
+		forwarded  string
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name:       "splash page at the root",
+			server:     &Server{SplashTemplate: splash, PageTitle: "Example"},
+			path:       "/",
+			wantStatus: http.StatusOK,
+			wantBody:   "Example is coming soon",
+		},
+		{
+			name:       "splash page for any path",
+			server:     &Server{SplashTemplate: splash},
+			path:       "/docs/guide.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "coming soon",
+		},
+		{
+			name:       "splash page for missing paths",
+			server:     &Server{SplashTemplate: splash},
+			path:       "/missing.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "coming soon",
+		},
+		{
+			name:       "real content for allowed clients",
+			server:     &Server{SplashTemplate: splash, SplashAllowCIDRs: []string{"10.0.0.0/8"}},
+			path:       "/docs/guide.txt",
+			remoteAddr: "10.1.2.3:1234",
+			wantStatus: http.StatusOK,
+			wantBody:   "real guide",
+		},
+		{
+			name:       "splash page for clients outside the allowed ranges",
+			server:     &Server{SplashTemplate: splash, SplashAllowCIDRs: []string{"10.0.0.0/8"}},
+			path:       "/docs/guide.txt",
+			remoteAddr: "192.0.2.1:1234",
+			wantStatus: http.StatusOK,
+			wantBody:   "coming soon",
+		},
+		{
+			name:       "allowed client behind a trusted proxy",
+			server:     &Server{SplashTemplate: splash, SplashAllowCIDRs: []string{"10.0.0.0/8"}, TrustProxy: true},
+			path:       "/docs/guide.txt",
+			remoteAddr: "192.0.2.1:1234",
+			forwarded:  "10.1.2.3",
+			wantStatus: http.StatusOK,
+			wantBody:   "real guide",
+		},
+		{
+			name:       "health check isn't hidden",
+			server:     &Server{SplashTemplate: splash},
+			path:       "/_/health",
+			wantStatus: http.StatusOK,
+			wantBody:   "OK",
+		},
+		{
+			name:       "real content without a splash page",
+			server:     &Server{},
+			path:       "/docs/guide.txt",
+			wantStatus: http.StatusOK,
+			wantBody:   "real guide",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.remoteAddr != "" {
+				req.RemoteAddr = tt.remoteAddr
+			}
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+
+			if !strings.Contains(rec.Body.String(), tt.wantBody) {
+				t.Fatalf("expected body to contain %q, got %q", tt.wantBody, rec.Body.String())
+			}
+		})
+	}
+}
@@ -1,7 +1,13 @@
 package server
 
 import (
+	"cmp"
 	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/patrickdappollonio/http-server/internal/utils"
 )
 
 const startupPrefix = " >"
@@ -9,25 +15,173 @@ const startupPrefix = " >"
 func (s *Server) PrintStartup() {
 	fmt.Fprintln(s.LogOutput, "SETUP:")
 
-	fmt.Fprintln(s.LogOutput, startupPrefix, "Configured to use port:", s.Port)
+	if s.SocketActivation {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Socket activation enabled: using sockets passed by systemd when available")
+	}
+
+	switch {
+	case s.Listener != nil:
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Configured to use the provided listener on:", s.Listener.Addr())
+	case s.Addr != "":
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Configured to use address:", s.Addr)
+	default:
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Configured to use port:", s.Port)
+	}
 	fmt.Fprintln(s.LogOutput, startupPrefix, "Serving path:", s.Path)
 
+	if len(s.Roots) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Overlays, from lowest to highest precedence:", strings.Join(s.Roots, ", "))
+	}
+
+	if len(s.Aliases) > 0 {
+		prefixes := make([]string, 0, len(s.Aliases))
+		for prefix := range s.Aliases {
+			prefixes = append(prefixes, prefix)
+		}
+		sort.Strings(prefixes)
+
+		for _, prefix := range prefixes {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Serving", prefix, "from:", s.Aliases[prefix])
+		}
+	}
+
+	if s.LogFlushInterval > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Log output buffered, written out at least every:", s.LogFlushInterval)
+	}
+
+	if s.ACMEChallengeDir != "" {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "ACME challenges served without authentication from:", s.ACMEChallengeDir)
+	}
+
+	if s.MaxConnections > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Maximum concurrent connections:", s.MaxConnections)
+	}
+
+	if s.ReadHeaderTimeout > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Request headers timeout:", s.ReadHeaderTimeout)
+	}
+
 	if s.PathPrefix != "" && s.PathPrefix != "/" {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "Path prefix:", s.PathPrefix)
 	}
 
+	if len(s.ExtraPathPrefixes) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Also serving under path prefixes:", strings.Join(s.ExtraPathPrefixes, ", "))
+	}
+
+	if s.ExternalPrefix != "" {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "External path prefix used for generated links:", s.ExternalPrefix)
+	}
+
+	if s.TrustProxy {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Trusting reverse proxy headers: \"X-Forwarded-Prefix\" and \"X-Forwarded-For\"")
+	}
+
+	if len(s.AllowCIDRs) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Access allowed only from network ranges:", strings.Join(s.AllowCIDRs, ", "))
+	}
+
+	if len(s.DenyCIDRs) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Access denied from network ranges:", strings.Join(s.DenyCIDRs, ", "))
+	}
+
+	if s.SplashTemplate != "" {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Splash page served for every path, hiding the real content:", s.SplashTemplate)
+
+		if len(s.SplashAllowCIDRs) > 0 {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Real content shown to network ranges:", strings.Join(s.SplashAllowCIDRs, ", "))
+		}
+	}
+
+	if len(s.TryExtensions) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Extensions tried for extensionless URLs:", strings.Join(s.TryExtensions, ", "))
+	}
+
 	if s.DisableDirectoryList {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "Directory listing disabled (including markdown rendering)")
 	}
 
+	if s.StreamListing {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Directory listing streaming enabled (entries are unsorted and markdown is not rendered)")
+	}
+
+	if s.MaxExpensiveOps > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Maximum concurrent expensive operations:", s.MaxExpensiveOps, "(waiting up to", s.ExpensiveOpsWait, "for a slot)")
+	}
+
+	if s.Watch {
+		if s.MaxWatchers > 0 {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Directory changes sent as server-sent events with \"?watch=1\", up to watchers:", s.MaxWatchers)
+		} else {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Directory changes sent as server-sent events with \"?watch=1\"")
+		}
+	}
+
+	if s.ListingCacheTTL > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Directory listing cache enabled, rendered listings are kept for:", s.ListingCacheTTL)
+	}
+
+	switch s.AccelRedirect {
+	case accelRedirectNginx:
+		fmt.Fprintln(s.LogOutput, startupPrefix, "File transfers delegated to nginx at internal location:", s.AccelRedirectPrefix)
+	case accelRedirectSendfile:
+		fmt.Fprintln(s.LogOutput, startupPrefix, "File transfers delegated to the reverse proxy with the X-Sendfile header")
+	}
+
+	if s.ResponseDelay > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Response delay enabled for testing, every response is delayed by:", s.ResponseDelay)
+	}
+
+	if s.MaxRequestBodyBytes > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Request bodies limited to:", utils.Humansize(s.MaxRequestBodyBytes))
+	}
+
+	if s.AllowUpload {
+		if s.MaxUploadSize > 0 {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Uploads enabled, up to:", utils.Humansize(s.MaxUploadSize))
+		} else {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Uploads enabled, without a size limit")
+		}
+
+		if s.RequireWritePreconditions {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Replacing or moving files requires an \"If-Match\" or \"If-Unmodified-Since\" header")
+		}
+	}
+
 	if s.GzipEnabled {
-		fmt.Fprintln(s.LogOutput, startupPrefix, "Gzip compression enabled for supported content types")
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Compression enabled for supported content types, in order of preference:", strings.Join(s.compressionAlgorithms(), ", "))
+
+		if len(s.NoCompressUserAgents) > 0 {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Compression skipped for user agents matching:", strings.Join(s.NoCompressUserAgents, ", "))
+		}
 	}
 
 	if s.ETagDisabled {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "ETag headers disabled")
 	}
 
+	if s.ExtendedHealthCheck {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Extended health check enabled: responding with version and uptime in JSON")
+	} else if s.HealthBody != "" && s.HealthBody != defaultHealthBody {
+		fmt.Fprintf(s.LogOutput, "%s Health check responding with a custom body of type %q\n", startupPrefix, s.HealthContentType)
+	}
+
+	if s.LogServedPath {
+		if s.LogServedPathOnErrors {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Access log includes the served filesystem path, including for error responses")
+		} else {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Access log includes the served filesystem path for successful responses")
+		}
+	}
+
+	if s.MetricsEnabled {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Metrics enabled at:", path.Join(s.PathPrefix, specialPath, "metrics"))
+	}
+
+	if s.DebugEndpoints {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Debug statistics enabled at:", path.Join(s.PathPrefix, specialPath, "debug"))
+	}
+
 	if s.CorsEnabled {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "CORS headers enabled: adding \"Access-Control-Allow-Origin=*\" header")
 	}
@@ -44,6 +198,26 @@ func (s *Server) PrintStartup() {
 		}
 	}
 
+	if s.ShareSecret != "" {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Share links enabled: requests with a valid share token skip authentication for the shared path")
+	}
+
+	if s.ErrorTemplate != "" {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Custom error template:", s.ErrorTemplate)
+	}
+
+	if len(s.Layouts) > 0 {
+		names := make([]string, 0, len(s.Layouts))
+		for name := range s.Layouts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			fmt.Fprintln(s.LogOutput, startupPrefix, "Directory listing layout", name, "from:", s.Layouts[name])
+		}
+	}
+
 	if s.PageTitle != "" {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "Custom page title:", s.PageTitle)
 	}
@@ -52,6 +226,31 @@ func (s *Server) PrintStartup() {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "Consider helping the project here:", repositoryURL)
 	}
 
+	if s.BareListing {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Directory listings rendered as plain lists of links")
+	}
+
+	if (s.DefaultSort != "" && s.DefaultSort != "name") || s.DefaultOrder == "desc" {
+		order := "ascending"
+		if s.DefaultOrder == "desc" {
+			order = "descending"
+		}
+
+		fmt.Fprintf(s.LogOutput, "%s Directory listings sorted by %s, in %s order\n", startupPrefix, cmp.Or(s.DefaultSort, "name"), order)
+	}
+
+	if s.HideDotfiles {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Dotfiles hidden from directory listings, but still accessible by URL")
+	}
+
+	if s.ShowMode {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "File permissions shown in directory listings")
+	}
+
+	if s.ShowChecksums {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "SHA-256 checksums shown in directory listings, computed in the background")
+	}
+
 	if s.DisableCacheBuster {
 		fmt.Fprintln(s.LogOutput, startupPrefix, "Cache busting for static assets disabled")
 	}
@@ -72,6 +271,11 @@ func (s *Server) PrintStartup() {
 		}
 	}
 
+	if len(s.Redirects) > 0 {
+		fmt.Fprintln(s.LogOutput, startupPrefix, "Redirections configured as settings:", len(s.Redirects))
+	}
+
+	s.printEffectiveConfig()
 	s.printWarnings()
 }
 
@@ -79,4 +283,13 @@ func (s *Server) printWarnings() {
 	if s.JWTSigningKey != "" && len(s.JWTSigningKey) < 32 {
 		s.printWarning("JWT key is less than 32 characters. It can be brute forced easily.")
 	}
+
+	if s.ShareSecret != "" && len(s.ShareSecret) < 32 {
+		s.printWarning("Share secret is less than 32 characters. It can be brute forced easily.")
+	}
+
+	// Uploads are bound by both limits, so a bigger upload limit has no effect
+	if s.AllowUpload && s.MaxRequestBodyBytes > 0 && (s.MaxUploadSize == 0 || s.MaxUploadSize > s.MaxRequestBodyBytes) {
+		s.printWarning("Uploads are limited to %s by the maximum request body size.", utils.Humansize(s.MaxRequestBodyBytes))
+	}
 }
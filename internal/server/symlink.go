@@ -0,0 +1,71 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// symlinkTarget describes where a symbolic link shown
+// in the directory listing points to
+type symlinkTarget struct {
+	// Target is the destination as stored in the link
+	Target string
+
+	// InRoot is set when the destination is inside the served directory
+	InRoot bool
+
+	// Broken is set when the destination doesn't exist
+	Broken bool
+}
+
+// symlinkTarget returns where the file in the given directory points to,
+// or nil if it isn't a symbolic link or showing link targets is disabled.
+// Links are fully resolved to tell whether they stay within the served
+// directory, so a chain of links pointing elsewhere is reported as such.
+func (s *Server) symlinkTarget(dir string, fi os.FileInfo) *symlinkTarget {
+	if !s.ShowSymlinkTargets || fi.Mode()&os.ModeSymlink == 0 {
+		return nil
+	}
+
+	p := filepath.Join(fileDir(dir, fi), fi.Name())
+
+	target, err := os.Readlink(p)
+	if err != nil {
+		s.printWarning("unable to read symbolic link %q: %s", p, err)
+		return nil
+	}
+
+	resolved, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return &symlinkTarget{Target: target, Broken: true}
+	}
+
+	// Links into any of the overlays stay within the served content too
+	inRoot := false
+	for _, root := range s.roots() {
+		if isWithinDir(root, resolved) {
+			inRoot = true
+			break
+		}
+	}
+
+	return &symlinkTarget{Target: target, InRoot: inRoot}
+}
+
+// isWithinDir checks if the resolved path is the directory or one of its
+// descendants. The directory could be a symbolic link too, so it's
+// compared by its resolved location.
+func isWithinDir(dir, resolved string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+
+	if r, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = r
+	}
+
+	rel, err := filepath.Rel(dir, resolved)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
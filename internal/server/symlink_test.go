@@ -0,0 +1,82 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_symlinkTargets(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	writeTestFile(t, root, "file.txt", "inside")
+	writeTestFile(t, outside, "secret.txt", "outside")
+
+	links := map[string]string{
+		"inside-link":  "file.txt",
+		"outside-link": filepath.Join(outside, "secret.txt"),
+		"broken-link":  "missing.txt",
+	}
+
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("unable to create symbolic links: %s", err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		server     *Server
+		want       []string
+		wantNoneOf []string
+	}{
+		{
+			name:       "targets hidden by default",
+			server:     &Server{Path: root},
+			wantNoneOf: []string{`class="symlink`},
+		},
+		{
+			name:   "targets shown when enabled",
+			server: &Server{Path: root, ShowSymlinkTargets: true},
+			want: []string{
+				`<span class="symlink" title="Link within the served directory">&rarr; file.txt</span>`,
+				`<span class="symlink external" title="Link outside the served directory">&rarr; ` + filepath.Join(outside, "secret.txt") + `</span>`,
+				`<span class="symlink broken" title="Broken link">&rarr; missing.txt</span>`,
+			},
+		},
+		{
+			name:   "targets shown in streamed listings",
+			server: &Server{Path: root, ShowSymlinkTargets: true, StreamListing: true},
+			want: []string{
+				`<span class="symlink" title="Link within the served directory">&rarr; file.txt</span>`,
+				`<span class="symlink broken" title="Broken link">&rarr; missing.txt</span>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			body := rec.Body.String()
+			for _, s := range tt.want {
+				if !strings.Contains(body, s) {
+					t.Errorf("expected listing to contain %q", s)
+				}
+			}
+
+			for _, s := range tt.wantNoneOf {
+				if strings.Contains(body, s) {
+					t.Errorf("expected listing not to contain %q", s)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd
+// when using socket activation, after stdin, stdout and stderr
+const listenFDsStart = 3
+
+// listenFDCount returns the amount of file descriptors passed by systemd
+// to this process, following the "sd_listen_fds" protocol: the sockets are
+// only meant for this process if "LISTEN_PID" matches its process ID
+func listenFDCount() int {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return 0
+	}
+
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || count < 0 {
+		return 0
+	}
+
+	return count
+}
+
+// activationListeners returns the listeners inherited from systemd through
+// socket activation, or none if the process wasn't started that way. The
+// environment variables are cleared so child processes don't inherit them.
+func activationListeners() ([]net.Listener, error) {
+	count := listenFDCount()
+	if count == 0 {
+		return nil, nil
+	}
+
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFDsStart; fd < listenFDsStart+count; fd++ {
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+
+		// FileListener duplicates the descriptor, so the original
+		// file can be closed right away
+		l, err := net.FileListener(f)
+		f.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("unable to use socket-activated file descriptor %d: %w", fd, err)
+		}
+
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// sdNotify sends a state change to systemd, such as "READY=1", if the
+// process is running as a notify-type service. It returns false if there's
+// no notification socket to send the state to.
+func sdNotify(state string) (bool, error) {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return false, nil
+	}
+
+	// Sockets starting with "@" live in the abstract namespace
+	if socket[0] == '@' {
+		socket = "\x00" + socket[1:]
+	}
+
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return false, fmt.Errorf("unable to connect to systemd notification socket: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte(state)); err != nil {
+		return false, fmt.Errorf("unable to send %q to systemd: %w", state, err)
+	}
+
+	return true, nil
+}
+
+// notifySystemd sends a state change to systemd, logging any failure
+// since the server can keep running without it
+func (s *Server) notifySystemd(state string) {
+	if _, err := sdNotify(state); err != nil {
+		s.printWarning("%s", err)
+	}
+}
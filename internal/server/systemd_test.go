@@ -0,0 +1,89 @@
+package server
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+)
+
+func Test_listenFDCount(t *testing.T) {
+	pid := strconv.Itoa(os.Getpid())
+
+	tests := []struct {
+		name      string
+		listenPID string
+		listenFDs string
+		want      int
+	}{
+		{
+			name:      "sockets passed to this process",
+			listenPID: pid,
+			listenFDs: "2",
+			want:      2,
+		},
+		{
+			name:      "sockets passed to another process",
+			listenPID: "1",
+			listenFDs: "2",
+			want:      0,
+		},
+		{
+			name: "not socket activated",
+			want: 0,
+		},
+		{
+			name:      "invalid amount of sockets",
+			listenPID: pid,
+			listenFDs: "two",
+			want:      0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.listenPID)
+			t.Setenv("LISTEN_FDS", tt.listenFDs)
+
+			if got := listenFDCount(); got != tt.want {
+				t.Errorf("expected %d file descriptors, got %d", tt.want, got)
+			}
+		})
+	}
+}
+
+func Test_sdNotify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("unix datagram sockets are not supported on windows")
+	}
+
+	t.Setenv("NOTIFY_SOCKET", "")
+	if sent, err := sdNotify("READY=1"); sent || err != nil {
+		t.Fatalf("expected no notification without a socket, got sent=%v err=%v", sent, err)
+	}
+
+	socket := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("unable to create notification socket: %s", err)
+	}
+	defer conn.Close()
+
+	t.Setenv("NOTIFY_SOCKET", socket)
+	sent, err := sdNotify("READY=1")
+	if !sent || err != nil {
+		t.Fatalf("expected notification to be sent, got sent=%v err=%v", sent, err)
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatalf("unable to read notification: %s", err)
+	}
+
+	if got := string(buf[:n]); got != "READY=1" {
+		t.Errorf("expected notification %q, got %q", "READY=1", got)
+	}
+}
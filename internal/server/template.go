@@ -4,8 +4,11 @@ import (
 	"embed"
 	"fmt"
 	"html/template"
+	"io/fs"
+	"net/url"
 	"path"
 	"reflect"
+	"strings"
 	"time"
 
 	"github.com/patrickdappollonio/http-server/internal/utils"
@@ -16,31 +19,39 @@ import (
 //go:embed templates/*
 var walkTemplatesFS embed.FS
 
+// listingTemplatesFS is where the directory listing templates are parsed
+// from. It's a variable so templates failing to parse can be tested.
+var listingTemplatesFS fs.FS = walkTemplatesFS
+
 // generateTemplates generates the templates used to render the directory listing
 func (s *Server) generateTemplates() (*template.Template, error) {
 	tplfuncs := template.FuncMap{
-		"assetpath":      s.assetpath,
-		"rfc1123":        rfc1123,
-		"prettytime":     prettytime,
-		"humansize":      utils.Humansize,
-		"canonicalURL":   canonicalURL,
-		"getIconForFile": getIconForFile,
-		"unsafeHTML":     func(s string) template.HTML { return template.HTML(s) },
-		"default":        dfault,
-		"serverVersion":  func() string { return s.version },
-		"bannerMessage":  s.generateBannerMarkdown,
+		"assetpath":       s.assetpath,
+		"rfc1123":         rfc1123,
+		"prettytime":      prettytime,
+		"humansize":       utils.Humansize,
+		"fileURL":         fileURL,
+		"getIconForFile":  getIconForFile,
+		"fileOwner":       fileOwner,
+		"symlinkTarget":   s.symlinkTarget,
+		"listingChecksum": s.listingChecksum,
+		"unsafeHTML":      func(s string) template.HTML { return template.HTML(s) },
+		"default":         dfault,
+		"serverVersion":   func() string { return s.version },
+		"bannerMessage":   s.generateBannerMarkdown,
+		"dict":            dict,
 	}
 
-	wtfs, err := template.New("").Funcs(tplfuncs).ParseFS(walkTemplatesFS, "templates/*")
+	wtfs, err := template.New("").Funcs(tplfuncs).ParseFS(listingTemplatesFS, "templates/*")
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse internal templates: this is likely a development error: %w", err)
+		return nil, withKind(ErrTemplateParse, fmt.Errorf("unable to parse internal templates: this is likely a development error: %w", err))
 	}
 
 	return wtfs, nil
 }
 
-func (s *Server) assetpath(p string) string {
-	return path.Join(s.PathPrefix, specialPath, s.cacheBuster, "assets", p)
+func (s *Server) assetpath(prefix, p string) string {
+	return path.Join(prefix, specialPath, s.cacheBuster, "assets", p)
 }
 
 func rfc1123(t time.Time) string {
@@ -51,16 +62,44 @@ func prettytime(t time.Time) string {
 	return t.Format("Jan 2, 2006 3:04pm MST")
 }
 
-func canonicalURL(isDir bool, p ...string) string {
-	s := path.Join(p...)
+// fileURL joins the path segments into a URL path, escaping each of them
+// so file names with characters such as "#", "?" or "%" link back to the
+// file instead of being read as part of the URL. Directories always end
+// with a slash.
+func fileURL(isDir bool, p ...string) string {
+	segments := strings.Split(path.Join(p...), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	s := strings.Join(segments, "/")
 
-	if isDir {
+	if isDir && !strings.HasSuffix(s, "/") {
 		s = s + "/"
 	}
 
 	return s
 }
 
+// dict builds a map from a list of alternating keys and values, so
+// templates can pass more than one value to a nested template.
+func dict(pairs ...interface{}) (map[string]interface{}, error) {
+	if len(pairs)%2 != 0 {
+		return nil, fmt.Errorf("dict requires an even number of arguments, got %d", len(pairs))
+	}
+
+	m := make(map[string]interface{}, len(pairs)/2)
+	for i := 0; i < len(pairs); i += 2 {
+		key, ok := pairs[i].(string)
+		if !ok {
+			return nil, fmt.Errorf("dict keys must be strings, got %T", pairs[i])
+		}
+		m[key] = pairs[i+1]
+	}
+
+	return m, nil
+}
+
 // dfault returns the first non-empty value.
 func dfault(d interface{}, given ...interface{}) interface{} {
 	if empty(given) || empty(given[0]) {
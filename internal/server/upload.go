@@ -0,0 +1,319 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// uploadTempPrefix is the prefix of the temporary files uploads are staged
+// into before being moved into place. It's filtered so partial uploads are
+// never listed nor served.
+const uploadTempPrefix = ".http-server-upload-"
+
+// stagedUpload is an uploaded file written to a temporary location,
+// waiting to be moved to its final destination
+type stagedUpload struct {
+	tempPath    string
+	destination string
+
+	// replacedPath is where the file previously at the
+	// destination is set aside while the upload is moved
+	replacedPath string
+}
+
+// allowedMethods returns the HTTP methods the server accepts, which are
+// also the ones advertised in the "Allow" header
+func (s *Server) allowedMethods() []string {
+	methods := []string{http.MethodGet, http.MethodHead}
+
+	if s.AllowUpload {
+		methods = append(methods, http.MethodPost, methodMkcol, methodMove)
+	}
+
+	if s.AllowOptions {
+		methods = append(methods, http.MethodOptions)
+	}
+
+	return methods
+}
+
+// uploadForm renders the page with the form used to upload
+// files into the current directory
+func (s *Server) uploadForm(w http.ResponseWriter, r *http.Request) {
+	if s.templates == nil {
+		s.httpError(http.StatusServiceUnavailable, w, r, "503 service unavailable: the upload form can't be rendered -- see application logs for more information")
+		return
+	}
+
+	prefix := s.publicPrefix(r)
+
+	content := map[string]any{
+		"DirectoryRootPath": prefix,
+		"PageTitle":         s.pageTitle(r),
+		"CurrentPath":       s.publicPath(r, r.URL.Path),
+		"HideLinks":         s.HideLinks,
+		"Columns":           s.listColumns(),
+		"MaxUploadSize":     s.MaxUploadSize,
+		"MaxUploadFiles":    s.MaxUploadFiles,
+	}
+
+	if err := s.templates.ExecuteTemplate(w, "upload.tmpl", content); err != nil {
+		s.printWarning("unable to render upload form: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to render upload form -- see application logs for more information")
+	}
+}
+
+// upload saves the files sent through a "multipart/form-data" request into
+// the given directory. Every file is staged into a temporary file first and
+// only moved into place once all of them were received, so a failed upload
+// never leaves partial files behind.
+func (s *Server) upload(dir string, w http.ResponseWriter, r *http.Request) {
+	// Browsers allow submitting forms to other sites, so reject
+	// uploads coming from pages that aren't served by this server
+	if !s.isSameOrigin(r) {
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden: uploads from other sites are not allowed")
+		return
+	}
+
+	// Make sure the destination is within the served directory,
+	// or the directory of the alias it's under
+	if _, ok := s.servedDir(dir); !ok {
+		s.printWarning("attempted to upload files outside the served directory: %s", dir)
+		s.httpError(http.StatusForbidden, w, r, "403 forbidden")
+		return
+	}
+
+	if s.MaxUploadSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, s.MaxUploadSize)
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: uploads must be sent as \"multipart/form-data\"")
+		return
+	}
+
+	// Remove any staged file that wasn't moved into place
+	var staged []stagedUpload
+	defer func() {
+		for _, upload := range staged {
+			os.Remove(upload.tempPath)
+		}
+	}()
+
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			s.uploadError(w, r, err)
+			return
+		}
+
+		// Skip form fields that aren't files
+		if part.FileName() == "" {
+			part.Close()
+			continue
+		}
+
+		// Files are counted as they arrive, so a request with too many
+		// of them is rejected before the rest are even read
+		if s.MaxUploadFiles > 0 && len(staged) >= s.MaxUploadFiles {
+			part.Close()
+			s.httpError(http.StatusRequestEntityTooLarge, w, r, "413 request entity too large: uploads can't have more than %d files", s.MaxUploadFiles)
+			return
+		}
+
+		name, ok := plainFileName(part.FileName())
+		if !ok || s.isFiltered(name) {
+			part.Close()
+			s.httpError(http.StatusBadRequest, w, r, "400 bad request: invalid file name %q", part.FileName())
+			return
+		}
+
+		destination := filepath.Join(dir, name)
+		if info, err := os.Stat(destination); err == nil {
+			if info.IsDir() {
+				part.Close()
+				s.httpError(http.StatusConflict, w, r, "409 conflict: a directory named %q already exists", name)
+				return
+			}
+
+			// Only replace the file if it's still the version the client saw
+			if !s.writePreconditionsMet(destination, info, w, r) {
+				part.Close()
+				return
+			}
+		}
+
+		tmp, err := os.CreateTemp(dir, uploadTempPrefix+"*")
+		if err != nil {
+			part.Close()
+			s.printWarning("unable to create temporary file for upload in %q: %s", dir, err)
+			s.httpError(http.StatusInternalServerError, w, r, "unable to save uploaded file -- see application logs for more information")
+			return
+		}
+
+		staged = append(staged, stagedUpload{tempPath: tmp.Name(), destination: destination})
+
+		_, err = io.Copy(tmp, part)
+		part.Close()
+
+		if closeErr := tmp.Close(); err == nil {
+			err = closeErr
+		}
+
+		if err != nil {
+			s.uploadError(w, r, err)
+			return
+		}
+	}
+
+	if len(staged) == 0 {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: no files were uploaded")
+		return
+	}
+
+	// Move all the files into place now that they were all received
+	if err := commitUploads(staged); err != nil {
+		s.printWarning("unable to move uploaded files into %q: %s", dir, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to save uploaded file -- see application logs for more information")
+		return
+	}
+
+	for _, upload := range staged {
+		fmt.Fprintf(s.LogOutput, "Uploaded file saved to %q\n", upload.destination)
+	}
+
+	// Send the user back to the directory listing
+	http.Redirect(w, r, fileURL(true, s.publicPath(r, r.URL.Path)), http.StatusSeeOther)
+}
+
+// renameFile moves a file, and is replaced in tests to simulate failures
+var renameFile = os.Rename
+
+// commitUploads moves the staged files to their destinations. Files being
+// replaced are set aside first, so if any of the moves fails, the ones
+// already done are undone and the replaced files restored, leaving the
+// directory as it was before the upload.
+func commitUploads(staged []stagedUpload) error {
+	var done []stagedUpload
+
+	// Undo the moves in reverse, so files uploaded more than
+	// once under the same name end up as they were
+	rollback := func() {
+		for i := len(done) - 1; i >= 0; i-- {
+			renameFile(done[i].destination, done[i].tempPath)
+
+			if done[i].replacedPath != "" {
+				renameFile(done[i].replacedPath, done[i].destination)
+			}
+		}
+	}
+
+	for _, upload := range staged {
+		if _, err := os.Lstat(upload.destination); err == nil {
+			upload.replacedPath = upload.tempPath + ".replaced"
+
+			if err := renameFile(upload.destination, upload.replacedPath); err != nil {
+				rollback()
+				return err
+			}
+		}
+
+		if err := renameFile(upload.tempPath, upload.destination); err != nil {
+			if upload.replacedPath != "" {
+				renameFile(upload.replacedPath, upload.destination)
+			}
+
+			rollback()
+			return err
+		}
+
+		done = append(done, upload)
+	}
+
+	for _, upload := range done {
+		if upload.replacedPath != "" {
+			os.Remove(upload.replacedPath)
+		}
+	}
+
+	return nil
+}
+
+// uploadError handles errors reading the uploaded files, which are either
+// caused by the upload being too big, a malformed request, or the disk
+func (s *Server) uploadError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		s.httpError(http.StatusRequestEntityTooLarge, w, r, "413 request entity too large: uploads can't be bigger than %d bytes", maxBytesErr.Limit)
+		return
+	}
+
+	var pathErr *os.PathError
+	if errors.As(err, &pathErr) {
+		s.printWarning("unable to save uploaded file: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to save uploaded file -- see application logs for more information")
+		return
+	}
+
+	s.httpError(http.StatusBadRequest, w, r, "400 bad request: unable to read uploaded files")
+}
+
+// plainFileName returns the name a file or directory is created with.
+// Browsers only send the base name, but other clients could send a full
+// path, so anything that isn't a plain file name is rejected.
+func plainFileName(name string) (string, bool) {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) || strings.ContainsRune(name, 0) {
+		return "", false
+	}
+
+	return name, true
+}
+
+// isWithinRoot checks if the absolute path is the served directory
+// or one of its descendants
+func (s *Server) isWithinRoot(p string) bool {
+	root, err := filepath.Abs(s.Path)
+	if err != nil {
+		return false
+	}
+
+	rel, err := filepath.Rel(root, p)
+	if err != nil {
+		return false
+	}
+
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// isSameOrigin checks if the request was sent from a page served by this
+// server, based on the "Origin" header browsers send with form submissions.
+// Requests without the header, like those from command line tools, are
+// always considered to be from the same origin.
+func (s *Server) isSameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	if u.Host == r.Host {
+		return true
+	}
+
+	return s.TrustProxy && u.Host == r.Header.Get("X-Forwarded-Host")
+}
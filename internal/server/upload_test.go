@@ -0,0 +1,308 @@
+package server
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newUploadRequest creates a multipart upload request with
+// the given file names and contents
+func newUploadRequest(t *testing.T, target string, files map[string]string) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	mpw := multipart.NewWriter(&body)
+
+	for name, contents := range files {
+		fw, err := mpw.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatalf("unable to create form file: %s", err)
+		}
+
+		fw.Write([]byte(contents))
+	}
+
+	mpw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, target, &body)
+	req.Header.Set("Content-Type", mpw.FormDataContentType())
+	return req
+}
+
+func Test_upload(t *testing.T) {
+	tests := []struct {
+		name          string
+		server        *Server
+		target        string
+		files         map[string]string
+		origin        string
+		wantStatus    int
+		wantFiles     map[string]string
+		wantMissing   []string
+		wantLeftovers bool
+	}{
+		{
+			name:       "uploads disabled",
+			server:     &Server{},
+			target:     "/",
+			files:      map[string]string{"hello.txt": "hello"},
+			wantStatus: http.StatusMethodNotAllowed,
+			wantMissing: []string{
+				"hello.txt",
+			},
+		},
+		{
+			name:       "files saved into the directory",
+			server:     &Server{AllowUpload: true},
+			target:     "/docs/",
+			files:      map[string]string{"hello.txt": "hello", "world.txt": "world"},
+			wantStatus: http.StatusSeeOther,
+			wantFiles:  map[string]string{"docs/hello.txt": "hello", "docs/world.txt": "world"},
+		},
+		{
+			name:       "existing files are replaced",
+			server:     &Server{AllowUpload: true},
+			target:     "/docs/",
+			files:      map[string]string{"existing.txt": "new contents"},
+			wantStatus: http.StatusSeeOther,
+			wantFiles:  map[string]string{"docs/existing.txt": "new contents"},
+		},
+		{
+			name:        "filtered file names are rejected",
+			server:      &Server{AllowUpload: true, ConfigFilePrefix: ".http-server"},
+			target:      "/",
+			files:       map[string]string{".http-server.yaml": "port: 80"},
+			wantStatus:  http.StatusBadRequest,
+			wantMissing: []string{".http-server.yaml"},
+		},
+		{
+			name:        "uploads bigger than the limit are rejected",
+			server:      &Server{AllowUpload: true, MaxUploadSize: 1024},
+			target:      "/",
+			files:       map[string]string{"big.txt": strings.Repeat("a", 4096)},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantMissing: []string{"big.txt"},
+		},
+		{
+			name:        "uploads bigger than the request body limit are rejected",
+			server:      &Server{AllowUpload: true, MaxRequestBodyBytes: 1024},
+			target:      "/",
+			files:       map[string]string{"big.txt": strings.Repeat("a", 4096)},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantMissing: []string{"big.txt"},
+		},
+		{
+			name:        "uploads with more files than the limit are rejected",
+			server:      &Server{AllowUpload: true, MaxUploadFiles: 2},
+			target:      "/",
+			files:       map[string]string{"one.txt": "1", "two.txt": "2", "three.txt": "3"},
+			wantStatus:  http.StatusRequestEntityTooLarge,
+			wantMissing: []string{"one.txt", "two.txt", "three.txt"},
+		},
+		{
+			name:       "uploads with as many files as the limit are saved",
+			server:     &Server{AllowUpload: true, MaxUploadFiles: 2},
+			target:     "/",
+			files:      map[string]string{"one.txt": "1", "two.txt": "2"},
+			wantStatus: http.StatusSeeOther,
+			wantFiles:  map[string]string{"one.txt": "1", "two.txt": "2"},
+		},
+		{
+			name:        "uploads from other sites are rejected",
+			server:      &Server{AllowUpload: true},
+			target:      "/",
+			files:       map[string]string{"hello.txt": "hello"},
+			origin:      "https://evil.example.com",
+			wantStatus:  http.StatusForbidden,
+			wantMissing: []string{"hello.txt"},
+		},
+		{
+			name:        "uploads into files are rejected",
+			server:      &Server{AllowUpload: true},
+			target:      "/docs/existing.txt",
+			files:       map[string]string{"hello.txt": "hello"},
+			wantStatus:  http.StatusMethodNotAllowed,
+			wantMissing: []string{"docs/hello.txt"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "docs/existing.txt", "old contents")
+
+			tt.server.Path = root
+			h := newTestHandler(t, tt.server)
+
+			req := newUploadRequest(t, tt.target, tt.files)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			for name, contents := range tt.wantFiles {
+				b, err := os.ReadFile(filepath.Join(root, name))
+				if err != nil {
+					t.Errorf("expected file %q to exist: %s", name, err)
+					continue
+				}
+
+				if string(b) != contents {
+					t.Errorf("expected file %q to contain %q, got %q", name, contents, string(b))
+				}
+			}
+
+			for _, name := range tt.wantMissing {
+				if _, err := os.Stat(filepath.Join(root, name)); err == nil {
+					t.Errorf("expected file %q to not exist", name)
+				}
+			}
+
+			// No temporary files should be left behind
+			matches, _ := filepath.Glob(filepath.Join(root, "*", uploadTempPrefix+"*"))
+			rootMatches, _ := filepath.Glob(filepath.Join(root, uploadTempPrefix+"*"))
+			if len(matches)+len(rootMatches) > 0 {
+				t.Errorf("expected no temporary upload files, found: %v", append(matches, rootMatches...))
+			}
+		})
+	}
+}
+
+func Test_uploadIntoAlias(t *testing.T) {
+	root, media := t.TempDir(), t.TempDir()
+	h := newTestHandler(t, &Server{Path: root, AllowUpload: true, Aliases: map[string]string{"/media/": media}})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newUploadRequest(t, "/media/", map[string]string{"hello.txt": "hello"}))
+
+	if rec.Code != http.StatusSeeOther {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusSeeOther, rec.Code, rec.Body.String())
+	}
+
+	b, err := os.ReadFile(filepath.Join(media, "hello.txt"))
+	if err != nil || string(b) != "hello" {
+		t.Fatalf("expected the file to be saved into the aliased directory, got %q: %v", string(b), err)
+	}
+}
+
+func Test_commitUploadsRollback(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, dir, "existing.txt", "old contents")
+
+	var staged []stagedUpload
+	for _, name := range []string{"existing.txt", "new.txt", "failing.txt"} {
+		tempPath := filepath.Join(dir, uploadTempPrefix+name)
+		if err := os.WriteFile(tempPath, []byte("uploaded "+name), 0o644); err != nil {
+			t.Fatalf("unable to write staged file: %s", err)
+		}
+
+		staged = append(staged, stagedUpload{tempPath: tempPath, destination: filepath.Join(dir, name)})
+	}
+
+	// Fail moving the last file into place
+	renameFile = func(from, to string) error {
+		if to == filepath.Join(dir, "failing.txt") {
+			return os.ErrPermission
+		}
+
+		return os.Rename(from, to)
+	}
+	t.Cleanup(func() { renameFile = os.Rename })
+
+	if err := commitUploads(staged); err == nil {
+		t.Fatalf("expected an error moving the uploaded files")
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "existing.txt"))
+	if err != nil || string(b) != "old contents" {
+		t.Errorf("expected the replaced file to be restored, got %q: %v", string(b), err)
+	}
+
+	for _, name := range []string{"new.txt", "failing.txt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			t.Errorf("expected file %q to not exist", name)
+		}
+	}
+
+	// Every staged file is back in place, to be removed by the caller
+	for _, upload := range staged {
+		if _, err := os.Stat(upload.tempPath); err != nil {
+			t.Errorf("expected staged file %q to be restored: %s", upload.tempPath, err)
+		}
+	}
+
+	if matches, _ := filepath.Glob(filepath.Join(dir, "*.replaced")); len(matches) > 0 {
+		t.Errorf("expected no replaced files left behind, found: %v", matches)
+	}
+}
+
+func Test_uploadForm(t *testing.T) {
+	tests := []struct {
+		name     string
+		server   *Server
+		wantForm bool
+	}{
+		{
+			name:     "form shown when uploads are enabled",
+			server:   &Server{AllowUpload: true},
+			wantForm: true,
+		},
+		{
+			name:     "listing shown when uploads are disabled",
+			server:   &Server{},
+			wantForm: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			rec := doRequest(h, http.MethodGet, "/?upload", nil)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+			}
+
+			hasForm := strings.Contains(rec.Body.String(), `enctype="multipart/form-data"`)
+			if hasForm != tt.wantForm {
+				t.Errorf("expected form to be shown: %v, got: %v", tt.wantForm, hasForm)
+			}
+		})
+	}
+}
+
+func Test_plainFileName(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   string
+		wantOK bool
+	}{
+		{name: "report.pdf", want: "report.pdf", wantOK: true},
+		{name: "../report.pdf", wantOK: false},
+		{name: "dir/report.pdf", wantOK: false},
+		{name: `dir\report.pdf`, wantOK: false},
+		{name: "..", wantOK: false},
+		{name: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := plainFileName(tt.name)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("expected (%q, %v), got (%q, %v)", tt.want, tt.wantOK, got, ok)
+			}
+		})
+	}
+}
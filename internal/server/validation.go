@@ -6,9 +6,13 @@ import (
 	"regexp"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/patrickdappollonio/http-server/internal/mw"
 )
 
-const warnPrefix = "[WARNING] >>> "
+const (
+	warnPrefix  = "[WARNING] >>> "
+	errorPrefix = "[ERROR] >>> "
+)
 
 // Validate checks the configuration using struct tags and validate
 // if the fields are valid per those rules
@@ -18,6 +22,7 @@ func (s *Server) Validate() error {
 
 	// Add custom validation rules
 	valid.RegisterValidation("ispathprefix", validateIsPathPrefix)
+	valid.RegisterValidation("listcolumns", validateListColumns)
 
 	// Read tag names from struct fields
 	valid.RegisterTagNameFunc(func(fld reflect.StructField) string {
@@ -59,8 +64,37 @@ func validateIsPathPrefix(field validator.FieldLevel) bool {
 	return reIsPathPrefix.MatchString(field.Field().String())
 }
 
+// validateListColumns checks if the value is a list of known directory
+// listing columns, which must include the file name, since it's the
+// column that links to the file
+func validateListColumns(field validator.FieldLevel) bool {
+	columns, ok := field.Field().Interface().([]string)
+	if !ok {
+		return false
+	}
+
+	hasName := false
+	for _, column := range columns {
+		if !isListColumn(column) {
+			return false
+		}
+
+		if column == "name" {
+			hasName = true
+		}
+	}
+
+	return hasName
+}
+
 func (s *Server) printWarning(format string, args ...interface{}) {
-	if s.LogOutput != nil {
+	if s.LogOutput != nil && s.logLevels.Enabled(mw.LogWarn) {
 		fmt.Fprintf(s.LogOutput, warnPrefix+format+"\n", args...)
 	}
 }
+
+func (s *Server) printError(format string, args ...interface{}) {
+	if s.LogOutput != nil && s.logLevels.Enabled(mw.LogError) {
+		fmt.Fprintf(s.LogOutput, errorPrefix+format+"\n", args...)
+	}
+}
@@ -0,0 +1,212 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+const (
+	// maxWarmPaths is how many paths a single warming request can list
+	maxWarmPaths = 1000
+
+	// maxWarmBodySize is how large the list of paths to warm can be
+	maxWarmBodySize = 1 << 20
+
+	// warmConcurrency is how many paths are warmed at the same time
+	warmConcurrency = 4
+)
+
+// warmResult is the outcome of warming a single path
+type warmResult struct {
+	Path   string `json:"path"`
+	Warmed bool   `json:"warmed"`
+	Bytes  int64  `json:"bytes"`
+	Error  string `json:"error,omitempty"`
+}
+
+// warmEndpoint returns the path of the endpoint warming caches
+func (s *Server) warmEndpoint() string {
+	return path.Join(s.PathPrefix, specialPath, "debug", "warm")
+}
+
+// isWarmRequest checks if the request is for the endpoint warming caches,
+// which only exists when the debug endpoints are enabled
+func (s *Server) isWarmRequest(r *http.Request) bool {
+	return s.DebugEndpoints && r.URL.Path == s.warmEndpoint()
+}
+
+// warmCaches reads the paths listed, as a JSON array, in the request body,
+// so the first requests for them after a restart don't hit a cold disk.
+// Files are read all the way through, computing their checksum when
+// checksums are sent with them, and directories have their entries read.
+func (s *Server) warmCaches(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		s.httpError(http.StatusMethodNotAllowed, w, r, "405 method not allowed: send the paths to warm as a JSON array")
+		return
+	}
+
+	var paths []string
+	if err := json.NewDecoder(io.LimitReader(r.Body, maxWarmBodySize)).Decode(&paths); err != nil {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: the body must be a JSON array of paths: %s", err)
+		return
+	}
+
+	if len(paths) == 0 || len(paths) > maxWarmPaths {
+		s.httpError(http.StatusBadRequest, w, r, "400 bad request: between 1 and %d paths can be warmed at once, got %d", maxWarmPaths, len(paths))
+		return
+	}
+
+	// Warming reads whole files, so it counts as an expensive operation
+	release, ok := s.startExpensiveOp(w, r)
+	if !ok {
+		return
+	}
+
+	results := make([]warmResult, len(paths))
+	sem := make(chan struct{}, warmConcurrency)
+
+	var wg sync.WaitGroup
+	for i, p := range paths {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results[i] = s.warmPath(p, r)
+		}()
+	}
+
+	wg.Wait()
+	release()
+
+	if err := r.Context().Err(); err != nil {
+		s.printCanceled(r, "stopped warming %d paths: %s", len(paths), err)
+		return
+	}
+
+	warmed := 0
+	for _, res := range results {
+		if res.Warmed {
+			warmed++
+		}
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"warmed": warmed,
+		"failed": len(results) - warmed,
+		"paths":  results,
+	})
+	if err != nil {
+		s.printWarning("unable to generate warming response: %s", err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to generate warming response -- see application logs for more information")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(body)
+}
+
+// warmPath reads the file or directory at the given path, relative to the
+// path prefix, the same way a request for it would resolve it
+func (s *Server) warmPath(p string, r *http.Request) warmResult {
+	res := warmResult{Path: p}
+
+	// Backslashes are handled as in any request, before looking
+	// for segments climbing out of the served directory
+	p, ok := s.normalizeBackslashes(p)
+	if !ok {
+		res.Error = "path can't contain backslashes"
+		return res
+	}
+
+	// Paths are rejected, rather than cleaned, when they try to climb
+	// out of the served directory, so mistakes don't go unnoticed
+	for _, segment := range strings.Split(p, "/") {
+		if segment == ".." {
+			res.Error = "path is outside of the served directory"
+			return res
+		}
+
+		if segment != "" && s.isFiltered(segment) {
+			res.Error = "not found"
+			return res
+		}
+	}
+
+	requested := path.Clean("/" + p)
+
+	basePath, err := s.servedPath(requested)
+	if err != nil {
+		res.Error = "path is outside of the served directory"
+		return res
+	}
+
+	fp := s.resolveOverlay(requested, basePath)
+
+	fi, err := os.Stat(fp)
+	if err != nil {
+		if os.IsNotExist(err) {
+			res.Error = "not found"
+			return res
+		}
+
+		s.printWarning("unable to stat path %q to warm it: %s", fp, err)
+		res.Error = "unable to stat path"
+		return res
+	}
+
+	if fi.IsDir() {
+		if _, err := os.ReadDir(fp); err != nil {
+			s.printWarning("unable to read directory %q to warm it: %s", fp, err)
+			res.Error = "unable to read directory"
+			return res
+		}
+
+		res.Warmed = true
+		return res
+	}
+
+	if err := s.warmFile(fp, fi, r); err != nil {
+		if r.Context().Err() == nil {
+			s.printWarning("unable to read file %q to warm it: %s", fp, err)
+		}
+
+		res.Error = "unable to read file"
+		return res
+	}
+
+	res.Warmed = true
+	res.Bytes = fi.Size()
+	return res
+}
+
+// warmFile reads the whole file, going through the checksum cache when
+// checksums are sent with files, so they're ready for the first request
+func (s *Server) warmFile(fp string, fi os.FileInfo, r *http.Request) error {
+	if s.ChecksumHeaders || s.ChecksumTrailers {
+		_, err := s.fileChecksum(fp, fi, "sha256", r)
+		return err
+	}
+
+	f, err := os.Open(fp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(io.Discard, &contextReader{ctx: r.Context(), ReadSeeker: f}); err != nil {
+		return fmt.Errorf("unable to read file: %w", err)
+	}
+
+	return nil
+}
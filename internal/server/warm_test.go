@@ -0,0 +1,163 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func Test_warmCaches(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+	writeTestFile(t, root, "dir/nested.txt", "world!")
+	writeTestFile(t, root, ".http-server.yaml", "hidden")
+
+	tests := []struct {
+		name       string
+		server     *Server
+		method     string
+		body       string
+		headers    map[string]string
+		wantStatus int
+		wantWarmed map[string]bool
+	}{
+		{
+			name:       "disabled by default",
+			server:     &Server{Path: root},
+			method:     http.MethodPost,
+			body:       `["/file.txt"]`,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "protected by authentication",
+			server:     &Server{Path: root, DebugEndpoints: true, Username: "admin", Password: "secret"},
+			method:     http.MethodPost,
+			body:       `["/file.txt"]`,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "only POST is accepted",
+			server:     &Server{Path: root, DebugEndpoints: true},
+			method:     http.MethodGet,
+			wantStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:       "invalid body",
+			server:     &Server{Path: root, DebugEndpoints: true},
+			method:     http.MethodPost,
+			body:       `{"paths": "/file.txt"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "empty list",
+			server:     &Server{Path: root, DebugEndpoints: true},
+			method:     http.MethodPost,
+			body:       `[]`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "warms files and directories",
+			server:     &Server{Path: root, DebugEndpoints: true, ConfigFilePrefix: ".http-server", Username: "admin", Password: "secret"},
+			method:     http.MethodPost,
+			body:       `["/file.txt", "dir/", "/dir/nested.txt", "/missing.txt", "/../etc/passwd", "/..\\etc\\passwd", "/.http-server.yaml"]`,
+			headers:    map[string]string{"Authorization": "Basic YWRtaW46c2VjcmV0"},
+			wantStatus: http.StatusOK,
+			wantWarmed: map[string]bool{
+				"/file.txt":          true,
+				"dir/":               true,
+				"/dir/nested.txt":    true,
+				"/missing.txt":       false,
+				"/../etc/passwd":     false,
+				`/..\etc\passwd`:     false,
+				"/.http-server.yaml": false,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newTestHandler(t, tt.server)
+
+			req := httptest.NewRequest(tt.method, "/_/debug/warm", strings.NewReader(tt.body))
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rec.Code, rec.Body.String())
+			}
+
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var summary struct {
+				Warmed int          `json:"warmed"`
+				Failed int          `json:"failed"`
+				Paths  []warmResult `json:"paths"`
+			}
+
+			if err := json.Unmarshal(rec.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("unable to decode warming response: %s", err)
+			}
+
+			if len(summary.Paths) != len(tt.wantWarmed) {
+				t.Fatalf("expected %d paths in the summary, got %d", len(tt.wantWarmed), len(summary.Paths))
+			}
+
+			warmed := 0
+			for _, res := range summary.Paths {
+				if want := tt.wantWarmed[res.Path]; res.Warmed != want {
+					t.Errorf("expected path %q warmed to be %v, got %v (%s)", res.Path, want, res.Warmed, res.Error)
+				}
+
+				if res.Warmed {
+					warmed++
+				}
+			}
+
+			if summary.Warmed != warmed || summary.Failed != len(summary.Paths)-warmed {
+				t.Errorf("expected %d warmed and %d failed, got %d and %d", warmed, len(summary.Paths)-warmed, summary.Warmed, summary.Failed)
+			}
+		})
+	}
+}
+
+func Test_warmCachesChecksums(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "file.txt", "hello")
+
+	s := &Server{Path: root, DebugEndpoints: true, ChecksumHeaders: true}
+	h := newTestHandler(t, s)
+
+	req := httptest.NewRequest(http.MethodPost, "/_/debug/warm", strings.NewReader(`["/file.txt"]`))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if n := s.checksumCache.len(); n != 1 {
+		t.Fatalf("expected 1 cached checksum, got %d", n)
+	}
+}
+
+func Test_postWithoutUploads(t *testing.T) {
+	h := newTestHandler(t, &Server{DebugEndpoints: true})
+
+	rec := doRequest(h, http.MethodPost, "/", nil)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected status %d, got %d", http.StatusMethodNotAllowed, rec.Code)
+	}
+
+	// POST is only accepted by the endpoint warming caches
+	if got, want := rec.Header().Get("Allow"), "GET, HEAD"; got != want {
+		t.Fatalf("expected Allow header %q, got %q", want, got)
+	}
+}
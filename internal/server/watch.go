@@ -0,0 +1,123 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchKeepAlive is how often a comment is sent to idle watchers, so
+// proxies and clients don't consider the connection dead
+const watchKeepAlive = 30 * time.Second
+
+// watchEvent is a change in a watched directory, sent to the client
+// as the data of a server-sent event named after the change
+type watchEvent struct {
+	Name string `json:"name"`
+}
+
+// isWatchRequest checks if the directory changes were requested as
+// server-sent events with the "watch" query string parameter
+func (s *Server) isWatchRequest(r *http.Request) bool {
+	return s.Watch && queryFlag(r, "watch")
+}
+
+// watchDirectory sends the changes to the directory, and its overlays,
+// as server-sent events until the client goes away. Events are named
+// "created", "removed" or "changed", with the name of the file as data.
+// Renamed files are reported as removed, and their new name as created.
+func (s *Server) watchDirectory(dirPath string, overlays []string, w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		s.httpError(http.StatusInternalServerError, w, r, "500 internal server error: streaming is not supported")
+		return
+	}
+
+	// Each watcher holds operating system resources,
+	// so only so many can be active at once
+	if s.MaxWatchers > 0 {
+		if s.activeWatchers.Add(1) > int64(s.MaxWatchers) {
+			s.activeWatchers.Add(-1)
+			w.Header().Set("Retry-After", "30")
+			s.httpError(http.StatusServiceUnavailable, w, r, "503 service unavailable: too many directories being watched")
+			return
+		}
+		defer s.activeWatchers.Add(-1)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		s.printWarning("unable to watch directory %q: %s", dirPath, err)
+		s.httpError(http.StatusInternalServerError, w, r, "unable to watch directory -- see application logs for more information")
+		return
+	}
+	defer watcher.Close()
+
+	for _, dir := range append([]string{dirPath}, overlays...) {
+		if err := watcher.Add(dir); err != nil {
+			s.printWarning("unable to watch directory %q: %s", dir, err)
+			s.httpError(http.StatusInternalServerError, w, r, "unable to watch directory -- see application logs for more information")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+
+	// Send the headers right away, so the client knows it's connected
+	fmt.Fprint(w, ": watching\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(watchKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+
+			s.printWarning("error watching directory %q: %s", dirPath, err)
+			continue
+
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+
+			name := filepath.Base(ev.Name)
+			if s.isHidden(name) {
+				continue
+			}
+
+			var kind string
+			switch {
+			case ev.Has(fsnotify.Create):
+				kind = "created"
+			case ev.Has(fsnotify.Remove), ev.Has(fsnotify.Rename):
+				kind = "removed"
+			case ev.Has(fsnotify.Write):
+				kind = "changed"
+			default:
+				continue
+			}
+
+			data, _ := json.Marshal(watchEvent{Name: name})
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data)
+		}
+
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvent reads the next server-sent event, skipping comments
+func readEvent(t *testing.T, body *bufio.Reader) string {
+	t.Helper()
+
+	var lines []string
+	for {
+		line, err := body.ReadString('\n')
+		if err != nil {
+			t.Fatalf("unable to read event: %s", err)
+		}
+
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "" && len(lines) > 0:
+			return strings.Join(lines, "\n")
+		case line == "", strings.HasPrefix(line, ":"):
+			continue
+		default:
+			lines = append(lines, line)
+		}
+	}
+}
+
+func Test_watchDirectory(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "existing.txt", "contents")
+
+	srv := httptest.NewServer(newTestHandler(t, &Server{Path: root, Watch: true, MaxWatchers: 1, HideDotfiles: true}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/?watch=1", nil)
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("unable to watch directory: %s", err)
+	}
+	defer res.Body.Close()
+
+	if ct := res.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected content type %q, got %q", "text/event-stream", ct)
+	}
+
+	// Only one watcher is allowed at once
+	if second, err := http.Get(srv.URL + "/?watch=1"); err != nil {
+		t.Fatalf("unable to send second request: %s", err)
+	} else {
+		second.Body.Close()
+		if second.StatusCode != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d for a second watcher, got %d", http.StatusServiceUnavailable, second.StatusCode)
+		}
+	}
+
+	body := bufio.NewReader(res.Body)
+
+	// Hidden files aren't reported
+	writeTestFile(t, root, ".hidden", "contents")
+	writeTestFile(t, root, "new.txt", "contents")
+	if got, want := readEvent(t, body), "event: created\ndata: {\"name\":\"new.txt\"}"; got != want {
+		t.Fatalf("expected event %q, got %q", want, got)
+	}
+
+	if err := os.Remove(filepath.Join(root, "existing.txt")); err != nil {
+		t.Fatalf("unable to remove file: %s", err)
+	}
+
+	// Writing the new file could report one or more changes first
+	for {
+		got := readEvent(t, body)
+		if got == "event: changed\ndata: {\"name\":\"new.txt\"}" {
+			continue
+		}
+
+		if want := "event: removed\ndata: {\"name\":\"existing.txt\"}"; got != want {
+			t.Fatalf("expected event %q, got %q", want, got)
+		}
+		break
+	}
+}
+
+func Test_watchDisabled(t *testing.T) {
+	root := t.TempDir()
+	writeTestFile(t, root, "existing.txt", "contents")
+
+	h := newTestHandler(t, &Server{Path: root})
+
+	rec := doRequest(h, http.MethodGet, "/?watch=1", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rec.Code)
+	}
+
+	if !strings.Contains(rec.Body.String(), "existing.txt") {
+		t.Fatalf("expected the listing to be rendered, got %q", rec.Body.String())
+	}
+}
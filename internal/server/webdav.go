@@ -0,0 +1,16 @@
+package server
+
+import "github.com/go-chi/chi/v5"
+
+// WebDAV methods used to manage files when writes are enabled
+const (
+	methodMkcol = "MKCOL"
+	methodMove  = "MOVE"
+)
+
+func init() {
+	// The router only matches the standard HTTP methods
+	// unless told about any others
+	chi.RegisterMethod(methodMkcol)
+	chi.RegisterMethod(methodMove)
+}
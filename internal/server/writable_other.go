@@ -0,0 +1,11 @@
+//go:build !unix
+
+package server
+
+import "os"
+
+// isGroupOrWorldWritable always reports files as not writable by
+// others, since permission bits don't tell so on this platform
+func isGroupOrWorldWritable(fi os.FileInfo) bool {
+	return false
+}
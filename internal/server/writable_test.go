@@ -0,0 +1,129 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"image"
+	"image/png"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func Test_refuseWorldWritable(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions don't tell who can write files on windows")
+	}
+
+	tests := []struct {
+		name       string
+		mode       os.FileMode
+		refuse     bool
+		wantStatus int
+	}{
+		{
+			name:       "served by default",
+			mode:       0o666,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "world writable file refused",
+			mode:       0o666,
+			refuse:     true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "group writable file refused",
+			mode:       0o664,
+			refuse:     true,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "file writable by its owner only",
+			mode:       0o644,
+			refuse:     true,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeTestFile(t, root, "file.txt", "hello")
+
+			// Set the mode explicitly, since the umask applies on creation
+			if err := os.Chmod(filepath.Join(root, "file.txt"), tt.mode); err != nil {
+				t.Fatalf("unable to change file mode: %s", err)
+			}
+
+			h := newTestHandler(t, &Server{Path: root, RefuseWorldWritable: tt.refuse})
+
+			rec := doRequest(h, http.MethodGet, "/file.txt", nil)
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d", tt.wantStatus, rec.Code)
+			}
+		})
+	}
+}
+
+func Test_refuseWorldWritableQueries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions don't tell who can write files on windows")
+	}
+
+	var img bytes.Buffer
+	if err := png.Encode(&img, image.NewRGBA(image.Rect(0, 0, 4, 4))); err != nil {
+		t.Fatalf("unable to encode test image: %s", err)
+	}
+
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	zw.Write([]byte("compressed contents\n"))
+	zw.Close()
+
+	root := t.TempDir()
+	files := map[string]string{
+		"main.go":         "package main\n",
+		"image.png":       img.String(),
+		"archive.log.gz":  compressed.String(),
+		"stored.txt.gz":   compressed.String(),
+		"docs/index.html": "<h1>{{ .CurrentPath }}</h1>",
+	}
+
+	for name, contents := range files {
+		writeTestFile(t, root, name, contents)
+		if err := os.Chmod(filepath.Join(root, name), 0o666); err != nil {
+			t.Fatalf("unable to change file mode: %s", err)
+		}
+	}
+
+	s := &Server{
+		Path:                root,
+		RefuseWorldWritable: true,
+		CodeViewer:          true,
+		GzipPreviews:        true,
+		Checksums:           true,
+		ImageConversion:     true,
+		ServeGzipped:        true,
+		IndexTemplating:     true,
+	}
+	h := newTestHandler(t, s)
+
+	for _, target := range []string{
+		"/main.go?view=1",
+		"/archive.log.gz?preview=1",
+		"/main.go?checksum=sha256",
+		"/image.png?format=jpeg",
+		"/stored.txt",
+		"/docs/",
+	} {
+		t.Run(target, func(t *testing.T) {
+			rec := doRequest(h, http.MethodGet, target, nil)
+			if rec.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusForbidden, rec.Code, rec.Body.String())
+			}
+		})
+	}
+}
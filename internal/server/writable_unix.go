@@ -0,0 +1,11 @@
+//go:build unix
+
+package server
+
+import "os"
+
+// isGroupOrWorldWritable checks if users other than the owner of
+// the file can change it, based on its permissions
+func isGroupOrWorldWritable(fi os.FileInfo) bool {
+	return fi.Mode().Perm()&0o022 != 0
+}